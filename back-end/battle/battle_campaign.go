@@ -2,47 +2,70 @@ package battle
 
 import (
 	"bytes"
+	"clash_and_card/engine"
+	"clash_and_card/httputil"
 	"clash_and_card/models"
+	"clash_and_card/store"
 	"clash_and_card/user"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 )
 
-type Card struct {
-	ID   string `json:"id"`
-	Type string `json:"type"`
-}
-
 type GameState struct {
 	PVPState
 	PlayingLevel int
+	OwnerID      string    // user id ของผู้เล่นที่เริ่ม match นี้ (ฝั่ง A)
+	LastActive   time.Time // เวลาที่เล่นล่าสุด ใช้ตัดสิน match ที่ถูกทิ้ง
 }
 
 var gameStates = make(map[string]*GameState)
 var gameStatesMutex sync.Mutex
 
-func newShuffledDeck(level int) []Card {
-	var cards []Card
+// ownedGameState หา match ของ route /api/battle/{matchID}/... และตรวจว่าผู้เรียกเป็นเจ้าของ
+// ถ้าไม่ผ่านจะตอบ error ไปแล้วและคืน ok เป็น false
+func ownedGameState(w http.ResponseWriter, r *http.Request) (gs *GameState, ok bool) {
+	matchID := mux.Vars(r)["matchID"]
+	if matchID == "" {
+		httputil.WriteJSONError(w, http.StatusBadRequest, "Missing matchID")
+		return nil, false
+	}
+
+	gameStatesMutex.Lock()
+	gs, ok = gameStates[matchID]
+	gameStatesMutex.Unlock()
+	if !ok {
+		httputil.WriteJSONError(w, http.StatusNotFound, "Game not found")
+		return nil, false
+	}
+
+	userID, _ := user.UserIDFromContext(r.Context())
+	if gs.OwnerID != userID {
+		fmt.Println("[WARN] user", userID, "tried to access match", matchID, "owned by", gs.OwnerID)
+		httputil.WriteJSONError(w, http.StatusForbidden, "Not your match")
+		return nil, false
+	}
+	return gs, true
+}
+
+// newBotDeck สร้างเด็คของ bot (ยังไม่สับ การสับไพ่ทำตอน Match.Start)
+func newBotDeck(level int) []engine.Card {
+	var cards []engine.Card
 	types := []string{"rock", "paper", "scissors"}
 	idCounter := 1
 	for _, t := range types {
 		for i := 0; i < 5+(level); i++ {
-			cards = append(cards, Card{ID: "bot" + strconv.Itoa(idCounter), Type: t})
+			cards = append(cards, engine.Card{ID: "bot" + strconv.Itoa(idCounter), Type: t})
 			idCounter++
 		}
 	}
-	rand.Shuffle(len(cards), func(i, j int) {
-		cards[i], cards[j] = cards[j], cards[i]
-	})
 	return cards
 }
 
@@ -60,43 +83,10 @@ func generateBotStats(level int) (atk, def, spd, hp int) {
 	return
 }
 
-func handlePlayerWin(userID string, db *sql.DB, wonLevel int) (statGain models.UnitStat, levelGain, expGain, goldGain int, err error) {
-	var currentLevel int
-	err = db.QueryRow(`SELECT current_campaign_level FROM users WHERE id = ?`, userID).Scan(&currentLevel)
-	if err != nil {
-		err = fmt.Errorf("failed to get current campaign level: %v", err)
-		return
-	}
-
-	fmt.Println("[DEBUG] cLVL ", currentLevel)
-	fmt.Println("[DEBUG] wLVL ", wonLevel)
-	// คำนวณรางวัล
-	if wonLevel == currentLevel {
-		fmt.Println("[DEBUG] ??? ")
-		expGain = 50 + (20 * wonLevel)
-		goldGain = 20 * wonLevel
-		levelGain = 1 // เพราะเลเวลเพิ่มแน่ๆ
-	} else {
-		expGain = 5 * wonLevel
-		goldGain = 5 * wonLevel
-		levelGain = 0
-	}
-	fmt.Println("[DEBUG] exp gain ", expGain)
-	fmt.Println("[DEBUG] gold gain ", goldGain)
-
-	// ดึงข้อมูลปัจจุบัน
-	var level, currentExp int
-	var class string
-	query := `SELECT level, exp, class FROM users WHERE id = ?`
-	err = db.QueryRow(query, userID).Scan(&level, &currentExp, &class)
-	if err != nil {
-		err = fmt.Errorf("failed to get user level/class/exp: %v", err)
-		return
-	}
-
-	totalExp := currentExp + expGain
-	newLevel := level
-	levelUpCount := 0
+// applyRewards เพิ่ม exp และทองให้ผู้ใช้ พร้อมคำนวณเลเวลอัปและ stat ที่ได้ตามคลาส
+func applyRewards(u *models.User, expGain, goldGain int) (statGain models.UnitStat, levelUpCount int) {
+	totalExp := u.Exp + expGain
+	newLevel := u.Level
 
 	// คำนวณเลเวลใหม่ (ถ้าเก็บ exp เพิ่มเลเวล)
 	for {
@@ -110,10 +100,8 @@ func handlePlayerWin(userID string, db *sql.DB, wonLevel int) (statGain models.U
 		}
 	}
 
-	levelGain = levelUpCount
-
 	// คำนวณ stat bonus ตามคลาส
-	switch class {
+	switch u.Class {
 	case "warrior":
 		statGain.Atk = 2 * levelUpCount
 		statGain.Def = 2 * levelUpCount
@@ -131,78 +119,111 @@ func handlePlayerWin(userID string, db *sql.DB, wonLevel int) (statGain models.U
 		statGain.HP = 10 * levelUpCount
 	}
 
-	statPointUp := levelUpCount * 2
-
-	// สร้าง SQL update
-	var updateQuery string
-	if wonLevel == currentLevel {
-		updateQuery = `
-			UPDATE users
-			SET exp = ?, gold = gold + ?, current_campaign_level = current_campaign_level + 1,
-				level = ?, stat_point = stat_point + ?,
-				atk = atk + ?, def = def + ?, spd = spd + ?, hp = hp + ?
-			WHERE id = ?
-		`
-		_, err = db.Exec(updateQuery, totalExp, goldGain, newLevel, statPointUp,
-			statGain.Atk, statGain.Def, statGain.Spd, statGain.HP, userID)
-	} else {
-		updateQuery = `
-			UPDATE users
-			SET exp = ?, gold = gold + ?,
-				level = ?, stat_point = stat_point + ?,
-				atk = atk + ?, def = def + ?, spd = spd + ?, hp = hp + ?
-			WHERE id = ?
-		`
-		_, err = db.Exec(updateQuery, totalExp, goldGain, newLevel, statPointUp,
-			statGain.Atk, statGain.Def, statGain.Spd, statGain.HP, userID)
-	}
+	u.Exp = totalExp
+	u.Gold += goldGain
+	u.Level = newLevel
+	u.StatPoint += levelUpCount * 2
+	u.Stat.Atk += statGain.Atk
+	u.Stat.Def += statGain.Def
+	u.Stat.Spd += statGain.Spd
+	u.Stat.HP += statGain.HP
+	return
+}
+
+func handlePlayerWin(userID string, st store.Store, wonLevel int) (statGain models.UnitStat, levelGain, expGain, goldGain int, err error) {
+	err = st.Users().UpdateUser(userID, func(u *models.User) error {
+		currentLevel := u.CurrentCampaignLevel
+
+		// คำนวณรางวัล
+		if wonLevel == currentLevel {
+			expGain = 50 + (20 * wonLevel)
+			goldGain = 20 * wonLevel
+			u.CurrentCampaignLevel++
+		} else {
+			expGain = 5 * wonLevel
+			goldGain = 5 * wonLevel
+		}
 
+		statGain, levelGain = applyRewards(u, expGain, goldGain)
+		u.CampaignWinStreak++
+		u.BestCampaignWinStreak = max(u.BestCampaignWinStreak, u.CampaignWinStreak)
+		return nil
+	})
 	if err != nil {
 		err = fmt.Errorf("failed to update user data: %v", err)
-		return
 	}
-
 	return
 }
 
+// resetCampaignStreak ตั้งสถิติชนะติดกันกลับเป็น 0 เมื่อแพ้ เสมอ หรือทิ้งเกม
+func resetCampaignStreak(st store.Store, userID string) {
+	err := st.Users().UpdateUser(userID, func(u *models.User) error {
+		u.CampaignWinStreak = 0
+		return nil
+	})
+	if err != nil {
+		fmt.Println("[ERROR] resetCampaignStreak:", err)
+	}
+}
+
+// campaignInitialData ข้อมูลเริ่มเกม (หรือสถานะปัจจุบัน) ที่ส่งให้ client ต้องถือ lock ของ gs ก่อนเรียก
+func campaignInitialData(gs *GameState) map[string]interface{} {
+	playerCardRemaining := engine.CountCards(append(gs.PlayerA.Deck, gs.PlayerA.Hand...))
+	botCardRemaining := engine.CountCards(append(gs.PlayerB.Deck, gs.PlayerB.Hand...))
+	return map[string]interface{}{
+		"type":    "initialData",
+		"matchID": gs.ID,
+		"player": map[string]interface{}{
+			"name":          gs.PlayerA.Name,
+			"level":         gs.PlayerA.Level,
+			"currentHP":     gs.PlayerA.CurrentHP,
+			"cardRemaining": playerCardRemaining,
+			"hand":          gs.PlayerA.Hand,
+			"stat": map[string]interface{}{
+				"atk": gs.PlayerA.Stat.ATK,
+				"def": gs.PlayerA.Stat.DEF,
+				"spd": gs.PlayerA.Stat.SPD,
+				"hp":  gs.PlayerA.Stat.HP,
+			},
+			"class":     gs.PlayerA.Class,
+			"trueSight": gs.PlayerA.TrueSight,
+		},
+		"opponent": map[string]interface{}{
+			"name":          gs.PlayerB.Name,
+			"level":         gs.PlayerB.Level,
+			"currentHP":     gs.PlayerB.CurrentHP,
+			"cardRemaining": botCardRemaining,
+			"handSize":      len(gs.PlayerB.Hand),
+			"stat": map[string]interface{}{
+				"atk": gs.PlayerB.Stat.ATK,
+				"def": gs.PlayerB.Stat.DEF,
+				"spd": gs.PlayerB.Stat.SPD,
+				"hp":  gs.PlayerB.Stat.HP,
+			},
+			"class":     gs.PlayerB.Class,
+			"trueSight": gs.PlayerB.TrueSight,
+		},
+	}
+}
+
 // ----------- Handlers -----------
 
-func StartBattleHandler(db *sql.DB) http.HandlerFunc {
+func StartBattleHandler(st store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
 			BotLevel int `json:"levelId"`
 		}
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			fmt.Println("[ERROR] Missing Authorization header")
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		var tokenStr string
-		fmt.Sscanf(authHeader, "Bearer %s", &tokenStr)
-		if tokenStr == "" {
-			fmt.Println("[ERROR] Invalid Authorization header format:", authHeader)
-			http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		userID, err := user.ExtractUserIDFromToken(tokenStr)
-		if err != nil {
-			fmt.Println("[ERROR] Failed to extract user ID from token:", err)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+		userID, _ := user.UserIDFromContext(r.Context())
 		fmt.Println("[INFO] userID from token:", userID)
 
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || userID == "0" {
-			fmt.Println("[ERROR] Failed to decode request body or invalid userID, body decode error:", err)
-			http.Error(w, "Invalid or missing userId", http.StatusBadRequest)
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			fmt.Println("[ERROR] Failed to decode request body:", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 		fmt.Println("[INFO] BotLevel requested:", req.BotLevel)
 
-		user, err := getUserByIDFromDB(db, userID)
+		user, err := st.Users().GetUser(userID)
 		if err != nil {
 			fmt.Println("[ERROR] Failed to get user by ID:", userID, "err:", err)
 			http.Error(w, "User not found", http.StatusNotFound)
@@ -210,7 +231,7 @@ func StartBattleHandler(db *sql.DB) http.HandlerFunc {
 		}
 		fmt.Println("[INFO] Fetched user:", user.Username)
 
-		deck, err := getDeckByUserIDFromDB(db, userID)
+		deck, err := getDeckCards(st, userID)
 		if err != nil {
 			fmt.Println("[ERROR] Failed to get deck for user:", userID, "err:", err)
 			http.Error(w, "Deck not found", http.StatusNotFound)
@@ -218,87 +239,62 @@ func StartBattleHandler(db *sql.DB) http.HandlerFunc {
 		}
 		fmt.Println("[INFO] Deck fetched for user:", userID, "| deck len:", len(deck))
 
-		botDeck := newShuffledDeck(req.BotLevel)
-		playerHand := drawCards(&deck, 3)
-		botHand := drawCards(&botDeck, 3)
+		botDeck := newBotDeck(req.BotLevel)
 		botATK, botDEF, botSPD, botHP := generateBotStats(req.BotLevel)
 
 		gameState := &GameState{
 			PVPState: PVPState{
-				PlayerA: PlayerData{
-					Name:      user.Username,
-					Level:     user.Level,
-					CurrentHP: user.Stat.HP,
-					Deck:      deck,
-					Hand:      playerHand,
-					Stat: Stat{
-						ATK: user.Stat.Atk,
-						DEF: user.Stat.Def,
-						SPD: user.Stat.Spd,
-						HP:  user.Stat.HP,
+				Match: engine.Match{
+					PlayerA: engine.PlayerData{
+						Name:      user.Username,
+						Level:     user.Level,
+						CurrentHP: user.Stat.HP,
+						Deck:      deck,
+						Stat: engine.Stat{
+							ATK: user.Stat.Atk,
+							DEF: user.Stat.Def,
+							SPD: user.Stat.Spd,
+							HP:  user.Stat.HP,
+						},
+						Class:     user.Class,
+						TrueSight: 0,
 					},
-					Class:     user.Class,
-					TrueSight: 0,
-				},
-				PlayerB: PlayerData{
-					Name:      "Mad Bot",
-					Level:     req.BotLevel,
-					CurrentHP: botHP,
-					Deck:      botDeck,
-					Hand:      botHand,
-					Stat: Stat{
-						ATK: botATK,
-						DEF: botDEF,
-						SPD: botSPD,
-						HP:  botHP,
+					PlayerB: engine.PlayerData{
+						Name:      "Mad Bot",
+						Level:     req.BotLevel,
+						CurrentHP: botHP,
+						Deck:      botDeck,
+						Stat: engine.Stat{
+							ATK: botATK,
+							DEF: botDEF,
+							SPD: botSPD,
+							HP:  botHP,
+						},
+						Class:     "none",
+						TrueSight: 0,
 					},
-					Class:     "none",
-					TrueSight: 0,
 				},
 			},
 			PlayingLevel: req.BotLevel,
+			OwnerID:      userID,
+			LastActive:   time.Now(),
 		}
-
 		matchID := uuid.New().String() // สร้าง match id ใหม่
+		gameState.ID = matchID
 
-		gameStatesMutex.Lock()
-		gameStates[matchID] = gameState
-		gameStatesMutex.Unlock()
-		playerCardRemaining := countCard(append(gameState.PVPState.PlayerA.Deck, gameState.PVPState.PlayerA.Hand...))
-		botCardRemaining := countCard(append(gameState.PVPState.PlayerB.Deck, gameState.PVPState.PlayerB.Hand...))
-		res := map[string]interface{}{
-			"type":    "initialData",
-			"matchID": matchID,
-			"player": map[string]interface{}{
-				"name":          gameState.PVPState.PlayerA.Name,
-				"level":         gameState.PVPState.PlayerA.Level,
-				"currentHP":     gameState.PVPState.PlayerA.CurrentHP,
-				"cardRemaining": playerCardRemaining,
-				"hand":          gameState.PVPState.PlayerA.Hand,
-				"stat": map[string]interface{}{
-					"atk": gameState.PVPState.PlayerA.Stat.ATK,
-					"def": gameState.PVPState.PlayerA.Stat.DEF,
-					"spd": gameState.PVPState.PlayerA.Stat.SPD,
-					"hp":  gameState.PVPState.PlayerA.Stat.HP,
-				},
-				"class":     gameState.PVPState.PlayerA.Class,
-				"trueSight": gameState.PVPState.PlayerA.TrueSight,
-			},
-			"opponent": map[string]interface{}{
-				"name":          gameState.PVPState.PlayerB.Name,
-				"level":         gameState.PVPState.PlayerB.Level,
-				"currentHP":     gameState.PVPState.PlayerB.CurrentHP,
-				"cardRemaining": botCardRemaining,
-				"handSize":      len(gameState.PVPState.PlayerB.Hand),
-				"stat": map[string]interface{}{
-					"atk": gameState.PVPState.PlayerB.Stat.ATK,
-					"def": gameState.PVPState.PlayerB.Stat.DEF,
-					"spd": gameState.PVPState.PlayerB.Stat.SPD,
-					"hp":  gameState.PVPState.PlayerB.Stat.HP,
-				},
-				"class":     gameState.PVPState.PlayerB.Class,
-				"trueSight": gameState.PVPState.PlayerB.TrueSight,
-			},
+		seed := newMatchSeed()
+		if err := recordMatchStart(st, matchID, modeCampaign, userID, "", seed, &gameState.PVPState); err != nil {
+			fmt.Println("[ERROR] recordMatchStart:", err)
+		}
+		gameState.Start(seed)
+		playerHand := gameState.PlayerA.Hand
+		res := campaignInitialData(gameState)
+		saveCampaignState(gameState)
+
+		// ผู้เล่นมี match ที่เล่นอยู่ได้ match เดียว match เก่าที่ยังไม่จบถือว่าแพ้ฟอร์ฟิต
+		if previous := addGameState(gameState); previous != nil {
+			fmt.Println("[INFO] Forfeit previous match:", previous.ID)
+			forfeitGameState(st, previous)
 		}
 
 		fmt.Println("[DEBUG] Created matchID:", matchID)
@@ -309,32 +305,37 @@ func StartBattleHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func PlayCardHandler(db *sql.DB) http.HandlerFunc {
+// ActiveBattleHandler GET /api/battle/active คืน match ที่ผู้เล่นกำลังเล่นอยู่ในรูปแบบเดียวกับ initialData
+// ใช้ให้ client กลับเข้าเกมเดิมได้หลังรีเฟรชหน้า
+func ActiveBattleHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		matchID := vars["matchID"]
-		fmt.Println("[DEBUG] matchID:", matchID)
+		userID, _ := user.UserIDFromContext(r.Context())
 
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+		gs, ok := activeGameState(userID)
+		if !ok {
+			httputil.WriteJSONError(w, http.StatusNotFound, "No active match")
 			return
 		}
 
-		var tokenStr string
-		fmt.Sscanf(authHeader, "Bearer %s", &tokenStr)
-		if tokenStr == "" {
-			http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
+		gs.Lock()
+		if gs.Status != engine.StatusOnGoing {
+			gs.Unlock()
+			httputil.WriteJSONError(w, http.StatusNotFound, "No active match")
 			return
 		}
-		fmt.Println("[DEBUG] token:", tokenStr)
+		res := campaignInitialData(gs)
+		gs.Unlock()
 
-		userID, err := user.ExtractUserIDFromToken(tokenStr)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-		fmt.Println("[DEBUG] userID:", userID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}
+}
+
+func PlayCardHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		matchID := vars["matchID"]
+		userID, _ := user.UserIDFromContext(r.Context())
 
 		bodyBytes, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -352,44 +353,48 @@ func PlayCardHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
-		fmt.Println("[DEBUG] cardID:", req.CardID)
 
-		gameStatesMutex.Lock()
-		gs, ok := gameStates[matchID]
-		gameStatesMutex.Unlock()
+		gs, ok := ownedGameState(w, r)
 		if !ok {
-			fmt.Println("[ERROR] Game state not found for matchID:", matchID)
-			http.Error(w, "Game not started", http.StatusBadRequest)
 			return
 		}
 
 		gs.Lock()
 		defer gs.Unlock()
 
-		var playerCard Card
-		for _, card := range gs.PlayerA.Hand {
-			if card.ID == req.CardID {
-				playerCard = card
-			}
+		if _, err := gs.Apply(engine.Action{Type: engine.ActionPlayCard, Slot: "A", CardID: req.CardID}); err == engine.ErrMatchEnded {
+			httputil.WriteJSONError(w, http.StatusConflict, "Match has ended")
+			return
+		} else if err != nil {
+			fmt.Println("[ERROR] Play card:", err)
+			http.Error(w, "Invalid card", http.StatusBadRequest)
+			return
 		}
-		fmt.Println("[DEBUG] playerCard chosen:", playerCard)
-
-		botIndex := rand.Intn(len(gs.PlayerB.Hand))
-		botCard := gs.PlayerB.Hand[botIndex]
-		fmt.Println("[DEBUG] botCard chosen:", botCard)
+		gs.LastActive = time.Now()
 
-		removeCardFromHand(&gs.PlayerA.Hand, playerCard.ID)
-		removeCardFromHand(&gs.PlayerB.Hand, botCard.ID)
+		botCardID := gs.RandomCardID("B")
+		events, err := gs.Apply(engine.Action{Type: engine.ActionPlayCard, Slot: "B", CardID: botCardID})
+		if err != nil {
+			fmt.Println("[ERROR] Bot play card:", err)
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
 
-		winner := findWinner(playerCard, botCard)
-		fmt.Println("[DEBUG] round winner:", winner)
+		if err := recordMatchEvents(st, matchID, events); err != nil {
+			fmt.Println("[ERROR] recordMatchEvents:", err)
+		}
 
-		damageToA, damageToB, specialEventA, specialEventB := doDamage(&gs.PVPState, playerCard, botCard, winner)
-		fmt.Printf("[DEBUG] Damage A: %d | Damage B: %d\n", damageToA, damageToB)
-		fmt.Printf("[DEBUG] Event A: %+v | Event B: %+v\n", specialEventA, specialEventB)
+		round := engine.FindEvent(events, engine.EventRoundResolved).Round
+		playerCard := round.CardA
+		winner := round.Winner
+		damageToA, damageToB := round.DamageToA, round.DamageToB
+		specialEventA, specialEventB := round.SpecialEventA, round.SpecialEventB
 
-		gameStatus, result, detail, _, _ := checkGameResult(&gs.PVPState)
-		fmt.Printf("[DEBUG] gameStatus: %s | result: %s | detail: %s\n", gameStatus, result, detail)
+		gameStatus := gs.Status
+		var result, detail string
+		if ended := engine.FindEvent(events, engine.EventGameEnded); ended != nil {
+			result, detail = ended.Result.ResultA, ended.Result.DetailA
+		}
 
 		postGameDetail := models.PostGameDetail{
 			Result:   result,
@@ -401,11 +406,9 @@ func PlayCardHandler(db *sql.DB) http.HandlerFunc {
 		}
 
 		if gameStatus == "end" && result == "Win" {
-			statGain, levelGain, expGain, goldGain, err := handlePlayerWin(userID, db, gs.PlayingLevel)
+			statGain, levelGain, expGain, goldGain, err := handlePlayerWin(userID, st, gs.PlayingLevel)
 			if err != nil {
 				fmt.Println("[ERROR] handlePlayerWin:", err)
-			} else {
-				fmt.Printf("[DEBUG] Rewards - EXP: %d, Gold: %d, LvlUp: %d, StatGain: %+v\n", expGain, goldGain, levelGain, statGain)
 			}
 			postGameDetail = models.PostGameDetail{
 				Result:   result,
@@ -415,22 +418,12 @@ func PlayCardHandler(db *sql.DB) http.HandlerFunc {
 				LvlUp:    levelGain,
 				StatGain: statGain,
 			}
+		} else if gameStatus == engine.StatusEnd {
+			resetCampaignStreak(st, userID)
 		}
 
-		// Draw card
-		if gameStatus == "onGoing" {
-			if len(gs.PlayerA.Deck) > 0 && len(gs.PlayerA.Hand) < 3 {
-				gs.PlayerA.Hand = append(gs.PlayerA.Hand, drawCards(&gs.PlayerA.Deck, 1)...)
-				fmt.Println("[DEBUG] Player A draws a card")
-			}
-			if len(gs.PlayerB.Deck) > 0 && len(gs.PlayerB.Hand) < 3 {
-				gs.PlayerB.Hand = append(gs.PlayerB.Hand, drawCards(&gs.PlayerB.Deck, 1)...)
-				fmt.Println("[DEBUG] Player B draws a card")
-			}
-		}
-
-		A_CardRemaining := countCard(append(gs.PlayerA.Deck, gs.PlayerA.Hand...))
-		B_CardRemaining := countCard(append(gs.PlayerB.Deck, gs.PlayerB.Hand...))
+		A_CardRemaining := engine.CountCards(append(gs.PlayerA.Deck, gs.PlayerA.Hand...))
+		B_CardRemaining := engine.CountCards(append(gs.PlayerB.Deck, gs.PlayerB.Hand...))
 
 		res := map[string]interface{}{
 			"type": "round_result",
@@ -446,21 +439,13 @@ func PlayCardHandler(db *sql.DB) http.HandlerFunc {
 			"opponent": map[string]interface{}{
 				"hp":            gs.PlayerB.CurrentHP,
 				"handLength":    len(gs.PlayerB.Hand),
-				"cardPlayed":    botCard,
+				"cardPlayed":    round.CardB,
 				"doDamage":      damageToA,
 				"cardRemaining": B_CardRemaining,
 				"trueSight":     gs.PlayerB.TrueSight,
 				"specialEvent":  specialEventB,
 			},
-			"gameStatus": func() string {
-				switch gameStatus {
-				case "Awin":
-					return "playerWin"
-				case "Bwin":
-					return "opponentWin"
-				}
-				return gameStatus
-			}(),
+			"gameStatus": gameStatus,
 			"roundWinner": func() string {
 				switch winner {
 				case "A":
@@ -472,50 +457,50 @@ func PlayCardHandler(db *sql.DB) http.HandlerFunc {
 			}(),
 			"postGameDetail": postGameDetail,
 		}
+
+		// เกมจบแล้วไม่ต้องเก็บ state ไว้อีก (ผลถูกบันทึกใน match log แล้ว)
+		if gameStatus == engine.StatusEnd {
+			removeGameState(matchID)
+		} else {
+			saveCampaignState(gs)
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(res)
 	}
 
 }
 
-func TrueSightHandler() http.HandlerFunc {
+func TrueSightHandler(st store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		matchID := vars["matchID"]
-		if matchID == "" {
-			http.Error(w, "Missing matchID", http.StatusBadRequest)
-			return
-		}
-
-		gameStatesMutex.Lock()
-		gs, ok := gameStates[matchID]
-		gameStatesMutex.Unlock()
-
+		gs, ok := ownedGameState(w, r)
 		if !ok {
-			http.Error(w, "Game not found", http.StatusNotFound)
 			return
 		}
+		matchID := gs.ID
 
 		gs.Lock()
 		defer gs.Unlock()
 
-		if gs.PlayerA.TrueSight <= 0 {
-			w.WriteHeader(http.StatusForbidden)
-			json.NewEncoder(w).Encode(map[string]string{
-				"type":  "error",
-				"error": "No TrueSight left",
-			})
+		events, err := gs.Apply(engine.Action{Type: engine.ActionUseTrueSight, Slot: "A"})
+		if err == engine.ErrMatchEnded {
+			httputil.WriteJSONError(w, http.StatusConflict, "Match has ended")
+			return
+		} else if err != nil {
+			httputil.WriteJSONError(w, http.StatusForbidden, "No TrueSight left")
 			return
 		}
-
-		gs.PlayerA.TrueSight--
-
-		opponentCardCount := countCard(gs.PlayerB.Hand)
+		gs.LastActive = time.Now()
+		used := events[0]
+		if err := recordMatchEvents(st, matchID, events); err != nil {
+			fmt.Println("[ERROR] recordMatchEvents:", err)
+		}
+		saveCampaignState(gs)
 
 		response := map[string]interface{}{
 			"type":          "true_sight_result",
-			"opponentHand":  opponentCardCount,
-			"trueSightLeft": gs.PlayerA.TrueSight,
+			"opponentHand":  used.OpponentHand,
+			"trueSightLeft": used.TrueSightLeft,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
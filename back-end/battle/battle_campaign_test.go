@@ -0,0 +1,166 @@
+package battle
+
+import (
+	"bytes"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"clash_and_card/user"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// newCampaignTestServer สร้าง router ของ route campaign แบบเดียวกับ main.go บน MemoryStore
+func newCampaignTestServer(t *testing.T) (store.Store, http.Handler) {
+	t.Helper()
+	st := store.NewMemory()
+	user.ConfigureTokens("test-secret", time.Hour, 24*time.Hour)
+	user.ConfigureSessions(st.Sessions())
+
+	r := mux.NewRouter()
+	auth := r.PathPrefix("/api").Subrouter()
+	auth.Use(user.RequireAuth)
+	auth.HandleFunc("/battle/start", StartBattleHandler(st)).Methods("POST")
+	auth.HandleFunc("/battle/active", ActiveBattleHandler()).Methods("GET")
+	auth.HandleFunc("/battle/{matchID}/play", PlayCardHandler(st)).Methods("POST")
+	auth.HandleFunc("/battle/{matchID}/play/true-sight", TrueSightHandler(st)).Methods("POST")
+	return st, r
+}
+
+// newTestUser สร้างผู้เล่นพร้อมเด็คและ session คืน user id กับ access token
+func newTestUser(t *testing.T, st store.Store) (string, string) {
+	t.Helper()
+	userID := uuid.New().String()
+	u := &models.User{
+		ID:       userID,
+		Username: "Player",
+		Email:    userID + "@example.com",
+		Stat:     models.UnitStat{Atk: 10, Def: 10, HP: 100, Spd: 10},
+		Level:    1,
+		Class:    "warrior",
+		Rating:   models.InitialRating,
+	}
+	deck := []models.DeckCard{
+		{CardType: "rock", Quantity: 5},
+		{CardType: "paper", Quantity: 5},
+		{CardType: "scissors", Quantity: 5},
+	}
+	if err := st.Users().CreateUser(u, "hash", deck); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	sessionID := uuid.New().String()
+	if err := st.Sessions().CreateSession(sessionID, userID, "refresh-"+sessionID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	token, err := user.CreateToken(userID, sessionID)
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+	return userID, token
+}
+
+func doRequest(h http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// campaignSnapshot คืน state ของ match ในรูป JSON ไว้เทียบว่าไม่มีอะไรเปลี่ยน
+func campaignSnapshot(t *testing.T, matchID string) []byte {
+	t.Helper()
+	gameStatesMutex.Lock()
+	gs, ok := gameStates[matchID]
+	gameStatesMutex.Unlock()
+	if !ok {
+		t.Fatalf("match %s not found", matchID)
+	}
+	gs.Lock()
+	defer gs.Unlock()
+	snap, err := gs.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	data, _ := json.Marshal(snap)
+	return data
+}
+
+func TestCampaignCrossUserAccessDenied(t *testing.T) {
+	st, h := newCampaignTestServer(t)
+	_, tokenA := newTestUser(t, st)
+	_, tokenB := newTestUser(t, st)
+
+	rec := doRequest(h, "POST", "/api/battle/start", tokenA, map[string]int{"levelId": 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("start match: status %d body %s", rec.Code, rec.Body)
+	}
+	var started struct {
+		MatchID string `json:"matchID"`
+		Player  struct {
+			Hand []struct {
+				ID string `json:"id"`
+			} `json:"hand"`
+		} `json:"player"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&started); err != nil || started.MatchID == "" || len(started.Player.Hand) == 0 {
+		t.Fatalf("start match: bad response %v %+v", err, started)
+	}
+	t.Cleanup(func() {
+		gameStatesMutex.Lock()
+		delete(gameStates, started.MatchID)
+		gameStatesMutex.Unlock()
+	})
+	before := campaignSnapshot(t, started.MatchID)
+	cardID := started.Player.Hand[0].ID
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		want   int
+	}{
+		{"play card as other user", "POST", "/api/battle/" + started.MatchID + "/play", tokenB, map[string]string{"cardID": cardID}, http.StatusForbidden},
+		{"true sight as other user", "POST", "/api/battle/" + started.MatchID + "/play/true-sight", tokenB, nil, http.StatusForbidden},
+		{"active match of other user", "GET", "/api/battle/active", tokenB, nil, http.StatusNotFound},
+		{"play card without token", "POST", "/api/battle/" + started.MatchID + "/play", "", map[string]string{"cardID": cardID}, http.StatusUnauthorized},
+		{"true sight without token", "POST", "/api/battle/" + started.MatchID + "/play/true-sight", "", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, tt.method, tt.path, tt.token, tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code == http.StatusOK {
+				return
+			}
+			var resp map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp["type"] != "error" {
+				t.Fatalf("expected JSON error body, got %v %v", resp, err)
+			}
+		})
+	}
+
+	if after := campaignSnapshot(t, started.MatchID); !bytes.Equal(before, after) {
+		t.Fatalf("match state changed after denied requests\nbefore %s\nafter  %s", before, after)
+	}
+
+	// เจ้าของยังเล่นได้ตามปกติ
+	if rec := doRequest(h, "POST", "/api/battle/"+started.MatchID+"/play", tokenA, map[string]string{"cardID": cardID}); rec.Code != http.StatusOK {
+		t.Fatalf("owner play card: status %d body %s", rec.Code, rec.Body)
+	}
+}
@@ -1,16 +1,18 @@
 package battle
 
 import (
-	"clash_and_card/models"
+	"clash_and_card/engine"
+	"clash_and_card/store"
 	"clash_and_card/user"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -29,19 +31,73 @@ const (
 type Message struct {
 	Type   string `json:"type"`
 	CardID string `json:"cardID,omitempty"` // ใช้เมื่อ Type = "selected_card"
+	BestOf int    `json:"bestOf,omitempty"` // ใช้เมื่อ Type = "rematch_request" (1, 3 หรือ 5)
+	RoomID string `json:"roomID,omitempty"` // ใช้เมื่อ Type = "challenge_decline" บน /ws/notifications
 }
 
 type PVPClient struct {
 	conn   *websocket.Conn
 	roomID string
-	slot   string // "A" หรือ "B"
+	slot   string // "A" หรือ "B" ว่างถ้าเป็นผู้ชม
 	userID string // เก็บ userID ที่ถอดจาก token
 	send   chan []byte
+	st     store.Store
 }
 
 type PVPMatch struct {
-	Clients  map[string]*PVPClient // key: "A", "B"
-	Selected map[string]*Card      // key: slot, value: selected card
+	Clients map[string]*PVPClient // key: "A", "B"
+	// Players ผู้เล่นที่ได้รับสิทธิ์เข้าห้องนี้ (slot -> user id) ห้องถูกสร้างโดยเซิร์ฟเวอร์เท่านั้น
+	Players   map[string]string
+	CreatedAt time.Time
+	// Disconnected slot ที่หลุดกลางเกม -> เวลาที่หลุด ลบออกเมื่อกลับเข้ามา (ดู reconnect.go)
+	Disconnected map[string]time.Time
+	// Spectators ผู้ชมที่ดูห้องนี้อยู่ (ดู spectator.go)
+	Spectators map[*PVPClient]bool
+
+	// Rules กติกาของเกมในห้องนี้ Ranked นับ rating และคะแนนฤดูกาลหรือไม่
+	Rules  RoomRules
+	Ranked bool
+	// ใช้เฉพาะห้อง private (ดู private_room.go) InviteCode ว่างคือห้องจาก matchmaking
+	InviteCode   string
+	PasswordHash []byte
+	Kicked       map[string]bool // user id ที่ host เตะออกแล้ว เข้าห้องนี้อีกไม่ได้
+	challenged   string          // ห้องที่สร้างจากการท้าเพื่อน (ดู friends.go) มีแค่คนนี้ที่เข้าได้
+
+	starting bool // กำลังโหลด match อยู่ กันไม่ให้เริ่มซ้ำ
+
+	// หลังเกมจบ รอเกมถัดไปของ series หรือ rematch (ดู series.go)
+	waitingGame   string          // match id ของเกมที่จบแล้ว ว่างคือไม่ได้รออยู่
+	afterGame     *time.Timer     // เริ่มเกมถัดไปหรือปิดห้องเมื่อหมดเวลา
+	rematch       map[string]bool // slot ที่ขอ rematch แล้ว
+	rematchBestOf int
+
+	tournament tournamentRef // ห้องของคู่ในการแข่งขัน (ดู tournament.go)
+}
+
+// newPVPRoom สร้างห้องใหม่ที่ให้ userAID และ userBID เข้าได้ ต้องถือ pvpManager.lock ก่อนเรียก
+func newPVPRoom(roomID, userAID, userBID string) *PVPMatch {
+	match := &PVPMatch{
+		Clients:      make(map[string]*PVPClient),
+		Players:      map[string]string{"A": userAID, "B": userBID},
+		CreatedAt:    time.Now(),
+		Disconnected: make(map[string]time.Time),
+		Spectators:   make(map[*PVPClient]bool),
+		Rules:        defaultRoomRules(),
+		Ranked:       true,
+		Kicked:       make(map[string]bool),
+	}
+	pvpManager.rooms[roomID] = match
+	return match
+}
+
+// pvpUserInRoom ผู้เล่นคนนี้มีห้องที่ยังไม่จบอยู่หรือไม่ ต้องถือ pvpManager.lock ก่อนเรียก
+func pvpUserInRoom(userID string) bool {
+	for _, match := range pvpManager.rooms {
+		if match.Players["A"] == userID || match.Players["B"] == userID {
+			return true
+		}
+	}
+	return false
 }
 
 type PVPManager struct {
@@ -61,86 +117,96 @@ var (
 
 type PVPState struct {
 	sync.Mutex // ล็อกภายใน state เอง
-	PlayerA    PlayerData
-	PlayerB    PlayerData
-}
-
-type Stat struct {
-	ATK int
-	DEF int
-	SPD int
-	HP  int
+	engine.Match
+	ID string // match id ที่ใช้บันทึก replay
+
+	// ใช้เฉพาะ PvP
+	RoomID  string
+	UserIDs map[string]string // slot -> user id ใช้ให้ผู้เล่นกลับเข้าห้องเดิมได้ slot เดิม
+	Rules   RoomRules
+	Ranked  bool
+	Series  SeriesScore // คะแนน series ของห้องนี้รวมเกมนี้ (ดู series.go)
+
+	tournament tournamentRef
+
+	// ตัวนับเวลาต่อรอบ ไม่ได้อยู่ใน snapshot เริ่มนับใหม่เมื่อโหลดกลับมา
+	turnDeadline time.Time
+	turnTimer    *time.Timer
+	timeouts     map[string]int  // slot -> จำนวนครั้งที่หมดเวลาติดกัน
+	autoPlayed   map[string]bool // slot ที่เซิร์ฟเวอร์เลือกการ์ดแทนในรอบนี้
 }
 
-type PlayerData struct {
-	//ID        int
-	Name      string
-	Level     int
-	CurrentHP int
-	Deck      []Card
-	Hand      []Card
-	Stat      Stat
-	Class     string
-	TrueSight int
-}
-
-func loadPVPStateFromDB(db *sql.DB, userAID, userBID string) (*PVPState, error) {
-	userA, err := getUserByIDFromDB(db, userAID)
+// loadPVPStateFromDB สร้าง match ใหม่จากข้อมูลผู้เล่นในฐานข้อมูลตามกติกา rules
+func loadPVPStateFromDB(st store.Store, userAID, userBID string, rules RoomRules) (*PVPState, error) {
+	userA, err := st.Users().GetUser(userAID)
 	if err != nil {
 		return nil, err
 	}
-	userB, err := getUserByIDFromDB(db, userBID)
+	userB, err := st.Users().GetUser(userBID)
 	if err != nil {
 		return nil, err
 	}
 
-	deckA, err := getDeckByUserIDFromDB(db, userAID)
+	deckA, err := getDeckCards(st, userAID)
 	if err != nil {
 		return nil, err
 	}
-	deckB, err := getDeckByUserIDFromDB(db, userBID)
+	deckB, err := getDeckCards(st, userBID)
 	if err != nil {
 		return nil, err
 	}
 
+	hpA := int(math.Round(float64(userA.Stat.HP) * rules.HPMultiplier))
+	hpB := int(math.Round(float64(userB.Stat.HP) * rules.HPMultiplier))
+
 	state := &PVPState{
-		PlayerA: PlayerData{
-			Name:      userA.Username,
-			Level:     userA.Level,
-			Deck:      deckA,
-			CurrentHP: userA.Stat.HP,
-			Hand:      []Card{},
-			Stat: Stat{
-				ATK: userA.Stat.Atk,
-				DEF: userA.Stat.Def,
-				SPD: userA.Stat.Spd,
-				HP:  userA.Stat.HP,
+		Match: engine.Match{
+			HandSize: rules.HandSize,
+			PlayerA: engine.PlayerData{
+				Name:      userA.Username,
+				Level:     userA.Level,
+				Deck:      deckA,
+				CurrentHP: hpA,
+				Stat: engine.Stat{
+					ATK: userA.Stat.Atk,
+					DEF: userA.Stat.Def,
+					SPD: userA.Stat.Spd,
+					HP:  hpA,
+				},
+				Class:     userA.Class,
+				TrueSight: 0,
 			},
-			Class:     userA.Class,
-			TrueSight: 0,
-		},
-		PlayerB: PlayerData{
-			Name:      userB.Username,
-			Level:     userB.Level,
-			Deck:      deckB,
-			Hand:      []Card{},
-			CurrentHP: userB.Stat.HP,
-			Stat: Stat{
-				ATK: userB.Stat.Atk,
-				DEF: userB.Stat.Def,
-				SPD: userB.Stat.Spd,
-				HP:  userB.Stat.HP,
+			PlayerB: engine.PlayerData{
+				Name:      userB.Username,
+				Level:     userB.Level,
+				Deck:      deckB,
+				CurrentHP: hpB,
+				Stat: engine.Stat{
+					ATK: userB.Stat.Atk,
+					DEF: userB.Stat.Def,
+					SPD: userB.Stat.Spd,
+					HP:  hpB,
+				},
+				Class:     userB.Class,
+				TrueSight: 0,
 			},
-			Class:     userB.Class,
-			TrueSight: 0,
 		},
 	}
+	state.ID = uuid.New().String()
+	state.UserIDs = map[string]string{"A": userAID, "B": userBID}
+	state.Rules = rules
+	seed := newMatchSeed()
+	if err := recordMatchStart(st, state.ID, modePVP, userAID, userBID, seed, state); err != nil {
+		log.Println("recordMatchStart error:", err)
+	}
+	state.Start(seed)
+	log.Println("PvP match", state.ID, "started")
 
 	return state, nil
 }
 
 // Handler
-func HandlePVPWebSocket(db *sql.DB) http.HandlerFunc {
+func HandlePVPWebSocket(st store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		tokenStr := r.Header.Get("Sec-WebSocket-Protocol")
@@ -159,36 +225,37 @@ func HandlePVPWebSocket(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		conn, err := upgrader.Upgrade(w, r, header) // ต้องใส่ header กลับไป
-		if err != nil {
-			log.Println("WebSocket upgrade error:", err)
-			return
-		}
-
 		roomID := r.URL.Query().Get("room")
 		if roomID == "" {
 			http.Error(w, "room required", http.StatusBadRequest)
 			return
 		}
+		spectate := r.URL.Query().Get("spectate") == "true"
+
+		conn, err := upgrader.Upgrade(w, r, header) // ต้องใส่ header กลับไป
+		if err != nil {
+			log.Println("WebSocket upgrade error:", err)
+			return
+		}
 
 		pvpManager.lock.Lock()
 		match, exists := pvpManager.rooms[roomID]
 		if !exists {
-			match = &PVPMatch{
-				Clients:  make(map[string]*PVPClient),
-				Selected: make(map[string]*Card),
-			}
-			pvpManager.rooms[roomID] = match
+			pvpManager.lock.Unlock()
+			pvpReject(conn, "room not found")
+			return
+		}
+
+		if spectate {
+			pvpManager.lock.Unlock()
+			pvpJoinAsSpectator(st, conn, roomID, userID)
+			return
 		}
 
-		var slot string
-		if _, ok := match.Clients["A"]; !ok {
-			slot = "A"
-		} else if _, ok := match.Clients["B"]; !ok {
-			slot = "B"
-		} else {
+		slot, ok := pvpAssignSlot(match, userID)
+		if !ok {
 			pvpManager.lock.Unlock()
-			http.Error(w, "room full", http.StatusForbidden)
+			pvpReject(conn, "room full")
 			return
 		}
 
@@ -198,139 +265,222 @@ func HandlePVPWebSocket(db *sql.DB) http.HandlerFunc {
 			slot:   slot,
 			userID: userID,
 			send:   make(chan []byte, 256),
+			st:     st,
 		}
 
+		// เชื่อมต่อซ้ำขณะที่ connection เดิมยังไม่ตาย (เช่นเน็ตหลุดแล้วต่อใหม่) ให้ connection ใหม่ได้ slot ไป
+		previous := match.Clients[slot]
 		match.Clients[slot] = client
+		delete(match.Disconnected, slot)
+		pvpStatesMu.Lock()
+		_, started := pvpStates[roomID]
+		pvpStatesMu.Unlock()
+		// ห้อง private รอ host ส่ง start_match ห้องอื่นเริ่มทันทีที่ครบสองคน
+		startNow := !started && !match.starting && len(match.Clients) == 2 && match.InviteCode == ""
+		var userAID, userBID string
+		if startNow {
+			match.starting = true
+			userAID, userBID = match.Clients["A"].userID, match.Clients["B"].userID
+		}
 		pvpManager.lock.Unlock()
 
-		if len(match.Clients) == 2 {
-			clientA := match.Clients["A"]
-			clientB := match.Clients["B"]
+		if previous != nil {
+			pvpKick(previous, "connected from another session")
+		}
 
-			go func() {
-				state, err := loadPVPStateFromDB(db, clientA.userID, clientB.userID)
-				if err != nil {
-					log.Println("loadPVPStateFromDB error:", err)
-					return
-				}
+		// buffer ยังว่าง ส่งได้ทันทีโดยไม่ block และมาก่อนข้อมูลเกมเสมอ
+		respJSON, _ := json.Marshal(map[string]interface{}{
+			"type": "slot_assigned",
+			"slot": slot,
+		})
+		client.send <- respJSON
+
+		if started {
+			pvpResync(client)
+		} else if startNow {
+			go pvpStartOrResume(st, roomID, userAID, userBID)
+		}
 
-				pvpManager.lock.Lock()
-				match, ok := pvpManager.rooms[roomID]
-				if !ok {
-					pvpManager.lock.Unlock()
-					return
-				}
-				clientA, okA := match.Clients["A"]
-				clientB, okB := match.Clients["B"]
-				pvpManager.lock.Unlock()
-
-				state.PlayerA.Hand = drawCards(&state.PlayerA.Deck, 3)
-				state.PlayerB.Hand = drawCards(&state.PlayerB.Deck, 3)
-
-				A_CardRemaining := countCard(append(state.PlayerA.Deck, state.PlayerA.Hand...))
-				B_CardRemaining := countCard(append(state.PlayerB.Deck, state.PlayerB.Hand...))
-
-				if okA {
-					response := map[string]interface{}{
-						"type": "initialData",
-						"player": map[string]interface{}{
-							"name":          state.PlayerA.Name,
-							"level":         state.PlayerA.Level,
-							"currentHP":     state.PlayerA.CurrentHP,
-							"cardRemaining": A_CardRemaining,
-							"hand":          state.PlayerA.Hand,
-							"stat": map[string]interface{}{
-								"atk": state.PlayerA.Stat.ATK,
-								"def": state.PlayerA.Stat.DEF,
-								"spd": state.PlayerA.Stat.SPD,
-								"hp":  state.PlayerA.Stat.HP,
-							},
-							"class":     state.PlayerA.Class,
-							"trueSight": state.PlayerA.TrueSight,
-						},
-						"opponent": map[string]interface{}{
-							"name":          state.PlayerB.Name,
-							"level":         state.PlayerB.Level,
-							"currentHP":     state.PlayerB.CurrentHP,
-							"cardRemaining": B_CardRemaining,
-							"handSize":      len(state.PlayerB.Hand),
-							"stat": map[string]interface{}{
-								"atk": state.PlayerB.Stat.ATK,
-								"def": state.PlayerB.Stat.DEF,
-								"spd": state.PlayerB.Stat.SPD,
-								"hp":  state.PlayerB.Stat.HP,
-							},
-							"class":     state.PlayerB.Class,
-							"trueSight": state.PlayerB.TrueSight,
-						},
-					}
-					respJSON, err := json.Marshal(response)
-					if err == nil {
-						clientA.send <- respJSON
-					}
-				}
-				if okB {
-					response := map[string]interface{}{
-						"type":             "initialData",
-						"opponentHandSize": len(state.PlayerA.Hand),
-						"player": map[string]interface{}{
-							"name":          state.PlayerB.Name,
-							"level":         state.PlayerB.Level,
-							"currentHP":     state.PlayerB.CurrentHP,
-							"cardRemaining": B_CardRemaining,
-							"hand":          state.PlayerB.Hand,
-							"stat": map[string]interface{}{
-								"atk": state.PlayerB.Stat.ATK,
-								"def": state.PlayerB.Stat.DEF,
-								"spd": state.PlayerB.Stat.SPD,
-								"hp":  state.PlayerB.Stat.HP,
-							},
-							"class":     state.PlayerB.Class,
-							"trueSight": state.PlayerB.TrueSight,
-						},
-						"opponent": map[string]interface{}{
-							"handSize":      len(state.PlayerA.Hand),
-							"name":          state.PlayerA.Name,
-							"level":         state.PlayerA.Level,
-							"currentHP":     state.PlayerA.CurrentHP,
-							"cardRemaining": A_CardRemaining,
-							"stat": map[string]interface{}{
-								"atk": state.PlayerA.Stat.ATK,
-								"def": state.PlayerA.Stat.DEF,
-								"spd": state.PlayerA.Stat.SPD,
-								"hp":  state.PlayerA.Stat.HP,
-							},
-							"class":     state.PlayerA.Class,
-							"trueSight": state.PlayerA.TrueSight,
-						},
-					}
-					respJSON, err := json.Marshal(response)
-					if err == nil {
-						clientB.send <- respJSON
-					}
-				}
-				//logPVPState(roomID, state)
-				pvpStatesMu.Lock()
-				pvpStates[roomID] = state
-				pvpStatesMu.Unlock()
-			}()
+		go pvpRead(client)
+		go pvpWrite(client)
+
+	}
+}
+
+// pvpReject ส่ง error ให้ client แล้วปิด connection (หลัง upgrade แล้วตอบเป็น HTTP ไม่ได้)
+func pvpReject(conn *websocket.Conn, msg string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":  "error",
+		"error": msg,
+	})
+	conn.WriteMessage(websocket.TextMessage, data)
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, msg))
+	conn.Close()
+}
+
+// pvpKick ส่ง error ให้ client ที่เชื่อมต่ออยู่แล้วตัดการเชื่อมต่อ
+// (ส่งผ่าน send เพราะ websocket เขียนพร้อมกันจากหลาย goroutine ไม่ได้)
+func pvpKick(c *PVPClient, msg string) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":  "error",
+		"error": msg,
+	})
+	select {
+	case c.send <- data:
+	default:
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		c.conn.Close()
+	}()
+}
+
+// pvpAssignSlot คืน slot ที่ผู้เล่นคนนี้ได้รับในห้อง คนที่ไม่ได้รับสิทธิ์เข้าไม่ได้
+// ถ้า slot มี connection อยู่แล้วก็ยังคืน slot นั้น (ผู้เล่นคนเดิมต่อใหม่) ต้องถือ pvpManager.lock ก่อนเรียก
+func pvpAssignSlot(match *PVPMatch, userID string) (string, bool) {
+	for _, slot := range []string{"A", "B"} {
+		if match.Players[slot] == userID {
+			return slot, true
 		}
+	}
+	return "", false
+}
 
-		// ส่ง slot assigned ทันทีหลัง unlock
-		go func() {
-			response := map[string]interface{}{
-				"type": "slot_assigned",
-				"slot": slot,
-			}
-			respJSON, err := json.Marshal(response)
-			if err == nil {
-				client.send <- respJSON
+// pvpStartOrResume เริ่ม match ใหม่เมื่อผู้เล่นครบสองคน หรือถ้าห้องนี้มี match ที่ยังไม่จบอยู่แล้ว
+// (เช่นโหลดกลับมาหลังรีสตาร์ท) ก็ส่งสถานะปัจจุบันให้ทั้งสองฝั่งเล่นต่อ
+func pvpStartOrResume(st store.Store, roomID, userAID, userBID string) {
+	pvpStatesMu.Lock()
+	state, resumed := pvpStates[roomID]
+	pvpStatesMu.Unlock()
+
+	if !resumed {
+		var err error
+		state, err = pvpNewGame(st, roomID, userAID, userBID, nil)
+		if err != nil {
+			log.Println("loadPVPStateFromDB error:", err)
+			pvpManager.lock.Lock()
+			if room, ok := pvpManager.rooms[roomID]; ok {
+				room.starting = false
 			}
-		}()
+			pvpManager.lock.Unlock()
+			return
+		}
+	}
+	pvpPublishGame(st, roomID, state, !resumed)
+}
 
-		go pvpRead(client)
-		go pvpWrite(client)
+// pvpNewGame โหลด match ใหม่ของห้องตามกติกาของห้อง series เป็น nil คือเริ่ม series ใหม่ตาม RoomRules.BestOf
+func pvpNewGame(st store.Store, roomID, userAID, userBID string, series *SeriesScore) (*PVPState, error) {
+	pvpManager.lock.Lock()
+	room, ok := pvpManager.rooms[roomID]
+	if !ok {
+		pvpManager.lock.Unlock()
+		return nil, fmt.Errorf("no PvP room %s", roomID)
+	}
+	rules, ranked, tournament := room.Rules, room.Ranked, room.tournament
+	pvpManager.lock.Unlock()
+
+	state, err := loadPVPStateFromDB(st, userAID, userBID, rules)
+	if err != nil {
+		return nil, err
+	}
+	state.RoomID = roomID
+	state.Ranked = ranked
+	state.tournament = tournament
+	if series != nil {
+		state.Series = *series
+	} else {
+		state.Series = newSeries(rules.BestOf)
+	}
+	return state, nil
+}
+
+// pvpPublishGame ส่งสถานะเริ่มเกมให้ผู้เล่นและผู้ชมในห้อง isNew คือ state เพิ่งสร้าง ต้องเก็บลง pvpStates และบันทึก snapshot
+func pvpPublishGame(st store.Store, roomID string, state *PVPState, isNew bool) {
+	pvpManager.lock.Lock()
+	match, ok := pvpManager.rooms[roomID]
+	if !ok {
+		pvpManager.lock.Unlock()
+		return
+	}
+	clientA, okA := match.Clients["A"]
+	clientB, okB := match.Clients["B"]
+	pvpManager.lock.Unlock()
+
+	state.Lock()
+	if state.turnTimer == nil {
+		pvpStartTurn(st, state)
+	}
+	dataA, _ := json.Marshal(pvpInitialData(state, "A"))
+	dataB, _ := json.Marshal(pvpInitialData(state, "B"))
+	spectateState := pvpSpectateState(state)
+	if isNew {
+		savePVPState(state)
+	}
+	state.Unlock()
+
+	//logPVPState(roomID, state)
+	if isNew {
+		pvpStatesMu.Lock()
+		pvpStates[roomID] = state
+		pvpStatesMu.Unlock()
+	}
+
+	if okA {
+		clientA.send <- dataA
+	}
+	if okB {
+		clientB.send <- dataB
+	}
 
+	pvpManager.lock.Lock()
+	pvpBroadcastSpectators(match, spectateState)
+	pvpManager.lock.Unlock()
+}
+
+// pvpInitialData ข้อมูลเริ่มเกม (หรือสถานะปัจจุบัน) จากมุมมองของ slot ต้องถือ lock ของ state ก่อนเรียก
+func pvpInitialData(state *PVPState, slot string) map[string]interface{} {
+	player, opponent := &state.PlayerA, &state.PlayerB
+	if slot == "B" {
+		player, opponent = &state.PlayerB, &state.PlayerA
+	}
+
+	return map[string]interface{}{
+		"type":             "initialData",
+		"matchID":          state.ID,
+		"opponentHandSize": len(opponent.Hand),
+		"turnDeadline":     state.turnDeadline,
+		"series":           state.Series.view(slot),
+		"player": map[string]interface{}{
+			"name":          player.Name,
+			"level":         player.Level,
+			"currentHP":     player.CurrentHP,
+			"cardRemaining": engine.CountCards(append(player.Deck, player.Hand...)),
+			"hand":          player.Hand,
+			"stat": map[string]interface{}{
+				"atk": player.Stat.ATK,
+				"def": player.Stat.DEF,
+				"spd": player.Stat.SPD,
+				"hp":  player.Stat.HP,
+			},
+			"class":     player.Class,
+			"trueSight": player.TrueSight,
+		},
+		"opponent": map[string]interface{}{
+			"name":          opponent.Name,
+			"level":         opponent.Level,
+			"currentHP":     opponent.CurrentHP,
+			"cardRemaining": engine.CountCards(append(opponent.Deck, opponent.Hand...)),
+			"handSize":      len(opponent.Hand),
+			"stat": map[string]interface{}{
+				"atk": opponent.Stat.ATK,
+				"def": opponent.Stat.DEF,
+				"spd": opponent.Stat.SPD,
+				"hp":  opponent.Stat.HP,
+			},
+			"class":     opponent.Class,
+			"trueSight": opponent.TrueSight,
+		},
 	}
 }
 
@@ -353,246 +503,30 @@ func pvpRead(c *PVPClient) {
 		}
 
 		switch m.Type {
-		case "selected_card":
-			// ตรวจสอบและดึง state กับ match
-			pvpStatesMu.Lock()
-			state, ok := pvpStates[c.roomID]
-			pvpStatesMu.Unlock()
-			if !ok {
-				fmt.Println("Error PvP State")
-				return
-			}
+		case "start_match":
+			pvpHostStart(c)
 
-			pvpManager.lock.Lock()
-			match, ok := pvpManager.rooms[c.roomID]
-			if !ok {
-				pvpManager.lock.Unlock()
-				return
+		case "selected_card":
+			err := pvpSelectCard(c.st, c.roomID, c.slot, m.CardID, false)
+			if err == engine.ErrMatchEnded {
+				// ระหว่างรอเกมถัดไปหรือ rematch ไม่ต้องตัด connection
+				pvpSend(c, map[string]interface{}{"type": "error", "error": err.Error()})
+				continue
 			}
-
-			pvpManager.lock.Unlock()
-
-			// แก้ไขมือผู้เล่น
-			state.Lock()
-
-			var hand []Card
-			switch c.slot {
-			case "A":
-				hand = state.PlayerA.Hand
-			case "B":
-				hand = state.PlayerB.Hand
-			default:
-				state.Unlock()
-				fmt.Println("Invalid slot:", c.slot)
+			if err != nil {
+				fmt.Println("Select card error:", err)
 				return
 			}
 
-			var playerCard *Card
-			for _, card := range hand {
-				if card.ID == m.CardID {
-					playerCard = &card
-				}
-			}
-
-			if playerCard == nil {
-				state.Unlock()
-				fmt.Println("Card not found in hand")
-				return
-			}
+		case "rematch_request":
+			pvpRematchRequest(c, m.BestOf)
 
-			// อัปเดต selected card ของฝั่งนั้น
-			fmt.Println("CT " + CardType(playerCard.Type))
-			match.Selected[c.slot] = playerCard
-			fmt.Println("Player ", c.slot, " Selected Card")
+		case "rematch_accept":
+			pvpRematchAccept(c)
 
-			state.Unlock()
+		case "rematch_decline":
+			pvpRematchDecline(c)
 
-			//sent to opponent
-			switch c.slot {
-			case "A":
-				responseForB := map[string]interface{}{
-					"type":             "selection_status",
-					"playerSelected":   match.Selected["B"] != nil,
-					"opponentSelected": match.Selected["A"] != nil,
-				}
-
-				respBJSON, _ := json.Marshal(responseForB)
-				// A เป็นคนเลือก → ส่งให้ B
-				if clientB, ok := match.Clients["B"]; ok {
-					select {
-					case clientB.send <- respBJSON:
-					default:
-					}
-				}
-			case "B":
-				// สร้าง response ที่แยกฝั่ง
-				responseForA := map[string]interface{}{
-					"type":             "selection_status",
-					"playerSelected":   match.Selected["A"] != nil,
-					"opponentSelected": match.Selected["B"] != nil,
-				}
-				respAJSON, _ := json.Marshal(responseForA)
-
-				// B เป็นคนเลือก → ส่งให้ A
-				if clientA, ok := match.Clients["A"]; ok {
-					select {
-					case clientA.send <- respAJSON:
-					default:
-					}
-				}
-			}
-
-			//เช็คว่าเลือกครบ 2 คนยัง
-			if match.Selected["A"] != nil && match.Selected["B"] != nil {
-
-				// remove card from hand
-				removeCardFromHand(&state.PlayerA.Hand, match.Selected["A"].ID)
-				removeCardFromHand(&state.PlayerB.Hand, match.Selected["B"].ID)
-
-				var winner = findWinner(*match.Selected["A"], *match.Selected["B"])
-
-				damageToA, damageToB, specialEventA, specialEventB := doDamage(state, *match.Selected["A"], *match.Selected["B"], winner)
-
-				gameStatus, resultA, detailA, resultB, detailB := checkGameResult(state)
-
-				postGameDetailA := models.PostGameDetail{
-					Result:   resultA,
-					Detail:   detailA,
-					Exp:      0,
-					Gold:     0,
-					LvlUp:    0,
-					StatGain: models.UnitStat{Atk: 0, Def: 0, Spd: 0, HP: 0},
-				}
-
-				postGameDetailB := models.PostGameDetail{
-					Result:   resultB,
-					Detail:   detailB,
-					Exp:      0,
-					Gold:     0,
-					LvlUp:    0,
-					StatGain: models.UnitStat{Atk: 0, Def: 0, Spd: 0, HP: 0},
-				}
-
-				//draw card
-				if gameStatus == "onGoing" {
-					if len(state.PlayerA.Deck) > 0 && len(state.PlayerA.Hand) < 3 {
-						state.PlayerA.Hand = append(state.PlayerA.Hand, drawCards(&state.PlayerA.Deck, 1)...)
-					}
-					if len(state.PlayerB.Deck) > 0 && len(state.PlayerB.Hand) < 3 {
-						state.PlayerB.Hand = append(state.PlayerB.Hand, drawCards(&state.PlayerB.Deck, 1)...)
-					}
-				}
-
-				A_CardRemaining := countCard(append(state.PlayerA.Deck, state.PlayerA.Hand...))
-				B_CardRemaining := countCard(append(state.PlayerB.Deck, state.PlayerB.Hand...))
-
-				//ส่งผลลัพธ์แยกกัน
-				respA := map[string]interface{}{
-					"type": "round_result",
-					"player": map[string]interface{}{
-						"hp":            state.PlayerA.CurrentHP,
-						"hand":          state.PlayerA.Hand,
-						"cardPlayed":    match.Selected["A"],
-						"doDamage":      damageToB,
-						"cardRemaining": A_CardRemaining,
-						"trueSight":     state.PlayerA.TrueSight,
-						"specialEvent":  specialEventA,
-					},
-					"opponent": map[string]interface{}{
-						"hp":            state.PlayerB.CurrentHP,
-						"handLength":    len(state.PlayerB.Hand),
-						"cardPlayed":    match.Selected["B"],
-						"doDamage":      damageToA,
-						"cardRemaining": B_CardRemaining,
-						"trueSight":     state.PlayerB.TrueSight,
-						"specialEvent":  specialEventB,
-					},
-					"gameStatus": func() string {
-						if gameStatus == "Awin" {
-							return "playerWin"
-						} else if gameStatus == "Bwin" {
-							return "opponentWin"
-						}
-						return gameStatus
-					}(),
-					"roundWinner": func() string {
-						if winner == "A" {
-							return "player"
-						} else if winner == "B" {
-							return "opponent"
-						}
-						return "draw"
-					}(),
-					"postGameDetail": postGameDetailA,
-				}
-				respB := map[string]interface{}{
-					"type": "round_result",
-					"player": map[string]interface{}{
-						"hp":            state.PlayerB.CurrentHP,
-						"hand":          state.PlayerB.Hand,
-						"cardPlayed":    match.Selected["B"],
-						"doDamage":      damageToA,
-						"cardRemaining": B_CardRemaining,
-						"trueSight":     state.PlayerB.TrueSight,
-						"specialEvent":  specialEventB,
-					},
-					"opponent": map[string]interface{}{
-						"hp":            state.PlayerA.CurrentHP,
-						"handLength":    len(state.PlayerA.Hand),
-						"cardPlayed":    match.Selected["A"],
-						"doDamage":      damageToB,
-						"cardRemaining": A_CardRemaining,
-						"trueSight":     state.PlayerA.TrueSight,
-						"specialEvent":  specialEventA,
-					},
-					"gameStatus": func() string {
-						if gameStatus == "Bwin" {
-							return "playerWin"
-						} else if gameStatus == "Awin" {
-							return "opponentWin"
-						}
-						return gameStatus
-					}(),
-					"roundWinner": func() string {
-						if winner == "B" {
-							return "player"
-						} else if winner == "A" {
-							return "opponent"
-						}
-						return "draw"
-					}(),
-					"postGameDetail": postGameDetailB,
-				}
-
-				respAJSON, _ := json.Marshal(respA)
-				respBJSON, _ := json.Marshal(respB)
-
-				// ส่งกลับ client A และ B
-				if clientA, ok := match.Clients["A"]; ok {
-					select {
-					case clientA.send <- respAJSON:
-					default:
-					}
-				}
-				if clientB, ok := match.Clients["B"]; ok {
-					select {
-					case clientB.send <- respBJSON:
-					default:
-					}
-				}
-
-				if gameStatus == "Awin" || gameStatus == "Bwin" || gameStatus == "draw" {
-					go func(roomID string) {
-						time.Sleep(100 * time.Millisecond)
-						disconnectAllClients(roomID)
-					}(c.roomID)
-				}
-
-				//logPVPState(c.roomID, state)
-				pvpManager.lock.Lock()
-				match.Selected = make(map[string]*Card)
-				pvpManager.lock.Unlock()
-			}
 		case "use_true_sight":
 
 			pvpStatesMu.Lock()
@@ -603,25 +537,30 @@ func pvpRead(c *PVPClient) {
 				return
 			}
 
+			// ห้องอาจถูกลบหรือผู้เล่น reconnect ระหว่างนี้ ดึง client ครั้งเดียวระหว่างถือ lock
+			pvpManager.lock.Lock()
+			var self, opponent *PVPClient
+			if match, ok := pvpManager.rooms[c.roomID]; ok {
+				self = match.Clients[c.slot]
+				opponent = match.Clients[opponentSlotOf(c.slot)]
+			}
+			pvpManager.lock.Unlock()
+
 			state.Lock()
+			events, err := state.Apply(engine.Action{Type: engine.ActionUseTrueSight, Slot: c.slot})
+			if err == nil {
+				if err := recordMatchEvents(c.st, state.ID, events); err != nil {
+					fmt.Println("recordMatchEvents error:", err)
+				}
+				savePVPState(state)
+			}
+			state.Unlock()
 
-			var player *PlayerData
-			var opponent *PlayerData
-
-			if c.slot == "A" {
-				player = &state.PlayerA
-				opponent = &state.PlayerB
-			} else if c.slot == "B" {
-				player = &state.PlayerB
-				opponent = &state.PlayerA
-			} else {
-				state.Unlock()
+			if err == engine.ErrInvalidSlot {
 				fmt.Println("Invalid slot:", c.slot)
 				return
 			}
-
-			if player.TrueSight <= 0 {
-				state.Unlock()
+			if err != nil {
 				fmt.Println("No TrueSight left")
 				errorResp := map[string]interface{}{
 					"type":  "error",
@@ -629,26 +568,20 @@ func pvpRead(c *PVPClient) {
 				}
 
 				errorJSON, err := json.Marshal(errorResp)
-				if err == nil {
-					if client, ok := pvpManager.rooms[c.roomID].Clients[c.slot]; ok {
-						select {
-						case client.send <- errorJSON:
-						default:
-						}
+				if err == nil && self != nil {
+					select {
+					case self.send <- errorJSON:
+					default:
 					}
 				}
 				return
 			}
-
-			player.TrueSight--
-			opponentCardCount := countCard(opponent.Hand)
-
-			state.Unlock()
+			used := events[0]
 
 			response := map[string]interface{}{
 				"type":          "true_sight_result",
-				"opponentHand":  opponentCardCount,
-				"trueSightLeft": player.TrueSight,
+				"opponentHand":  used.OpponentHand,
+				"trueSightLeft": used.TrueSightLeft,
 			}
 
 			respJSON, err := json.Marshal(response)
@@ -657,15 +590,13 @@ func pvpRead(c *PVPClient) {
 				return
 			}
 
-			if client, ok := pvpManager.rooms[c.roomID].Clients[c.slot]; ok {
+			if self != nil {
 				select {
-				case client.send <- respJSON:
+				case self.send <- respJSON:
 				default:
 				}
 			}
 
-			fmt.Println("trueeee")
-
 			notify := map[string]interface{}{
 				"type": "true_sight_alert",
 			}
@@ -676,13 +607,9 @@ func pvpRead(c *PVPClient) {
 				return
 			}
 
-			opponentSlot := "A"
-			if c.slot == "A" {
-				opponentSlot = "B"
-			}
-			if client, ok := pvpManager.rooms[c.roomID].Clients[opponentSlot]; ok {
+			if opponent != nil {
 				select {
-				case client.send <- notifyJSON:
+				case opponent.send <- notifyJSON:
 				default:
 				}
 			}
@@ -691,6 +618,210 @@ func pvpRead(c *PVPClient) {
 	}
 }
 
+// pvpSelectCard ลงการ์ด cardID ของ slot ถ้าครบทั้งสองฝั่งก็ตัดสินรอบแล้วส่ง round_result ให้ทั้งห้อง
+// auto เป็น true เมื่อเซิร์ฟเวอร์เลือกแทนผู้เล่นที่หมดเวลา (cardID ถูกเลือกตาม autoPlayPolicy)
+func pvpSelectCard(st store.Store, roomID, slot, cardID string, auto bool) error {
+	// ตรวจสอบและดึง state กับ match
+	pvpStatesMu.Lock()
+	state, ok := pvpStates[roomID]
+	pvpStatesMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no PvP state for room %s", roomID)
+	}
+
+	// copy client ออกมาระหว่างถือ lock เพราะ reconnect เขียน match.Clients ได้ตลอด (อาจมาจาก turn timer ด้วย)
+	pvpManager.lock.Lock()
+	match, ok := pvpManager.rooms[roomID]
+	if !ok {
+		pvpManager.lock.Unlock()
+		return fmt.Errorf("no PvP room %s", roomID)
+	}
+	clients := map[string]*PVPClient{}
+	for s, client := range match.Clients {
+		clients[s] = client
+	}
+	pvpManager.lock.Unlock()
+
+	state.Lock()
+	if auto {
+		// ผู้เล่นเลือกเองทันก่อน timer ได้ lock หรือรอบที่หมดเวลาจบไปแล้วและรอบใหม่เริ่มนับเวลาใหม่
+		if state.Status != engine.StatusOnGoing || state.Selected[slot] != nil || time.Now().Before(state.turnDeadline) {
+			state.Unlock()
+			return nil
+		}
+		cardID = autoPlayCardID(state, slot)
+	}
+	events, err := state.Apply(engine.Action{Type: engine.ActionPlayCard, Slot: slot, CardID: cardID})
+	if err != nil {
+		state.Unlock()
+		return err
+	}
+	if state.timeouts == nil {
+		state.timeouts = make(map[string]int)
+	}
+	if auto {
+		if state.autoPlayed == nil {
+			state.autoPlayed = make(map[string]bool)
+		}
+		state.autoPlayed[slot] = true
+	} else {
+		state.timeouts[slot] = 0
+	}
+
+	roundEvent := engine.FindEvent(events, engine.EventRoundResolved)
+	opponentSlot := opponentSlotOf(slot)
+
+	//sent to opponent
+	responseForOpponent := map[string]interface{}{
+		"type":             "selection_status",
+		"playerSelected":   roundEvent != nil || state.Selected[opponentSlot] != nil,
+		"opponentSelected": true,
+	}
+	respOpponentJSON, _ := json.Marshal(responseForOpponent)
+	if opponent, ok := clients[opponentSlot]; ok {
+		select {
+		case opponent.send <- respOpponentJSON:
+		default:
+		}
+	}
+
+	//เช็คว่าเลือกครบ 2 คนยัง
+	if roundEvent == nil {
+		state.Unlock()
+		return nil
+	}
+
+	if err := recordMatchEvents(st, state.ID, events); err != nil {
+		fmt.Println("recordMatchEvents error:", err)
+	}
+
+	round := roundEvent.Round
+	gameStatus := state.Status
+	autoPlayed := state.autoPlayed
+	if gameStatus == engine.StatusEnd {
+		pvpStopTurn(state)
+		deleteLiveState(state.ID)
+	} else {
+		pvpStartTurn(st, state)
+		savePVPState(state)
+	}
+
+	// ยังไม่จบ outcome เป็น nil postGameDetail จึงเป็นค่าว่าง
+	var outcome map[string]pvpOutcome
+	if ended := engine.FindEvent(events, engine.EventGameEnded); ended != nil {
+		outcome = finishPVPMatch(st, state, *ended.Result, pvpEndReason(state))
+		state.Series.record(ended.Result.ResultA)
+	}
+
+	A_CardRemaining := engine.CountCards(append(state.PlayerA.Deck, state.PlayerA.Hand...))
+	B_CardRemaining := engine.CountCards(append(state.PlayerB.Deck, state.PlayerB.Hand...))
+
+	//ส่งผลลัพธ์แยกกัน
+	respA := map[string]interface{}{
+		"type": "round_result",
+		"player": map[string]interface{}{
+			"hp":            state.PlayerA.CurrentHP,
+			"hand":          state.PlayerA.Hand,
+			"cardPlayed":    round.CardA,
+			"doDamage":      round.DamageToB,
+			"cardRemaining": A_CardRemaining,
+			"trueSight":     state.PlayerA.TrueSight,
+			"specialEvent":  round.SpecialEventA,
+			"autoPlayed":    autoPlayed["A"],
+		},
+		"opponent": map[string]interface{}{
+			"hp":            state.PlayerB.CurrentHP,
+			"handLength":    len(state.PlayerB.Hand),
+			"cardPlayed":    round.CardB,
+			"doDamage":      round.DamageToA,
+			"cardRemaining": B_CardRemaining,
+			"trueSight":     state.PlayerB.TrueSight,
+			"specialEvent":  round.SpecialEventB,
+			"autoPlayed":    autoPlayed["B"],
+		},
+		"gameStatus": gameStatus,
+		"roundWinner": func() string {
+			if round.Winner == "A" {
+				return "player"
+			} else if round.Winner == "B" {
+				return "opponent"
+			}
+			return "draw"
+		}(),
+		"postGameDetail": outcome["A"].Detail,
+		"series":         state.Series.view("A"),
+	}
+	respB := map[string]interface{}{
+		"type": "round_result",
+		"player": map[string]interface{}{
+			"hp":            state.PlayerB.CurrentHP,
+			"hand":          state.PlayerB.Hand,
+			"cardPlayed":    round.CardB,
+			"doDamage":      round.DamageToA,
+			"cardRemaining": B_CardRemaining,
+			"trueSight":     state.PlayerB.TrueSight,
+			"specialEvent":  round.SpecialEventB,
+			"autoPlayed":    autoPlayed["B"],
+		},
+		"opponent": map[string]interface{}{
+			"hp":            state.PlayerA.CurrentHP,
+			"handLength":    len(state.PlayerA.Hand),
+			"cardPlayed":    round.CardA,
+			"doDamage":      round.DamageToB,
+			"cardRemaining": A_CardRemaining,
+			"trueSight":     state.PlayerA.TrueSight,
+			"specialEvent":  round.SpecialEventA,
+			"autoPlayed":    autoPlayed["A"],
+		},
+		"gameStatus": gameStatus,
+		"roundWinner": func() string {
+			if round.Winner == "B" {
+				return "player"
+			} else if round.Winner == "A" {
+				return "opponent"
+			}
+			return "draw"
+		}(),
+		"postGameDetail": outcome["B"].Detail,
+		"series":         state.Series.view("B"),
+	}
+	publicResult := pvpPublicRoundResult(state, round, autoPlayed)
+	if gameStatus == engine.StatusOnGoing {
+		respA["turnDeadline"] = state.turnDeadline
+		respB["turnDeadline"] = state.turnDeadline
+	}
+	if gameStatus == engine.StatusEnd {
+		respA["rating"] = ratingChangeJSON(outcome["A"].Rating)
+		respB["rating"] = ratingChangeJSON(outcome["B"].Rating)
+	}
+	state.Unlock()
+
+	respAJSON, _ := json.Marshal(respA)
+	respBJSON, _ := json.Marshal(respB)
+
+	// ส่งกลับ client A และ B
+	if clientA, ok := clients["A"]; ok {
+		select {
+		case clientA.send <- respAJSON:
+		default:
+		}
+	}
+	if clientB, ok := clients["B"]; ok {
+		select {
+		case clientB.send <- respBJSON:
+		default:
+		}
+	}
+	pvpManager.lock.Lock()
+	pvpBroadcastSpectators(match, publicResult)
+	pvpManager.lock.Unlock()
+
+	if gameStatus == engine.StatusEnd {
+		pvpAfterGame(st, roomID, state)
+	}
+	return nil
+}
+
 func pvpWrite(c *PVPClient) {
 	for msg := range c.send {
 		err := c.conn.WriteMessage(websocket.TextMessage, msg)
@@ -708,34 +839,42 @@ func pvpRemoveClient(c *PVPClient) {
 	if !ok {
 		return
 	}
+	// slot ถูก connection ใหม่ของผู้เล่นคนเดิมรับไปแล้ว
+	if match.Clients[c.slot] != c {
+		return
+	}
+	delete(match.Clients, c.slot)
 
-	// แจ้งอีกฝั่งก่อนลบ
-	var opponent *PVPClient
-	if c.slot == "A" {
-		opponent = match.Clients["B"]
-	} else if c.slot == "B" {
-		opponent = match.Clients["A"]
+	// ห้องที่ยังไม่เริ่มเกมเก็บไว้ให้กลับเข้ามาได้ janitor จะลบเมื่อไม่มีใครใช้นานเกินไป
+	pvpStatesMu.Lock()
+	state, started := pvpStates[c.roomID]
+	pvpStatesMu.Unlock()
+	if !started {
+		return
 	}
 
-	if opponent != nil {
-		leaveMsg := map[string]interface{}{
-			"type": "opponent_left",
-		}
-		data, _ := json.Marshal(leaveMsg)
-		select {
-		case opponent.send <- data:
-		default:
+	state.Lock()
+	onGoing := state.Status == engine.StatusOnGoing
+	seriesOver := state.Series.Over()
+	// การ์ดที่เลือกไว้ในรอบนี้ถูกยกเลิก กลับมาแล้วเลือกใหม่
+	if onGoing && state.Selected[c.slot] != nil {
+		if _, err := state.Apply(engine.Action{Type: engine.ActionWithdrawCard, Slot: c.slot}); err == nil {
+			savePVPState(state)
 		}
 	}
+	state.Unlock()
 
-	delete(match.Clients, c.slot)
-	delete(match.Selected, c.slot)
-
-	if len(match.Clients) == 0 {
-		delete(pvpManager.rooms, c.roomID)
-		pvpStatesMu.Lock()
-		delete(pvpStates, c.roomID)
-		pvpStatesMu.Unlock()
+	// เกมที่ยังไม่จบรอให้กลับเข้ามาก่อน เกินเวลาแล้วแพ้ฟอร์ฟิต
+	if onGoing {
+		pvpMarkDisconnected(c.st, c.roomID, match, c.slot, time.Now())
+		return
+	}
+	// ออกระหว่างรอ rematch คือไม่เล่นต่อ ระหว่างเกมของ series เกมถัดไปจะนับว่าหลุดเอง
+	if match.waitingGame != "" && seriesOver {
+		if opponent, ok := match.Clients[opponentSlotOf(c.slot)]; ok {
+			pvpSend(opponent, map[string]interface{}{"type": "rematch_unavailable", "reason": "opponent left"})
+		}
+		pvpCloseSeries(c.roomID, match)
 	}
 }
 
@@ -753,10 +892,22 @@ func disconnectAllClients(roomID string) {
 			client.conn.Close()
 		}
 	}
+	for client := range match.Spectators {
+		client.conn.Close()
+	}
 
+	if match.afterGame != nil {
+		match.afterGame.Stop()
+	}
 	delete(pvpManager.rooms, roomID)
 
 	pvpStatesMu.Lock()
+	if state, ok := pvpStates[roomID]; ok {
+		state.Lock()
+		pvpStopTurn(state)
+		state.Unlock()
+		deleteLiveState(state.ID)
+	}
 	delete(pvpStates, roomID)
 	pvpStatesMu.Unlock()
 }
@@ -0,0 +1,403 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/httputil"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"clash_and_card/user"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ----------- Friends -----------
+//
+// POST /api/friends/requests {"userID"} ส่งคำขอเป็นเพื่อน ถ้าอีกฝั่งขอมาก่อนแล้วถือว่าตอบรับทันที
+// อีกฝั่งตอบรับหรือปฏิเสธด้วย /api/friends/requests/{userID}/accept|decline
+// DELETE /api/friends/{userID} เลิกเป็นเพื่อนหรือยกเลิกคำขอที่ส่งไป
+// GET /api/friends คืนเพื่อนพร้อมสถานะ online จาก connection ที่เปิดอยู่ และคำขอที่ค้างอยู่ทั้งสองทาง
+// POST /api/friends/{userID}/challenge สร้างห้อง private ที่เพื่อนคนนั้นเข้าได้คนเดียว แล้วส่ง challenge
+// ไปทาง /ws/notifications (ดู notify.go) เพื่อนรับด้วย /api/pvp/rooms/join ตาม invite code หรือส่ง challenge_decline
+
+// friendsMu กันการส่งคำขอสวนกันพร้อมกันจนเกิดความสัมพันธ์สองแถว
+var friendsMu sync.Mutex
+
+// สถานะของเพื่อนใน GET /api/friends
+const (
+	presenceOffline = "offline"
+	presenceOnline  = "online"   // เปิด /ws/notifications หรืออยู่ในห้องที่ยังไม่เริ่ม
+	presenceInQueue = "in_queue" // รอจับคู่ใน /ws/matchmaking
+	presenceInMatch = "in_match" // กำลังเล่นเกม PvP
+)
+
+// friendPresence สถานะของผู้เล่นแต่ละคน คนที่ไม่มี connection ใดเปิดอยู่คือ offline
+func friendPresence(userIDs []string) map[string]string {
+	presence := make(map[string]string, len(userIDs))
+	rank := map[string]int{presenceOffline: 0, presenceOnline: 1, presenceInQueue: 2, presenceInMatch: 3}
+	set := func(userID, p string) {
+		if _, wanted := presence[userID]; wanted && rank[p] > rank[presence[userID]] {
+			presence[userID] = p
+		}
+	}
+	for _, id := range userIDs {
+		presence[id] = presenceOffline
+	}
+
+	notifyHub.mu.Lock()
+	for userID, clients := range notifyHub.clients {
+		if len(clients) > 0 {
+			set(userID, presenceOnline)
+		}
+	}
+	notifyHub.mu.Unlock()
+
+	mm.mu.Lock()
+	for _, e := range mm.queue {
+		set(e.userID, presenceInQueue)
+	}
+	mm.mu.Unlock()
+
+	pvpManager.lock.Lock()
+	pvpStatesMu.Lock()
+	for roomID, match := range pvpManager.rooms {
+		if len(match.Clients) == 0 {
+			continue
+		}
+		p := presenceOnline
+		if state, ok := pvpStates[roomID]; ok {
+			state.Lock()
+			if state.Status == engine.StatusOnGoing {
+				p = presenceInMatch
+			}
+			state.Unlock()
+		}
+		for _, client := range match.Clients {
+			set(client.userID, p)
+		}
+	}
+	pvpStatesMu.Unlock()
+	pvpManager.lock.Unlock()
+
+	return presence
+}
+
+func friendSummary(u *models.User) map[string]interface{} {
+	return map[string]interface{}{"userID": u.ID, "name": u.Username, "level": u.Level, "class": u.Class}
+}
+
+// FriendsHandler GET /api/friends
+func FriendsHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+
+		all, err := st.Friends().ListFriends(userID)
+		if err != nil {
+			log.Println("ListFriends error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+
+		friends, incoming, outgoing := []models.Friend{}, []models.Friend{}, []models.Friend{}
+		var friendIDs []string
+		for _, f := range all {
+			switch {
+			case f.Status == models.FriendAccepted:
+				friends = append(friends, f)
+				friendIDs = append(friendIDs, f.UserID)
+			case f.Outgoing:
+				outgoing = append(outgoing, f)
+			default:
+				incoming = append(incoming, f)
+			}
+		}
+		presence := friendPresence(friendIDs)
+		for i := range friends {
+			friends[i].Presence = presence[friends[i].UserID]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"friends":  friends,
+			"incoming": incoming,
+			"outgoing": outgoing,
+		})
+	}
+}
+
+// SendFriendRequestHandler POST /api/friends/requests
+func SendFriendRequestHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+
+		var req struct {
+			UserID string `json:"userID"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if req.UserID == userID {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "cannot add yourself")
+			return
+		}
+
+		self, err := st.Users().GetUser(userID)
+		if err != nil {
+			httputil.WriteJSONError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		if _, err := st.Users().GetUser(req.UserID); err != nil {
+			httputil.WriteJSONError(w, http.StatusNotFound, "User not found")
+			return
+		}
+
+		friendsMu.Lock()
+		defer friendsMu.Unlock()
+
+		existing, err := st.Friends().GetFriendship(userID, req.UserID)
+		if err != nil && err != store.ErrNotFound {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+
+		status, code := models.FriendPending, http.StatusCreated
+		switch {
+		case existing == nil:
+			if err := st.Friends().CreateFriendRequest(userID, req.UserID); err != nil {
+				log.Println("CreateFriendRequest error:", err)
+				httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+				return
+			}
+			notifyUser(req.UserID, map[string]interface{}{"type": "friend_request", "from": friendSummary(self)})
+		case existing.Status == models.FriendAccepted:
+			httputil.WriteJSONError(w, http.StatusConflict, "already friends")
+			return
+		case existing.RequesterID == userID:
+			httputil.WriteJSONError(w, http.StatusConflict, "request already sent")
+			return
+		default:
+			// อีกฝั่งขอมาก่อนแล้ว ถือว่าตอบรับ
+			if err := st.Friends().AcceptFriendRequest(req.UserID, userID); err != nil {
+				log.Println("AcceptFriendRequest error:", err)
+				httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+				return
+			}
+			notifyUser(req.UserID, map[string]interface{}{"type": "friend_accepted", "friend": friendSummary(self)})
+			status, code = models.FriendAccepted, http.StatusOK
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{"userID": req.UserID, "status": status})
+	}
+}
+
+// AcceptFriendRequestHandler POST /api/friends/requests/{userID}/accept
+func AcceptFriendRequestHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+		requesterID := mux.Vars(r)["userID"]
+
+		self, err := st.Users().GetUser(userID)
+		if err != nil {
+			httputil.WriteJSONError(w, http.StatusNotFound, "User not found")
+			return
+		}
+
+		friendsMu.Lock()
+		err = st.Friends().AcceptFriendRequest(requesterID, userID)
+		friendsMu.Unlock()
+		if err == store.ErrNotFound {
+			httputil.WriteJSONError(w, http.StatusNotFound, "Friend request not found")
+			return
+		} else if err != nil {
+			log.Println("AcceptFriendRequest error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		notifyUser(requesterID, map[string]interface{}{"type": "friend_accepted", "friend": friendSummary(self)})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"userID": requesterID, "status": models.FriendAccepted})
+	}
+}
+
+// DeclineFriendRequestHandler POST /api/friends/requests/{userID}/decline
+// ไม่แจ้งคนที่ขอมา ฝั่งนั้นจะเห็นแค่ว่าคำขอหายไปจาก outgoing
+func DeclineFriendRequestHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+		requesterID := mux.Vars(r)["userID"]
+
+		friendsMu.Lock()
+		defer friendsMu.Unlock()
+
+		f, err := st.Friends().GetFriendship(requesterID, userID)
+		if err == store.ErrNotFound || (err == nil && (f.Status != models.FriendPending || f.RequesterID != requesterID)) {
+			httputil.WriteJSONError(w, http.StatusNotFound, "Friend request not found")
+			return
+		} else if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		if err := st.Friends().DeleteFriendship(requesterID, userID); err != nil && err != store.ErrNotFound {
+			log.Println("DeleteFriendship error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"userID": requesterID, "declined": true})
+	}
+}
+
+// RemoveFriendHandler DELETE /api/friends/{userID} เลิกเป็นเพื่อน หรือยกเลิกคำขอที่ตัวเองส่งไป
+func RemoveFriendHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+		otherID := mux.Vars(r)["userID"]
+
+		friendsMu.Lock()
+		defer friendsMu.Unlock()
+
+		f, err := st.Friends().GetFriendship(userID, otherID)
+		// คำขอที่คนอื่นส่งมาต้องใช้ decline
+		if err == store.ErrNotFound || (err == nil && f.Status == models.FriendPending && f.RequesterID != userID) {
+			httputil.WriteJSONError(w, http.StatusNotFound, "Friend not found")
+			return
+		} else if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		if err := st.Friends().DeleteFriendship(userID, otherID); err != nil && err != store.ErrNotFound {
+			log.Println("DeleteFriendship error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		if f.Status == models.FriendAccepted {
+			notifyUser(otherID, map[string]interface{}{"type": "friend_removed", "userID": userID})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"userID": otherID, "removed": true})
+	}
+}
+
+// ChallengeFriendHandler POST /api/friends/{userID}/challenge body {"rules"} ไม่ใส่ก็ได้
+// เพื่อนต้องเปิด /ws/notifications อยู่ ไม่อย่างนั้นจะไม่มีทางรู้ว่าถูกท้า
+func ChallengeFriendHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+		friendID := mux.Vars(r)["userID"]
+
+		req := struct {
+			Rules *RoomRules `json:"rules"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		rules := defaultRoomRules()
+		if req.Rules != nil {
+			rules = *req.Rules
+		}
+		if err := rules.validate(); err != nil {
+			httputil.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		f, err := st.Friends().GetFriendship(userID, friendID)
+		if err == store.ErrNotFound || (err == nil && f.Status != models.FriendAccepted) {
+			httputil.WriteJSONError(w, http.StatusNotFound, "Friend not found")
+			return
+		} else if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		self, err := st.Users().GetUser(userID)
+		if err != nil {
+			httputil.WriteJSONError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		if !notifyConnected(friendID) {
+			httputil.WriteJSONError(w, http.StatusConflict, "friend is offline")
+			return
+		}
+
+		roomID := uuid.New().String()
+
+		pvpManager.lock.Lock()
+		if pvpUserInRoom(userID) {
+			pvpManager.lock.Unlock()
+			httputil.WriteJSONError(w, http.StatusConflict, "already in a match")
+			return
+		}
+		if pvpUserInRoom(friendID) {
+			pvpManager.lock.Unlock()
+			httputil.WriteJSONError(w, http.StatusConflict, "friend is already in a match")
+			return
+		}
+		match, err := newPrivateRoom(roomID, userID, rules, nil)
+		if err != nil {
+			pvpManager.lock.Unlock()
+			log.Println("newPrivateRoom error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		match.challenged = friendID
+		code := match.InviteCode
+		expiresAt := match.CreatedAt.Add(privateRoomTimeout)
+		pvpManager.lock.Unlock()
+
+		notifyUser(friendID, map[string]interface{}{
+			"type":       "challenge",
+			"roomID":     roomID,
+			"inviteCode": code,
+			"rules":      rules,
+			"expiresAt":  expiresAt,
+			"from":       friendSummary(self),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"roomID":     roomID,
+			"inviteCode": code,
+			"slot":       "A",
+			"rules":      rules,
+			"challenged": friendID,
+			"expiresAt":  expiresAt,
+		})
+	}
+}
+
+// declineChallenge เพื่อนปฏิเสธคำท้าจาก /ws/notifications ปิดห้องและแจ้งคนท้า
+// คืน false ถ้าไม่มีคำท้านี้ถึงผู้เล่น หรือเพื่อนเข้าห้องไปแล้ว
+func declineChallenge(st store.Store, userID, roomID string) bool {
+	pvpManager.lock.Lock()
+	match, ok := pvpManager.rooms[roomID]
+	if !ok || match.challenged != userID || match.Players["B"] != "" || pvpStarted(roomID, match) {
+		pvpManager.lock.Unlock()
+		return false
+	}
+	hostID := match.Players["A"]
+	for _, client := range match.Clients {
+		pvpKick(client, "challenge declined")
+	}
+	delete(pvpManager.rooms, roomID)
+	pvpManager.lock.Unlock()
+
+	by := map[string]interface{}{"userID": userID}
+	if u, err := st.Users().GetUser(userID); err == nil {
+		by = friendSummary(u)
+	}
+	notifyUser(hostID, map[string]interface{}{"type": "challenge_declined", "roomID": roomID, "by": by})
+	return true
+}
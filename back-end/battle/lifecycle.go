@@ -0,0 +1,191 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ----------- Match lifecycle -----------
+//
+// match campaign อยู่ใน gameStates ตั้งแต่ StartBattleHandler จนกว่าจะ
+//   - จบเกม (PlayCardHandler ลบออกหลังส่งผลรอบสุดท้าย)
+//   - ไม่มีการเล่นนานเกิน idle timeout (janitor บันทึกเป็นแพ้ฟอร์ฟิตแล้วลบออก) หรือ
+//   - ผู้เล่นคนเดิมเริ่ม match ใหม่ (match เก่าถูกบันทึกเป็นแพ้ฟอร์ฟิต)
+//
+// ผู้เล่นหนึ่งคนมี match campaign ที่ยังเล่นอยู่ได้ไม่เกินหนึ่ง match
+
+// activeCampaignMatches owner user id -> match id ใช้ gameStatesMutex ร่วมกับ gameStates
+var activeCampaignMatches = make(map[string]string)
+
+// addGameState เก็บ match ใหม่และคืน match เดิมของผู้เล่นคนนี้ (ถ้ามี) ซึ่งถูกเอาออกแล้ว
+func addGameState(gs *GameState) (previous *GameState) {
+	gameStatesMutex.Lock()
+	defer gameStatesMutex.Unlock()
+
+	if prevID, ok := activeCampaignMatches[gs.OwnerID]; ok {
+		previous = gameStates[prevID]
+		delete(gameStates, prevID)
+	}
+	gameStates[gs.ID] = gs
+	activeCampaignMatches[gs.OwnerID] = gs.ID
+	return previous
+}
+
+// activeGameState คืน match ที่ผู้เล่นคนนี้กำลังเล่นอยู่
+func activeGameState(ownerID string) (*GameState, bool) {
+	gameStatesMutex.Lock()
+	defer gameStatesMutex.Unlock()
+
+	gs, ok := gameStates[activeCampaignMatches[ownerID]]
+	return gs, ok
+}
+
+// removeGameState ลบ match ออกจากหน่วยความจำและลบ snapshot
+func removeGameState(matchID string) {
+	gameStatesMutex.Lock()
+	if gs, ok := gameStates[matchID]; ok && activeCampaignMatches[gs.OwnerID] == matchID {
+		delete(activeCampaignMatches, gs.OwnerID)
+	}
+	delete(gameStates, matchID)
+	gameStatesMutex.Unlock()
+
+	deleteLiveState(matchID)
+}
+
+// forfeitGameState ปิด match ที่ยังไม่จบว่าผู้เล่นแพ้ฟอร์ฟิต
+// ต้องเรียกโดยไม่ถือ lock ของ gs
+func forfeitGameState(st store.Store, gs *GameState) {
+	gs.Lock()
+	onGoing := gs.Status == engine.StatusOnGoing
+	if onGoing {
+		// กันไม่ให้ request ที่ค้างอยู่เล่นต่อหลังบันทึกผลไปแล้ว
+		gs.Status = engine.StatusEnd
+	}
+	gs.Unlock()
+
+	if !onGoing {
+		return
+	}
+	if err := recordForfeit(st, gs.ID, "A"); err != nil {
+		fmt.Println("[ERROR] recordForfeit:", gs.ID, err)
+	}
+	resetCampaignStreak(st, gs.OwnerID)
+	deleteLiveState(gs.ID)
+}
+
+// StartMatchJanitor เริ่ม goroutine ที่คอยเก็บ match campaign ที่ค้างไว้เกิน idleTimeout
+// และห้อง PvP ที่สร้างแล้วแต่ไม่ได้เริ่มเกมภายใน roomTimeout
+func StartMatchJanitor(st store.Store, idleTimeout, roomTimeout time.Duration) {
+	interval := min(max(min(idleTimeout, roomTimeout)/4, 5*time.Second), time.Minute)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n := expireIdleMatches(st, idleTimeout); n > 0 {
+				fmt.Println("[INFO] expired idle campaign matches:", n)
+			}
+			if n := expireUnusedRooms(st, roomTimeout); n > 0 {
+				fmt.Println("[INFO] expired unused PvP rooms:", n)
+			}
+		}
+	}()
+}
+
+// expireUnusedRooms ลบห้อง PvP ที่ยังไม่เริ่มเกมหลังสร้างนานเกิน roomTimeout (ห้อง private ใช้ privateRoomTimeout)
+// ผู้เล่นที่รออยู่ในห้องจะได้รับ error แล้วถูกตัดการเชื่อมต่อ ห้องของการแข่งขันใช้ tournamentNoShowTimeout
+// และบันทึกผลให้ฝั่งที่รออยู่ชนะ (ไม่มีใครมาเลยถือว่าเสมอ)
+func expireUnusedRooms(st store.Store, roomTimeout time.Duration) int {
+	pvpManager.lock.Lock()
+	defer pvpManager.lock.Unlock()
+
+	pvpStatesMu.Lock()
+	defer pvpStatesMu.Unlock()
+
+	expired := 0
+	now := time.Now()
+	for roomID, match := range pvpManager.rooms {
+		timeout := roomTimeout
+		if match.InviteCode != "" {
+			timeout = privateRoomTimeout
+		}
+		if match.tournament.MatchID != "" {
+			timeout = tournamentNoShowTimeout
+		}
+		if _, started := pvpStates[roomID]; started || match.starting || now.Sub(match.CreatedAt) < timeout {
+			continue
+		}
+		for _, client := range match.Clients {
+			pvpKick(client, "opponent did not join")
+		}
+		for client := range match.Spectators {
+			pvpKick(client, "match did not start")
+		}
+		if match.tournament.MatchID != "" {
+			winner := tournamentDraw
+			if _, ok := match.Clients["A"]; ok {
+				winner = "A"
+			} else if _, ok := match.Clients["B"]; ok {
+				winner = "B"
+			}
+			go recordTournamentResult(st, match.tournament, winner)
+		}
+		delete(pvpManager.rooms, roomID)
+		expired++
+	}
+	return expired
+}
+
+// expireIdleMatches บันทึก match ที่ไม่มีการเล่นนานเกิน idleTimeout เป็นผู้เล่นแพ้ฟอร์ฟิต แล้วลบออก
+func expireIdleMatches(st store.Store, idleTimeout time.Duration) int {
+	gameStatesMutex.Lock()
+	states := make([]*GameState, 0, len(gameStates))
+	for _, gs := range gameStates {
+		states = append(states, gs)
+	}
+	gameStatesMutex.Unlock()
+
+	expired := 0
+	now := time.Now()
+	for _, gs := range states {
+		gs.Lock()
+		idle := now.Sub(gs.LastActive) >= idleTimeout
+		gs.Unlock()
+
+		if !idle {
+			continue
+		}
+		forfeitGameState(st, gs)
+		removeGameState(gs.ID)
+		expired++
+	}
+	return expired
+}
+
+// LiveMatchCounts จำนวน match ที่ยังอยู่ในหน่วยความจำ แยกตามโหมด
+func LiveMatchCounts() map[string]int {
+	gameStatesMutex.Lock()
+	campaign := len(gameStates)
+	gameStatesMutex.Unlock()
+
+	pvpStatesMu.Lock()
+	pvp := len(pvpStates)
+	pvpStatesMu.Unlock()
+
+	return map[string]int{
+		modeCampaign: campaign,
+		modePVP:      pvp,
+	}
+}
+
+// LiveMatchCountHandler GET /api/matches/live
+func LiveMatchCountHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LiveMatchCounts())
+	}
+}
@@ -0,0 +1,326 @@
+package battle
+
+import (
+	"clash_and_card/store"
+	"clash_and_card/user"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// ----------- Matchmaking -----------
+//
+// ผู้เล่นเชื่อมต่อ /ws/matchmaking แล้วส่ง {"type":"join_queue"}
+// ระบบจับคู่คนที่ rating ใกล้กัน ช่วงที่ยอมรับได้เริ่มจาก initialBand และกว้างขึ้นเรื่อยๆ ตามเวลาที่รอ
+// เมื่อจับคู่ได้จะสร้างห้องใหม่และส่ง match_found (roomID, slot) ให้ทั้งสองฝั่ง
+// จากนั้น client เชื่อมต่อ /ws/pvp?room=<roomID> เพื่อเริ่มเกม
+//
+// จับคู่ด้วย rating อย่างเดียว ไม่ดู level เพราะ rating ได้มาจากผล PvP จริงซึ่งรวมความได้เปรียบของ stat จาก level ไว้แล้ว
+// ผู้เล่น level สูงที่ stat ดีกว่าจะชนะจน rating ขึ้นไปเจอคนที่เก่งพอกัน การแบ่งช่วง level เพิ่มจะทำให้คิวแยกเป็นกลุ่มเล็กและรอนานขึ้น
+
+type queueEntry struct {
+	userID   string
+	name     string
+	level    int
+	class    string
+	rating   int
+	joinedAt time.Time
+	client   *queueClient
+}
+
+type queueClient struct {
+	conn   *websocket.Conn
+	userID string
+	send   chan []byte
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// close ปิดช่อง send ครั้งเดียว ตัว writer จะส่งข้อความที่ค้างอยู่ให้หมดแล้วปิด connection เอง
+func (c *queueClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
+	}
+}
+
+func (c *queueClient) sendJSON(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+	}
+}
+
+type matchmaker struct {
+	mu    sync.Mutex
+	queue []*queueEntry
+
+	initialBand    int
+	bandGrowth     int
+	growthInterval time.Duration
+}
+
+var mm = &matchmaker{
+	initialBand:    100,
+	bandGrowth:     50,
+	growthInterval: 10 * time.Second,
+}
+
+// ConfigureMatchmaking ตั้งช่วง rating เริ่มต้นและอัตราที่ช่วงกว้างขึ้น ต้องเรียกก่อน StartMatchmaker
+func ConfigureMatchmaking(initialBand, bandGrowth int, growthInterval time.Duration) {
+	mm.initialBand = initialBand
+	mm.bandGrowth = bandGrowth
+	mm.growthInterval = growthInterval
+}
+
+// StartMatchmaker เริ่ม goroutine ที่พยายามจับคู่ทุกวินาที (ช่วงที่ยอมรับได้กว้างขึ้นตามเวลา)
+func StartMatchmaker() {
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			mm.pair(time.Now())
+		}
+	}()
+}
+
+// band ช่วงผลต่าง rating ที่ผู้เล่นคนนี้ยอมรับได้ ณ เวลา now
+func (m *matchmaker) band(e *queueEntry, now time.Time) int {
+	steps := 0
+	if m.growthInterval > 0 {
+		steps = int(now.Sub(e.joinedAt) / m.growthInterval)
+	}
+	return m.initialBand + steps*m.bandGrowth
+}
+
+// join เพิ่มผู้เล่นเข้าคิว ถ้าอยู่ในคิวอยู่แล้ว (เช่นเปิดสองแท็บ) connection เดิมจะถูกแทนที่
+func (m *matchmaker) join(e *queueEntry) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, queued := range m.queue {
+		if queued.userID == e.userID {
+			if queued.client != e.client {
+				queued.client.sendJSON(map[string]interface{}{"type": "error", "error": "queued from another connection"})
+				queued.client.close()
+			}
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			break
+		}
+	}
+	m.queue = append(m.queue, e)
+	return len(m.queue)
+}
+
+// leave เอาผู้เล่นออกจากคิว เฉพาะถ้ายังเป็น connection เดียวกัน
+func (m *matchmaker) leave(c *queueClient) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, queued := range m.queue {
+		if queued.client == c {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// pair จับคู่ผู้เล่นในคิว คนที่รอนานที่สุดได้เลือกก่อน และเลือกคู่ที่ rating ใกล้ที่สุด
+// ทั้งสองฝั่งต้องยอมรับผลต่างนั้นได้ตามช่วงของตัวเอง
+func (m *matchmaker) pair(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := 0; i < len(m.queue); i++ {
+		a := m.queue[i]
+		best := -1
+		for j := i + 1; j < len(m.queue); j++ {
+			b := m.queue[j]
+			diff := abs(a.rating - b.rating)
+			if diff > m.band(a, now) || diff > m.band(b, now) {
+				continue
+			}
+			if best == -1 || diff < abs(a.rating-m.queue[best].rating) {
+				best = j
+			}
+		}
+		if best == -1 {
+			continue
+		}
+
+		b := m.queue[best]
+		m.queue = append(m.queue[:best], m.queue[best+1:]...)
+		m.queue = append(m.queue[:i], m.queue[i+1:]...)
+
+		// คนที่ยังว่างกลับเข้าคิวที่ตำแหน่งเดิม (ลำดับการรอไม่เปลี่ยน) แล้วหาคู่ใหม่ในรอบนี้เลย
+		requeue := createMatchedRoom(a, b)
+		m.queue = append(m.queue[:i], append(requeue, m.queue[i:]...)...)
+		i--
+	}
+}
+
+// createMatchedRoom สร้างห้องให้คู่ที่จับได้และแจ้งทั้งสองฝั่ง
+// ระหว่างที่รออยู่ในคิวผู้เล่นอาจเข้าห้องอื่นไปแล้ว (ห้องส่วนตัว ท้าเพื่อน) จึงตรวจอีกครั้งใต้ pvpManager.lock
+// คนที่ไม่ว่างถูกเอาออกจากคิว ถ้าเป็นแบบนั้นจะไม่สร้างห้อง และคืนคนที่ยังว่างให้ผู้เรียกใส่กลับเข้าคิว
+func createMatchedRoom(a, b *queueEntry) []*queueEntry {
+	roomID := uuid.New().String()
+
+	pvpManager.lock.Lock()
+	var requeue, stale []*queueEntry
+	for _, e := range []*queueEntry{a, b} {
+		if pvpUserInRoom(e.userID) {
+			stale = append(stale, e)
+		} else {
+			requeue = append(requeue, e)
+		}
+	}
+	if len(stale) == 0 {
+		newPVPRoom(roomID, a.userID, b.userID)
+	}
+	pvpManager.lock.Unlock()
+
+	if len(stale) > 0 {
+		for _, e := range stale {
+			e.client.sendJSON(map[string]interface{}{"type": "error", "error": "already in a match"})
+			e.client.close()
+		}
+		return requeue
+	}
+
+	log.Println("Matchmaking paired", a.userID, "and", b.userID, "into room", roomID)
+
+	notify := func(self, opponent *queueEntry, slot string) {
+		self.client.sendJSON(map[string]interface{}{
+			"type":   "match_found",
+			"roomID": roomID,
+			"slot":   slot,
+			"opponent": map[string]interface{}{
+				"name":   opponent.name,
+				"level":  opponent.level,
+				"class":  opponent.class,
+				"rating": opponent.rating,
+			},
+		})
+		self.client.close()
+	}
+	notify(a, b, "A")
+	notify(b, a, "B")
+	return nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// HandleMatchmakingWebSocket /ws/matchmaking
+func HandleMatchmakingWebSocket(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenStr := r.Header.Get("Sec-WebSocket-Protocol")
+		if tokenStr == "" {
+			http.Error(w, "missing token", http.StatusUnauthorized)
+			return
+		}
+		userID, err := user.ExtractUserIDFromToken(tokenStr)
+		if err != nil {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		header := http.Header{}
+		header.Add("Sec-WebSocket-Protocol", tokenStr)
+		conn, err := upgrader.Upgrade(w, r, header)
+		if err != nil {
+			log.Println("WebSocket upgrade error:", err)
+			return
+		}
+
+		c := &queueClient{conn: conn, userID: userID, send: make(chan []byte, 16)}
+		go queueWrite(c)
+		queueRead(st, c)
+	}
+}
+
+func queueRead(st store.Store, c *queueClient) {
+	defer func() {
+		mm.leave(c)
+		c.close()
+	}()
+
+	for {
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var m Message
+		if err := json.Unmarshal(msg, &m); err != nil {
+			log.Println("Invalid message:", err)
+			continue
+		}
+
+		switch m.Type {
+		case "join_queue":
+			pvpManager.lock.Lock()
+			busy := pvpUserInRoom(c.userID)
+			pvpManager.lock.Unlock()
+			if busy {
+				c.sendJSON(map[string]interface{}{"type": "error", "error": "already in a match"})
+				continue
+			}
+
+			u, err := st.Users().GetUser(c.userID)
+			if err != nil {
+				c.sendJSON(map[string]interface{}{"type": "error", "error": "user not found"})
+				continue
+			}
+
+			size := mm.join(&queueEntry{
+				userID:   c.userID,
+				name:     u.Username,
+				level:    u.Level,
+				class:    u.Class,
+				rating:   u.Rating,
+				joinedAt: time.Now(),
+				client:   c,
+			})
+			c.sendJSON(map[string]interface{}{"type": "queue_joined", "queueSize": size})
+			mm.pair(time.Now())
+
+		case "leave_queue":
+			if mm.leave(c) {
+				c.sendJSON(map[string]interface{}{"type": "queue_left"})
+			}
+		}
+	}
+}
+
+func queueWrite(c *queueClient) {
+	defer c.conn.Close()
+	for msg := range c.send {
+		if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+			return
+		}
+	}
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
@@ -0,0 +1,102 @@
+package battle
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// queuedPlayer ผู้เล่นในคิวที่ไม่มี connection จริง ข้อความที่ส่งหาค้างอยู่ใน send
+func queuedPlayer(userID string, rating int, joinedAt time.Time) *queueEntry {
+	return &queueEntry{
+		userID:   userID,
+		rating:   rating,
+		joinedAt: joinedAt,
+		client:   &queueClient{userID: userID, send: make(chan []byte, 16)},
+	}
+}
+
+func lastMessage(t *testing.T, e *queueEntry) map[string]interface{} {
+	t.Helper()
+	var last []byte
+	for msg := range e.client.send {
+		last = msg
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(last, &m); err != nil {
+		t.Fatalf("%s: no message sent (%v)", e.userID, err)
+	}
+	return m
+}
+
+func TestPairDropsPlayerAlreadyInRoom(t *testing.T) {
+	now := time.Now()
+	busy := queuedPlayer("mm-busy", 1000, now.Add(-3*time.Second))
+	first := queuedPlayer("mm-first", 1000, now.Add(-2*time.Second))
+	second := queuedPlayer("mm-second", 1010, now.Add(-time.Second))
+
+	// busy เข้าห้องส่วนตัวไประหว่างที่ยังอยู่ในคิว
+	pvpManager.lock.Lock()
+	newPVPRoom("mm-private", busy.userID, "")
+	pvpManager.lock.Unlock()
+
+	m := &matchmaker{initialBand: 100, queue: []*queueEntry{busy, first, second}}
+	m.pair(now)
+
+	var created string
+	pvpManager.lock.Lock()
+	for roomID, room := range pvpManager.rooms {
+		if room.Players["A"] == first.userID || room.Players["B"] == first.userID {
+			created = roomID
+			if room.Players["A"] != first.userID || room.Players["B"] != second.userID {
+				t.Errorf("room players = %v, want A %s B %s", room.Players, first.userID, second.userID)
+			}
+		}
+	}
+	delete(pvpManager.rooms, "mm-private")
+	delete(pvpManager.rooms, created)
+	pvpManager.lock.Unlock()
+
+	if created == "" {
+		t.Fatal("free players were not paired with each other")
+	}
+	if len(m.queue) != 0 {
+		t.Fatalf("queue still has %d entries", len(m.queue))
+	}
+	if msg := lastMessage(t, busy); msg["type"] != "error" {
+		t.Fatalf("busy player got %v, want error", msg)
+	}
+	for _, e := range []*queueEntry{first, second} {
+		if msg := lastMessage(t, e); msg["type"] != "match_found" || msg["roomID"] != created {
+			t.Fatalf("%s got %v, want match_found for %s", e.userID, msg, created)
+		}
+	}
+}
+
+func TestPairRequeuesFreePlayer(t *testing.T) {
+	now := time.Now()
+	free := queuedPlayer("mm-free", 1000, now.Add(-2*time.Second))
+	busy := queuedPlayer("mm-busy", 1000, now.Add(-time.Second))
+
+	pvpManager.lock.Lock()
+	newPVPRoom("mm-challenge", "mm-friend", busy.userID)
+	pvpManager.lock.Unlock()
+	t.Cleanup(func() {
+		pvpManager.lock.Lock()
+		delete(pvpManager.rooms, "mm-challenge")
+		pvpManager.lock.Unlock()
+	})
+
+	m := &matchmaker{initialBand: 100, queue: []*queueEntry{free, busy}}
+	m.pair(now)
+
+	if len(m.queue) != 1 || m.queue[0] != free {
+		t.Fatalf("queue = %v, want only %s", m.queue, free.userID)
+	}
+	if free.client.closed {
+		t.Fatal("free player's connection was closed")
+	}
+	if msg := lastMessage(t, busy); msg["type"] != "error" {
+		t.Fatalf("busy player got %v, want error", msg)
+	}
+}
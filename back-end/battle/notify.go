@@ -0,0 +1,117 @@
+package battle
+
+import (
+	"clash_and_card/store"
+	"clash_and_card/user"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+)
+
+// ----------- Notifications -----------
+//
+// client เปิด /ws/notifications ค้างไว้ตลอดที่อยู่ในเกม (token ส่งทาง Sec-WebSocket-Protocol เหมือน /ws/pvp)
+// เซิร์ฟเวอร์ส่งเหตุการณ์ที่ไม่ได้ผูกกับห้องใดห้องหนึ่งมาทางนี้ เช่น friend_request, friend_accepted, challenge
+// ผู้เล่นเปิดได้หลาย connection (หลายแท็บ) ทุก connection ได้ข้อความเดียวกัน
+// การมี connection นี้เปิดอยู่คือ "online" ในรายชื่อเพื่อน (ดู friends.go)
+
+var notifyHub = struct {
+	mu      sync.Mutex
+	clients map[string]map[*queueClient]bool // user id -> connection ที่เปิดอยู่
+}{clients: make(map[string]map[*queueClient]bool)}
+
+func notifyRegister(c *queueClient) {
+	notifyHub.mu.Lock()
+	defer notifyHub.mu.Unlock()
+
+	if notifyHub.clients[c.userID] == nil {
+		notifyHub.clients[c.userID] = make(map[*queueClient]bool)
+	}
+	notifyHub.clients[c.userID][c] = true
+}
+
+func notifyUnregister(c *queueClient) {
+	notifyHub.mu.Lock()
+	defer notifyHub.mu.Unlock()
+
+	delete(notifyHub.clients[c.userID], c)
+	if len(notifyHub.clients[c.userID]) == 0 {
+		delete(notifyHub.clients, c.userID)
+	}
+}
+
+// notifyUser ส่งข้อความให้ทุก connection ของผู้เล่น คืน false ถ้าผู้เล่นไม่ได้เชื่อมต่ออยู่
+func notifyUser(userID string, v interface{}) bool {
+	notifyHub.mu.Lock()
+	defer notifyHub.mu.Unlock()
+
+	for c := range notifyHub.clients[userID] {
+		c.sendJSON(v)
+	}
+	return len(notifyHub.clients[userID]) > 0
+}
+
+// notifyConnected ผู้เล่นเปิด /ws/notifications อยู่หรือไม่
+func notifyConnected(userID string) bool {
+	notifyHub.mu.Lock()
+	defer notifyHub.mu.Unlock()
+	return len(notifyHub.clients[userID]) > 0
+}
+
+// HandleNotificationWebSocket /ws/notifications
+func HandleNotificationWebSocket(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenStr := r.Header.Get("Sec-WebSocket-Protocol")
+		if tokenStr == "" {
+			http.Error(w, "missing token", http.StatusUnauthorized)
+			return
+		}
+		userID, err := user.ExtractUserIDFromToken(tokenStr)
+		if err != nil {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		header := http.Header{}
+		header.Add("Sec-WebSocket-Protocol", tokenStr)
+		conn, err := upgrader.Upgrade(w, r, header)
+		if err != nil {
+			log.Println("WebSocket upgrade error:", err)
+			return
+		}
+
+		// ใช้ queueClient เดียวกับ matchmaking เพราะต้องการแค่ช่อง send ที่ปิดได้ครั้งเดียวกับ writer ตัวเดียว
+		c := &queueClient{conn: conn, userID: userID, send: make(chan []byte, 16)}
+		notifyRegister(c)
+		go queueWrite(c)
+		notifyRead(st, c)
+	}
+}
+
+func notifyRead(st store.Store, c *queueClient) {
+	defer func() {
+		notifyUnregister(c)
+		c.close()
+	}()
+
+	for {
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var m Message
+		if err := json.Unmarshal(msg, &m); err != nil {
+			log.Println("Invalid message:", err)
+			continue
+		}
+
+		switch m.Type {
+		case "challenge_decline":
+			if !declineChallenge(st, c.userID, m.RoomID) {
+				c.sendJSON(map[string]interface{}{"type": "error", "error": "challenge not found"})
+			}
+		}
+	}
+}
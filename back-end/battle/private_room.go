@@ -0,0 +1,352 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/httputil"
+	"clash_and_card/store"
+	"clash_and_card/user"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ----------- Private PvP rooms -----------
+//
+// POST /api/pvp/rooms สร้างห้องที่ผู้สร้าง (host) อยู่ slot A ได้ invite code สั้นๆ ไว้ส่งให้เพื่อน
+// เพื่อนเข้าห้องด้วย POST /api/pvp/rooms/join (ถ้าตั้งรหัสผ่านไว้ต้องใส่ให้ถูก) แล้วได้ slot B
+// จากนั้นทั้งคู่ต่อ /ws/pvp?room=<roomID> เหมือนห้องจาก matchmaking แต่เกมยังไม่เริ่มจนกว่า host ส่ง start_match
+// ก่อนเริ่ม host เตะ guest ออกได้ด้วย POST /api/pvp/rooms/{roomID}/kick คนที่ถูกเตะเข้าห้องนั้นไม่ได้อีก
+// ห้อง private ไม่นับ rating และคะแนนฤดูกาล (กติกาไม่เหมือนกัน) ไม่แสดงใน /api/pvp/live และเข้าชมไม่ได้
+
+// RoomRules กติกาของห้อง ห้องจาก matchmaking ใช้ defaultRoomRules เสมอ
+type RoomRules struct {
+	HandSize     int     `json:"handSize"`
+	HPMultiplier float64 `json:"hpMultiplier"` // คูณกับ HP ของผู้เล่นทั้งสองตอนเริ่มเกม
+	TurnTimeout  int     `json:"turnTimeout"`  // วินาทีต่อรอบ 0 คือใช้ค่าของเซิร์ฟเวอร์
+	BestOf       int     `json:"bestOf"`       // จำนวนเกมสูงสุดของ series (ดู series.go) 0 คือเกมเดียว
+}
+
+func defaultRoomRules() RoomRules {
+	return RoomRules{HandSize: engine.HandSize, HPMultiplier: 1}
+}
+
+func (r RoomRules) validate() error {
+	if r.HandSize < 1 || r.HandSize > 5 {
+		return fmt.Errorf("handSize must be between 1 and 5")
+	}
+	if r.HPMultiplier < 0.5 || r.HPMultiplier > 3 {
+		return fmt.Errorf("hpMultiplier must be between 0.5 and 3")
+	}
+	if r.TurnTimeout != 0 && (r.TurnTimeout < 5 || r.TurnTimeout > 300) {
+		return fmt.Errorf("turnTimeout must be 0 or between 5 and 300 seconds")
+	}
+	if r.BestOf != 0 && !validBestOf(r.BestOf) {
+		return fmt.Errorf("bestOf must be 1, 3 or 5")
+	}
+	return nil
+}
+
+var privateRoomTimeout = 10 * time.Minute
+
+// ConfigurePrivateRooms ตั้งเวลาที่ห้อง private อยู่ได้โดยยังไม่เริ่มเกม
+func ConfigurePrivateRooms(timeout time.Duration) {
+	privateRoomTimeout = timeout
+}
+
+// inviteAlphabet ไม่มีตัวที่อ่านสับสนกันง่าย (0/O, 1/I/L)
+const (
+	inviteAlphabet   = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	inviteCodeLength = 6
+)
+
+// newInviteCode สุ่มแต่ละตัวด้วย rand.Int เพื่อให้ทุกตัวอักษรมีโอกาสเท่ากัน
+func newInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	alphabetSize := big.NewInt(int64(len(inviteAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		b[i] = inviteAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// pvpRoomByInvite หาห้องจาก invite code ต้องถือ pvpManager.lock ก่อนเรียก
+func pvpRoomByInvite(code string) (string, *PVPMatch, bool) {
+	for roomID, match := range pvpManager.rooms {
+		if match.InviteCode != "" && match.InviteCode == code {
+			return roomID, match, true
+		}
+	}
+	return "", nil, false
+}
+
+// pvpStarted match ของห้องนี้เริ่มแล้วหรือกำลังเริ่ม ต้องถือ pvpManager.lock ก่อนเรียก
+func pvpStarted(roomID string, match *PVPMatch) bool {
+	pvpStatesMu.Lock()
+	_, started := pvpStates[roomID]
+	pvpStatesMu.Unlock()
+	return started || match.starting
+}
+
+// newPrivateRoom สร้างห้อง private ที่ hostID อยู่ slot A พร้อม invite code ที่ไม่ซ้ำกับห้องอื่น
+// ต้องถือ pvpManager.lock ก่อนเรียก
+func newPrivateRoom(roomID, hostID string, rules RoomRules, passwordHash []byte) (*PVPMatch, error) {
+	var code string
+	for {
+		var err error
+		if code, err = newInviteCode(); err != nil {
+			return nil, err
+		}
+		if _, _, taken := pvpRoomByInvite(code); !taken {
+			break
+		}
+	}
+	match := newPVPRoom(roomID, hostID, "")
+	match.InviteCode = code
+	match.PasswordHash = passwordHash
+	match.Rules = rules
+	match.Ranked = false
+	return match, nil
+}
+
+// CreatePrivateRoomHandler POST /api/pvp/rooms
+func CreatePrivateRoomHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+
+		req := struct {
+			Password string     `json:"password"`
+			Rules    *RoomRules `json:"rules"`
+		}{}
+		// body ว่างได้ คือห้องไม่มีรหัสผ่านและใช้กติกาปกติ
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		rules := defaultRoomRules()
+		if req.Rules != nil {
+			rules = *req.Rules
+		}
+		if err := rules.validate(); err != nil {
+			httputil.WriteJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var passwordHash []byte
+		if req.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+			if err != nil {
+				httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+				return
+			}
+			passwordHash = hash
+		}
+
+		roomID := uuid.New().String()
+
+		pvpManager.lock.Lock()
+		if pvpUserInRoom(userID) {
+			pvpManager.lock.Unlock()
+			httputil.WriteJSONError(w, http.StatusConflict, "already in a match")
+			return
+		}
+		match, err := newPrivateRoom(roomID, userID, rules, passwordHash)
+		if err != nil {
+			pvpManager.lock.Unlock()
+			log.Println("newPrivateRoom error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		code := match.InviteCode
+		expiresAt := match.CreatedAt.Add(privateRoomTimeout)
+		pvpManager.lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"roomID":      roomID,
+			"inviteCode":  code,
+			"slot":        "A",
+			"rules":       rules,
+			"hasPassword": passwordHash != nil,
+			"expiresAt":   expiresAt,
+		})
+	}
+}
+
+// JoinPrivateRoomHandler POST /api/pvp/rooms/join ใช้ invite code รับ slot B ของห้อง
+func JoinPrivateRoomHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+
+		var req struct {
+			InviteCode string `json:"inviteCode"`
+			Password   string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		code := strings.ToUpper(strings.TrimSpace(req.InviteCode))
+
+		pvpManager.lock.Lock()
+		roomID, match, ok := pvpRoomByInvite(code)
+		if !ok {
+			pvpManager.lock.Unlock()
+			httputil.WriteJSONError(w, http.StatusNotFound, "Room not found")
+			return
+		}
+		hostID, passwordHash := match.Players["A"], match.PasswordHash
+		pvpManager.lock.Unlock()
+
+		if hostID == userID {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "cannot join your own room")
+			return
+		}
+		// ตรวจรหัสผ่านนอก lock เพราะ bcrypt ช้า
+		if passwordHash != nil && bcrypt.CompareHashAndPassword(passwordHash, []byte(req.Password)) != nil {
+			httputil.WriteJSONError(w, http.StatusForbidden, "wrong password")
+			return
+		}
+
+		host, err := st.Users().GetUser(hostID)
+		if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		guest, err := st.Users().GetUser(userID)
+		if err != nil {
+			httputil.WriteJSONError(w, http.StatusNotFound, "User not found")
+			return
+		}
+
+		pvpManager.lock.Lock()
+		// ห้องอาจหมดอายุหรือถูกคนอื่นเข้าไปแล้วระหว่างตรวจรหัสผ่าน
+		if pvpManager.rooms[roomID] != match {
+			pvpManager.lock.Unlock()
+			httputil.WriteJSONError(w, http.StatusNotFound, "Room not found")
+			return
+		}
+		switch {
+		case match.Kicked[userID]:
+			pvpManager.lock.Unlock()
+			httputil.WriteJSONError(w, http.StatusForbidden, "you were kicked from this room")
+			return
+		case match.challenged != "" && match.challenged != userID:
+			pvpManager.lock.Unlock()
+			httputil.WriteJSONError(w, http.StatusForbidden, "this challenge is for another player")
+			return
+		case match.Players["B"] == userID:
+			// เข้าซ้ำ เช่นกดลิงก์เชิญอีกครั้ง
+		case match.Players["B"] != "" || pvpStarted(roomID, match):
+			pvpManager.lock.Unlock()
+			httputil.WriteJSONError(w, http.StatusConflict, "room full")
+			return
+		case pvpUserInRoom(userID):
+			pvpManager.lock.Unlock()
+			httputil.WriteJSONError(w, http.StatusConflict, "already in a match")
+			return
+		default:
+			match.Players["B"] = userID
+			if hostClient, ok := match.Clients["A"]; ok {
+				pvpSend(hostClient, map[string]interface{}{
+					"type":  "guest_joined",
+					"guest": map[string]interface{}{"name": guest.Username, "level": guest.Level, "class": guest.Class},
+				})
+			}
+		}
+		rules := match.Rules
+		pvpManager.lock.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"roomID": roomID,
+			"slot":   "B",
+			"rules":  rules,
+			"host":   map[string]interface{}{"name": host.Username, "level": host.Level, "class": host.Class},
+		})
+	}
+}
+
+// KickGuestHandler POST /api/pvp/rooms/{roomID}/kick host เตะ guest ออกก่อนเริ่มเกม
+func KickGuestHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+		roomID := mux.Vars(r)["roomID"]
+
+		pvpManager.lock.Lock()
+		defer pvpManager.lock.Unlock()
+
+		match, ok := pvpManager.rooms[roomID]
+		if !ok || match.InviteCode == "" {
+			httputil.WriteJSONError(w, http.StatusNotFound, "Room not found")
+			return
+		}
+		if match.Players["A"] != userID {
+			httputil.WriteJSONError(w, http.StatusForbidden, "only the host can kick")
+			return
+		}
+		if pvpStarted(roomID, match) {
+			httputil.WriteJSONError(w, http.StatusConflict, "match already started")
+			return
+		}
+		guestID := match.Players["B"]
+		if guestID == "" {
+			httputil.WriteJSONError(w, http.StatusNotFound, "no guest in room")
+			return
+		}
+
+		match.Kicked[guestID] = true
+		match.Players["B"] = ""
+		if guest, ok := match.Clients["B"]; ok {
+			delete(match.Clients, "B")
+			pvpKick(guest, "kicked by host")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"roomID": roomID, "kicked": true})
+	}
+}
+
+// pvpHostStart host ส่ง start_match เริ่มเกมในห้อง private เมื่อ guest เชื่อมต่ออยู่แล้ว
+func pvpHostStart(c *PVPClient) {
+	pvpManager.lock.Lock()
+	match, ok := pvpManager.rooms[c.roomID]
+	if !ok {
+		pvpManager.lock.Unlock()
+		return
+	}
+	var errMsg string
+	switch {
+	case match.InviteCode == "" || c.slot != "A":
+		errMsg = "only the host of a private room can start the match"
+	case pvpStarted(c.roomID, match):
+		errMsg = "match already started"
+	case len(match.Clients) < 2:
+		errMsg = "guest not connected"
+	}
+	if errMsg != "" {
+		pvpManager.lock.Unlock()
+		pvpSend(c, map[string]interface{}{"type": "error", "error": errMsg})
+		return
+	}
+	match.starting = true
+	userAID, userBID := match.Clients["A"].userID, match.Clients["B"].userID
+	pvpManager.lock.Unlock()
+
+	pvpStartOrResume(c.st, c.roomID, userAID, userBID)
+}
@@ -0,0 +1,102 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"log"
+)
+
+// ----------- PvP results -----------
+//
+// match PvP ที่จบแล้ว (เล่นจนจบหรือฟอร์ฟิต) บันทึกลง pvp_matches พร้อมให้ exp และทองกับทั้งสองฝั่งใน transaction เดียว
+// แล้วจึงอัปเดต rating และคะแนนฤดูกาล (เฉพาะ match ที่ Ranked)
+
+// เหตุผลที่ match จบเมื่อเล่นจนจบ (ฟอร์ฟิตดู forfeitDisconnect และ forfeitTimeout)
+const (
+	pvpEndHP   = "hp"   // HP ของฝั่งใดฝั่งหนึ่งหรือทั้งคู่หมด
+	pvpEndDeck = "deck" // การ์ดหมด
+)
+
+// pvpEndReason เหตุผลที่ match จบหลังรอบสุดท้าย ต้องถือ lock ของ state ก่อนเรียก
+func pvpEndReason(state *PVPState) string {
+	if state.PlayerA.CurrentHP == 0 || state.PlayerB.CurrentHP == 0 {
+		return pvpEndHP
+	}
+	return pvpEndDeck
+}
+
+// pvpReward exp และทองจากผลเกม คิดจากเลเวลของคู่แข่ง ผู้เล่นที่ทิ้งเกมไม่ได้อะไรเลย
+func pvpReward(result string, opponentLevel int, abandoned bool) (exp, gold int) {
+	if abandoned {
+		return 0, 0
+	}
+	switch result {
+	case "Win":
+		return 40 + 15*opponentLevel, 15 * opponentLevel
+	case "Draw":
+		return 20 + 5*opponentLevel, 5 * opponentLevel
+	default:
+		return 10 + 2*opponentLevel, 0
+	}
+}
+
+// pvpOutcome ผลที่ผู้เล่นหนึ่งฝั่งได้รับเมื่อ match จบ ส่งไปใน postGameDetail และ rating
+type pvpOutcome struct {
+	Detail models.PostGameDetail
+	Rating *models.RatingChange
+}
+
+// finishPVPMatch บันทึกผล match ที่จบแล้ว ให้รางวัล อัปเดต rating และคะแนนฤดูกาล แล้วคืนผลของแต่ละ slot
+// ต้องถือ lock ของ state ก่อนเรียก และเรียกครั้งเดียวต่อ match (ตอนที่ Status เปลี่ยนเป็น end)
+func finishPVPMatch(st store.Store, state *PVPState, result engine.Result, reason string) map[string]pvpOutcome {
+	results := map[string]string{"A": result.ResultA, "B": result.ResultB}
+	outcome := map[string]pvpOutcome{
+		"A": {Detail: models.PostGameDetail{Result: result.ResultA, Detail: result.DetailA}},
+		"B": {Detail: models.PostGameDetail{Result: result.ResultB, Detail: result.DetailB}},
+	}
+	opponentLevel := map[string]int{"A": state.PlayerB.Level, "B": state.PlayerA.Level}
+	forfeit := reason == forfeitDisconnect || reason == forfeitTimeout
+
+	record := &models.PVPMatchResult{
+		ID:        state.ID,
+		RoomID:    state.RoomID,
+		PlayerAID: state.UserIDs["A"],
+		PlayerBID: state.UserIDs["B"],
+		Reason:    reason,
+	}
+	switch {
+	case result.ResultA == "Win":
+		record.WinnerID = state.UserIDs["A"]
+	case result.ResultB == "Win":
+		record.WinnerID = state.UserIDs["B"]
+	}
+
+	gains := make(map[string]models.PostGameDetail)
+	err := st.PVPMatches().RecordPVPResult(record, func(slot string, u *models.User) error {
+		detail := outcome[slot].Detail
+		detail.Exp, detail.Gold = pvpReward(results[slot], opponentLevel[slot], forfeit && results[slot] != "Win")
+		detail.StatGain, detail.LvlUp = applyRewards(u, detail.Exp, detail.Gold)
+		gains[slot] = detail
+		return nil
+	})
+	if err != nil {
+		log.Println("RecordPVPResult error:", err)
+	} else {
+		// ใช้รางวัลเฉพาะเมื่อบันทึกสำเร็จ transaction ที่ล้มเหลวไม่ได้ให้อะไรจริง
+		for slot, detail := range gains {
+			outcome[slot] = pvpOutcome{Detail: detail}
+		}
+	}
+
+	// ห้อง private ใช้กติกาที่ host เลือก จึงไม่นับ rating และคะแนนฤดูกาล
+	if state.Ranked {
+		ratingA, ratingB := updatePVPRatings(st, state, result)
+		recordSeasonResult(st, state, result)
+
+		a, b := outcome["A"], outcome["B"]
+		a.Rating, b.Rating = ratingA, ratingB
+		outcome["A"], outcome["B"] = a, b
+	}
+	return outcome
+}
@@ -0,0 +1,116 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/httputil"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"clash_and_card/user"
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// ----------- Rating -----------
+//
+// rating PvP ใช้ Elo: ผลต่างของ rating บอกโอกาสชนะที่คาดไว้
+// ชนะคนที่ rating สูงกว่าได้มาก ชนะคนที่ต่ำกว่าได้น้อย ผลรวมของทั้งสองฝั่งไม่เปลี่ยน
+
+var ratingKFactor = 32
+
+// ConfigureRating ตั้ง K factor ต้องเรียกก่อนเริ่มรับ request
+func ConfigureRating(kFactor int) {
+	ratingKFactor = kFactor
+}
+
+// expectedScore โอกาสชนะที่คาดไว้ของฝั่งที่มี rating นี้ (0 ถึง 1)
+func expectedScore(rating, opponent int) float64 {
+	return 1 / (1 + math.Pow(10, float64(opponent-rating)/400))
+}
+
+// eloDelta rating ที่ฝั่ง A ได้ (ฝั่ง B เสียเท่ากัน) scoreA คือ 1 ชนะ, 0.5 เสมอ, 0 แพ้
+func eloDelta(ratingA, ratingB int, scoreA float64) int {
+	return int(math.Round(float64(ratingKFactor) * (scoreA - expectedScore(ratingA, ratingB))))
+}
+
+func ratingScore(result string) float64 {
+	switch result {
+	case "Win":
+		return 1
+	case "Draw":
+		return 0.5
+	}
+	return 0
+}
+
+// updatePVPRatings ปรับ rating ของทั้งสองฝั่งจากผลของ match ที่จบแล้ว ต้องถือ lock ของ state ก่อนเรียก
+// คืน change ของ slot A และ B (nil ถ้าบันทึกไม่สำเร็จ)
+func updatePVPRatings(st store.Store, state *PVPState, result engine.Result) (a, b *models.RatingChange) {
+	err := st.Ratings().UpdateRatings(state.ID, state.UserIDs["A"], state.UserIDs["B"], func(ca, cb *models.RatingChange) {
+		delta := eloDelta(ca.Before, cb.Before, ratingScore(result.ResultA))
+		ca.Result, ca.After = result.ResultA, ca.Before+delta
+		cb.Result, cb.After = result.ResultB, cb.Before-delta
+		a, b = ca, cb
+	})
+	if err != nil {
+		log.Println("UpdateRatings error:", err)
+		return nil, nil
+	}
+	return a, b
+}
+
+// ratingChangeJSON ส่วน rating ใน round_result ของเกมที่จบแล้ว
+func ratingChangeJSON(c *models.RatingChange) interface{} {
+	if c == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"before": c.Before,
+		"after":  c.After,
+		"change": c.After - c.Before,
+	}
+}
+
+// RatingHandler GET /api/pvp/rating?limit=20 rating ปัจจุบันและประวัติล่าสุดของผู้เล่น
+func RatingHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+
+		limit := 20
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > 100 {
+				httputil.WriteJSONError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+				return
+			}
+			limit = n
+		}
+
+		u, err := st.Users().GetUser(userID)
+		if err == store.ErrNotFound {
+			httputil.WriteJSONError(w, http.StatusNotFound, "User not found")
+			return
+		} else if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+
+		history, err := st.Ratings().RatingHistory(userID, limit)
+		if err != nil {
+			log.Println("RatingHistory error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		if history == nil {
+			history = []models.RatingChange{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"rating":  u.Rating,
+			"history": history,
+		})
+	}
+}
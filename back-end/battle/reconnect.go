@@ -0,0 +1,192 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/store"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ----------- PvP reconnection -----------
+//
+// ผู้เล่นที่หลุดกลางเกมยังถือ slot เดิมไว้ reconnectGrace อีกฝั่งได้ opponent_disconnected พร้อมเวลาที่ต้องกลับมา
+// ถ้าต่อ /ws/pvp?room=<roomID> ใหม่ด้วยบัญชีเดิมทันเวลา จะได้ initialData ของสถานะปัจจุบันแล้วเล่นต่อ
+// ไม่ทันก็แพ้ฟอร์ฟิต อีกฝั่งได้ opponent_left พร้อมผลเกม
+
+var reconnectGrace = time.Minute
+
+// เหตุผลที่ match จบแบบฟอร์ฟิต ส่งให้ client ใน field reason
+const (
+	forfeitDisconnect = "disconnect" // หลุดแล้วไม่กลับมาภายใน reconnectGrace
+	forfeitTimeout    = "timeout"    // หมดเวลาติดกันครบ maxTimeouts
+)
+
+// ConfigureReconnectGrace ตั้งเวลาที่รอผู้เล่นที่หลุดกลับเข้ามา ต้องเรียกก่อน RestoreLiveMatches
+func ConfigureReconnectGrace(d time.Duration) {
+	reconnectGrace = d
+}
+
+func opponentSlotOf(slot string) string {
+	if slot == "A" {
+		return "B"
+	}
+	return "A"
+}
+
+// pvpSend ส่งข้อความให้ client โดยไม่ block ถ้า buffer เต็มก็ทิ้งไป
+func pvpSend(c *PVPClient, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+	}
+}
+
+// pvpMarkDisconnected เริ่มนับเวลารอผู้เล่น slot นี้กลับเข้ามา โดยถือว่าหลุดตั้งแต่ at ต้องถือ pvpManager.lock ก่อนเรียก
+func pvpMarkDisconnected(st store.Store, roomID string, match *PVPMatch, slot string, at time.Time) {
+	match.Disconnected[slot] = at
+
+	if opponent, ok := match.Clients[opponentSlotOf(slot)]; ok {
+		pvpSend(opponent, map[string]interface{}{
+			"type":              "opponent_disconnected",
+			"reconnectDeadline": at.Add(reconnectGrace),
+		})
+	}
+
+	time.AfterFunc(time.Until(at.Add(reconnectGrace)), func() {
+		pvpExpireDisconnect(st, roomID, slot, at)
+	})
+}
+
+// pvpExpireDisconnect ตัดสินแพ้ถ้าผู้เล่นยังไม่กลับมาตั้งแต่หลุดครั้งนั้น (at)
+// ถ้าอีกฝั่งก็หลุดอยู่ คนที่หลุดก่อนแพ้ หลุดพร้อมกัน (เช่นห้องที่โหลดกลับหลังรีสตาร์ท) ถือว่าเสมอ
+func pvpExpireDisconnect(st store.Store, roomID, slot string, at time.Time) {
+	pvpManager.lock.Lock()
+	match, ok := pvpManager.rooms[roomID]
+	if !ok || !match.Disconnected[slot].Equal(at) {
+		// กลับมาแล้ว (หรือหลุดรอบใหม่ซึ่งมีตัวนับของมันเอง) หรือห้องถูกลบไปแล้ว
+		pvpManager.lock.Unlock()
+		return
+	}
+
+	loserSlot := slot
+	if otherAt, gone := match.Disconnected[opponentSlotOf(slot)]; gone {
+		if otherAt.Before(at) {
+			loserSlot = opponentSlotOf(slot)
+		} else if otherAt.Equal(at) {
+			loserSlot = ""
+		}
+	}
+	delete(match.Disconnected, "A")
+	delete(match.Disconnected, "B")
+	pvpManager.lock.Unlock()
+
+	pvpForfeit(st, roomID, loserSlot, forfeitDisconnect)
+}
+
+// pvpForfeit จบ match ว่าฝั่ง loserSlot แพ้เพราะทิ้งเกม ("" = ทิ้งทั้งคู่ ถือว่าเสมอ)
+// บันทึกผล rating และคะแนนฤดูกาลเหมือนเกมที่เล่นจนจบ ผู้ชนะได้ opponent_left ผู้แพ้ที่ยังเชื่อมต่ออยู่ได้ forfeited
+func pvpForfeit(st store.Store, roomID, loserSlot, reason string) {
+	pvpStatesMu.Lock()
+	state, ok := pvpStates[roomID]
+	pvpStatesMu.Unlock()
+	if !ok {
+		return
+	}
+
+	state.Lock()
+	if state.Status != engine.StatusOnGoing {
+		state.Unlock()
+		return
+	}
+	state.Status = engine.StatusEnd
+	pvpStopTurn(state)
+	result := forfeitResult(loserSlot)
+	if err := recordForfeit(st, state.ID, loserSlot); err != nil {
+		fmt.Println("[ERROR] recordForfeit:", state.ID, err)
+	}
+	outcome := finishPVPMatch(st, state, result, reason)
+	deleteLiveState(state.ID)
+	state.Unlock()
+
+	pvpManager.lock.Lock()
+	if match, ok := pvpManager.rooms[roomID]; ok {
+		for slot, c := range match.Clients {
+			msgType := "forfeited"
+			if loserSlot != "" && slot != loserSlot {
+				msgType = "opponent_left"
+			}
+			pvpSend(c, map[string]interface{}{
+				"type":           msgType,
+				"reason":         reason,
+				"postGameDetail": outcome[slot].Detail,
+				"rating":         ratingChangeJSON(outcome[slot].Rating),
+			})
+		}
+		winnerSlot := "draw"
+		if loserSlot != "" {
+			winnerSlot = opponentSlotOf(loserSlot)
+		}
+		pvpBroadcastSpectators(match, map[string]interface{}{
+			"type":   "match_ended",
+			"reason": reason,
+			"winner": winnerSlot,
+		})
+		// ฟอร์ฟิตจบทั้ง series ฝั่งที่ไม่ได้ทิ้งเกมชนะคู่นี้
+		if match.tournament.MatchID != "" {
+			go recordTournamentResult(st, match.tournament, tournamentResultFor(winnerSlot))
+		}
+	}
+	pvpManager.lock.Unlock()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		disconnectAllClients(roomID)
+	}()
+}
+
+// pvpResync ส่งสถานะเกมปัจจุบันให้ผู้เล่นที่เพิ่งกลับเข้ามา และแจ้งอีกฝั่งว่ากลับมาแล้ว
+func pvpResync(c *PVPClient) {
+	pvpStatesMu.Lock()
+	state, ok := pvpStates[c.roomID]
+	pvpStatesMu.Unlock()
+	if !ok {
+		return
+	}
+
+	opponentSlot := opponentSlotOf(c.slot)
+
+	state.Lock()
+	data := pvpInitialData(state, c.slot)
+	opponentSelected := state.Selected[opponentSlot] != nil
+	state.Unlock()
+
+	pvpSend(c, data)
+	if opponentSelected {
+		pvpSend(c, map[string]interface{}{
+			"type":             "selection_status",
+			"playerSelected":   false,
+			"opponentSelected": true,
+		})
+	}
+
+	pvpManager.lock.Lock()
+	defer pvpManager.lock.Unlock()
+	match, ok := pvpManager.rooms[c.roomID]
+	if !ok {
+		return
+	}
+	if opponent, ok := match.Clients[opponentSlot]; ok {
+		pvpSend(opponent, map[string]interface{}{"type": "opponent_reconnected"})
+	}
+	if at, gone := match.Disconnected[opponentSlot]; gone {
+		pvpSend(c, map[string]interface{}{
+			"type":              "opponent_disconnected",
+			"reconnectDeadline": at.Add(reconnectGrace),
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	modeCampaign = "campaign"
+	modePVP      = "pvp"
+
+	// matchStatusForfeit สถานะของ match ที่จบเพราะมีฝั่งหนึ่งทิ้งเกม
+	matchStatusForfeit = "forfeit"
+)
+
+// replayPlayer ข้อมูลผู้เล่นตอนเริ่มเกม (ก่อนสับไพ่) ใช้ร่วมกับ seed เพื่อเล่นซ้ำได้
+type replayPlayer struct {
+	UserID string        `json:"userID,omitempty"`
+	Name   string        `json:"name"`
+	Level  int           `json:"level"`
+	Class  string        `json:"class"`
+	Stat   engine.Stat   `json:"stat"`
+	HP     int           `json:"hp"`
+	Deck   []engine.Card `json:"deck"`
+}
+
+func newReplayPlayer(userID string, p engine.PlayerData) replayPlayer {
+	return replayPlayer{
+		UserID: userID,
+		Name:   p.Name,
+		Level:  p.Level,
+		Class:  p.Class,
+		Stat:   p.Stat,
+		HP:     p.CurrentHP,
+		Deck:   append([]engine.Card(nil), p.Deck...),
+	}
+}
+
+// recordMatchStart บันทึก match ใหม่ ต้องเรียกก่อน Match.Start เพื่อเก็บเด็คก่อนสับ
+func recordMatchStart(st store.Store, matchID, mode string, userAID, userBID string, seed uint64, state *PVPState) error {
+	playerA, err := json.Marshal(newReplayPlayer(userAID, state.PlayerA))
+	if err != nil {
+		return err
+	}
+	playerB, err := json.Marshal(newReplayPlayer(userBID, state.PlayerB))
+	if err != nil {
+		return err
+	}
+
+	return st.Matches().CreateMatch(&models.MatchRecord{
+		ID:        matchID,
+		Mode:      mode,
+		Seed:      strconv.FormatUint(seed, 10),
+		PlayerAID: userAID,
+		PlayerBID: userBID,
+		PlayerA:   string(playerA),
+		PlayerB:   string(playerB),
+		HandSize:  state.HandSize,
+		Status:    engine.StatusOnGoing,
+	})
+}
+
+// recordMatchEvents บันทึก event ของรอบที่เพิ่งเกิดขึ้นลง match log
+// ถ้ามี game_ended จะปิด match ด้วย
+func recordMatchEvents(st store.Store, matchID string, events []engine.Event) error {
+	round := 0
+	for _, ev := range events {
+		// การเลือกและยกเลิกการ์ดไม่ต้องเก็บ การ์ดที่ลงจริงอยู่ใน round_resolved แล้ว
+		if ev.Type == engine.EventCardSelected || ev.Type == engine.EventCardWithdrawn {
+			continue
+		}
+
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+
+		// game_ended ใช้เลขรอบเดียวกับรอบที่ทำให้จบเกม
+		if ev.Round != nil {
+			round = ev.Round.Number
+		}
+
+		if err := st.Matches().AppendMatchEvent(matchID, round, string(ev.Type), string(payload)); err != nil {
+			return err
+		}
+
+		if ev.Type == engine.EventGameEnded {
+			r := ev.Result
+			if err := st.Matches().FinishMatch(matchID, engine.StatusEnd, r.ResultA, r.DetailA, r.ResultB, r.DetailB); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p replayPlayer) playerData() engine.PlayerData {
+	return engine.PlayerData{
+		Name:      p.Name,
+		Level:     p.Level,
+		Class:     p.Class,
+		Stat:      p.Stat,
+		CurrentHP: p.HP,
+		Deck:      append([]engine.Card(nil), p.Deck...),
+	}
+}
+
+// replayActions แปลง event ที่บันทึกไว้กลับเป็น action ตามลำดับ
+// card_selected ไม่ได้บันทึก จึงใช้การ์ดจาก round_resolved แทน (ลำดับการเลือกในรอบไม่มีผลกับการสุ่ม)
+func replayActions(events []engine.Event) []engine.Action {
+	var actions []engine.Action
+	for _, ev := range events {
+		switch ev.Type {
+		case engine.EventTrueSightUsed:
+			actions = append(actions, engine.Action{Type: engine.ActionUseTrueSight, Slot: ev.Slot})
+		case engine.EventRoundResolved:
+			actions = append(actions,
+				engine.Action{Type: engine.ActionPlayCard, Slot: "A", CardID: ev.Round.CardA.ID},
+				engine.Action{Type: engine.ActionPlayCard, Slot: "B", CardID: ev.Round.CardB.ID},
+			)
+		}
+	}
+	return actions
+}
+
+// verifyReplay เล่น match ซ้ำด้วย engine.Replay จากผู้เล่นตอนเริ่ม, hand size, seed และ action
+// แล้วเทียบกับ event ที่บันทึกไว้ คืน false ถ้าผลไม่ตรงกัน
+func verifyReplay(match *models.MatchRecord, playerA, playerB replayPlayer, events []engine.Event) (bool, error) {
+	seed, err := strconv.ParseUint(match.Seed, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	_, replayed, err := engine.Replay(playerA.playerData(), playerB.playerData(), match.HandSize, seed, replayActions(events))
+	if err != nil {
+		return false, nil
+	}
+
+	var got []engine.Event
+	for _, ev := range replayed {
+		if ev.Type != engine.EventCardSelected && ev.Type != engine.EventCardWithdrawn {
+			got = append(got, ev)
+		}
+	}
+	want, _ := json.Marshal(events)
+	have, _ := json.Marshal(got)
+	return string(want) == string(have), nil
+}
+
+// forfeitResult ผลของ match ที่ฝั่ง loserSlot ทิ้งเกม ("" = ทิ้งทั้งคู่)
+func forfeitResult(loserSlot string) engine.Result {
+	lose, win := "Lose", "Win"
+	loseDetail, winDetail := "You abandoned the match", "Opponent abandoned the match"
+	switch loserSlot {
+	case "":
+		detail := "Both players abandoned the match"
+		return engine.Result{ResultA: "Draw", DetailA: detail, ResultB: "Draw", DetailB: detail}
+	case "A":
+		return engine.Result{ResultA: lose, DetailA: loseDetail, ResultB: win, DetailB: winDetail}
+	}
+	return engine.Result{ResultA: win, DetailA: winDetail, ResultB: lose, DetailB: loseDetail}
+}
+
+// recordForfeit ปิด match ว่าฝั่ง loserSlot แพ้เพราะทิ้งเกม
+func recordForfeit(st store.Store, matchID, loserSlot string) error {
+	r := forfeitResult(loserSlot)
+	return st.Matches().FinishMatch(matchID, matchStatusForfeit, r.ResultA, r.DetailA, r.ResultB, r.DetailB)
+}
+
+type replayRoundSide struct {
+	Card         engine.Card `json:"card"`
+	DamageDealt  int         `json:"damageDealt"`
+	Missed       bool        `json:"missed"`
+	SpecialEvent string      `json:"specialEvent"`
+	HP           int         `json:"hp"`
+}
+
+type replayTimelineEntry struct {
+	Type          string           `json:"type"`
+	Round         int              `json:"round"`
+	Winner        string           `json:"winner,omitempty"`
+	PlayerA       *replayRoundSide `json:"playerA,omitempty"`
+	PlayerB       *replayRoundSide `json:"playerB,omitempty"`
+	Slot          string           `json:"slot,omitempty"`
+	Result        *engine.Result   `json:"result,omitempty"`
+	TrueSightLeft *int             `json:"trueSightLeft,omitempty"`
+	At            string           `json:"at"`
+}
+
+func newReplayTimelineEntry(ev engine.Event, round int, at string) replayTimelineEntry {
+	entry := replayTimelineEntry{Type: string(ev.Type), Round: round, At: at}
+
+	switch ev.Type {
+	case engine.EventRoundResolved:
+		r := ev.Round
+		entry.Winner = r.Winner
+		entry.PlayerA = &replayRoundSide{
+			Card:         r.CardA,
+			DamageDealt:  max(r.DamageToB, 0),
+			Missed:       r.DamageToB == -1,
+			SpecialEvent: r.SpecialEventA,
+			HP:           r.HPA,
+		}
+		entry.PlayerB = &replayRoundSide{
+			Card:         r.CardB,
+			DamageDealt:  max(r.DamageToA, 0),
+			Missed:       r.DamageToA == -1,
+			SpecialEvent: r.SpecialEventB,
+			HP:           r.HPB,
+		}
+	case engine.EventGameEnded:
+		entry.Result = ev.Result
+	case engine.EventTrueSightUsed:
+		// ไม่เปิดเผยมือของอีกฝั่ง บอกแค่ว่าใช้ไปแล้ว
+		entry.Slot = ev.Slot
+		left := ev.TrueSightLeft
+		entry.TrueSightLeft = &left
+	}
+	return entry
+}
+
+// ----------- Handlers -----------
+
+func MatchReplayHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		matchID := mux.Vars(r)["id"]
+		if matchID == "" {
+			http.Error(w, "Missing match id", http.StatusBadRequest)
+			return
+		}
+
+		match, err := st.Matches().GetMatch(matchID)
+		if err == store.ErrNotFound {
+			http.Error(w, "Match not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			fmt.Println("[ERROR] Load match:", err)
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+
+		var playerA, playerB replayPlayer
+		if err := json.Unmarshal([]byte(match.PlayerA), &playerA); err != nil {
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+		if err := json.Unmarshal([]byte(match.PlayerB), &playerB); err != nil {
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+
+		events, err := st.Matches().ListMatchEvents(matchID)
+		if err != nil {
+			fmt.Println("[ERROR] Load match events:", err)
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+
+		timeline := []replayTimelineEntry{}
+		var decoded []engine.Event
+		for _, stored := range events {
+			var ev engine.Event
+			if err := json.Unmarshal([]byte(stored.Payload), &ev); err != nil {
+				http.Error(w, "Server error", http.StatusInternalServerError)
+				return
+			}
+			decoded = append(decoded, ev)
+			timeline = append(timeline, newReplayTimelineEntry(ev, stored.Round, stored.CreatedAt))
+		}
+
+		side := func(p replayPlayer) map[string]interface{} {
+			return map[string]interface{}{
+				"userID": p.UserID,
+				"name":   p.Name,
+				"level":  p.Level,
+				"class":  p.Class,
+				"hp":     p.HP,
+				"stat": map[string]interface{}{
+					"atk": p.Stat.ATK,
+					"def": p.Stat.DEF,
+					"spd": p.Stat.SPD,
+					"hp":  p.Stat.HP,
+				},
+			}
+		}
+
+		// seed บอกผลการสุ่มที่เหลือทั้งหมด จึงเปิดเผยได้เมื่อจบเกมแล้วเท่านั้น
+		// verified บอกว่าเล่นซ้ำจาก seed แล้วได้ผลตรงกับที่บันทึกไว้ ตรวจเฉพาะเมื่อเปิดเผย seed ได้
+		seed := match.Seed
+		var verified *bool
+		if match.Status == engine.StatusOnGoing {
+			seed = ""
+		} else if ok, err := verifyReplay(match, playerA, playerB, decoded); err != nil {
+			fmt.Println("[ERROR] Verify replay:", matchID, err)
+		} else {
+			verified = &ok
+		}
+
+		res := map[string]interface{}{
+			"type":      "match_replay",
+			"matchID":   matchID,
+			"mode":      match.Mode,
+			"seed":      seed,
+			"handSize":  match.HandSize,
+			"verified":  verified,
+			"status":    match.Status,
+			"playerA":   side(playerA),
+			"playerB":   side(playerB),
+			"createdAt": match.CreatedAt,
+			"endedAt":   match.EndedAt,
+			"result": map[string]interface{}{
+				"resultA": match.ResultA,
+				"detailA": match.DetailA,
+				"resultB": match.ResultB,
+				"detailB": match.DetailB,
+			},
+			"timeline": timeline,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}
+}
@@ -0,0 +1,246 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/httputil"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"clash_and_card/user"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ----------- Ranked seasons -----------
+//
+// ผล PvP ทุกเกมบวก/ลบคะแนนฤดูกาลของทั้งสองฝั่ง อันดับเรียงตามคะแนน
+// เมื่อฤดูกาลหมดเวลา scheduler จะปิดฤดูกาล (บันทึกอันดับสุดท้ายและ soft reset rating)
+// แจกรางวัลตามอันดับผ่าน applyRewards แบบเดียวกับรางวัลชนะ campaign แล้วเปิดฤดูกาลถัดไป
+
+// SeasonReward รางวัลของผู้เล่นที่จบฤดูกาลในอันดับไม่เกิน MaxPlacement (0 = ทุกคนที่ได้เล่น)
+type SeasonReward struct {
+	MaxPlacement int
+	Gold         int
+	Cards        []models.DeckCard
+}
+
+type SeasonSettings struct {
+	Length     time.Duration
+	WinPoints  int
+	LossPoints int // คะแนนที่เสียเมื่อแพ้
+	DrawPoints int
+	// SoftResetKeep สัดส่วนของ rating ส่วนที่ห่างจาก InitialRating ที่เก็บไว้ข้ามฤดูกาล
+	SoftResetKeep float64
+	// Rewards เรียงจากอันดับดีที่สุด ผู้เล่นได้รางวัลแรกที่เข้าเงื่อนไข
+	Rewards []SeasonReward
+}
+
+var seasonSettings = SeasonSettings{
+	Length:        28 * 24 * time.Hour,
+	WinPoints:     25,
+	LossPoints:    15,
+	DrawPoints:    5,
+	SoftResetKeep: 0.5,
+}
+
+// StartSeasonScheduler เปิดฤดูกาลแรกถ้ายังไม่มี แล้วตรวจการเปลี่ยนฤดูกาลทุกนาที
+func StartSeasonScheduler(st store.Store, settings SeasonSettings) {
+	seasonSettings = settings
+	if err := rolloverSeason(st, time.Now()); err != nil {
+		log.Println("Season rollover error:", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := rolloverSeason(st, time.Now()); err != nil {
+				log.Println("Season rollover error:", err)
+			}
+		}
+	}()
+}
+
+// rolloverSeason ปิดฤดูกาลที่หมดเวลา แจกรางวัล และเปิดฤดูกาลถัดไป
+// แต่ละขั้นทำซ้ำได้ ถ้าเซิร์ฟเวอร์ดับกลางทางรอบถัดไปจะทำต่อจากที่ค้าง
+func rolloverSeason(st store.Store, now time.Time) error {
+	season, err := st.Seasons().LatestSeason()
+	if err == store.ErrNotFound {
+		return createSeason(st, 1, now)
+	} else if err != nil {
+		return err
+	}
+
+	if !season.Closed {
+		if now.Before(season.EndsAt) {
+			return nil
+		}
+		if err := st.Seasons().CloseSeason(season.ID, models.InitialRating, seasonSettings.SoftResetKeep); err != nil {
+			return err
+		}
+		log.Printf("[INFO] season %d closed", season.Number)
+	}
+
+	if err := grantSeasonRewards(st, season.ID); err != nil {
+		return err
+	}
+
+	// ฤดูกาลถัดไปเริ่มต่อจากฤดูกาลเดิม ถ้าเซิร์ฟเวอร์ปิดไปนานจนเลยความยาวหนึ่งฤดูกาลให้เริ่มนับจากตอนนี้
+	start := season.EndsAt
+	if now.Sub(start) >= seasonSettings.Length {
+		start = now
+	}
+	return createSeason(st, season.Number+1, start)
+}
+
+func createSeason(st store.Store, number int, start time.Time) error {
+	season := &models.Season{
+		Number:   number,
+		StartsAt: start,
+		EndsAt:   start.Add(seasonSettings.Length),
+	}
+	if err := st.Seasons().CreateSeason(season); err != nil {
+		return err
+	}
+	log.Printf("[INFO] season %d started, ends %s", number, season.EndsAt.Format(time.RFC3339))
+	return nil
+}
+
+// seasonRewardFor รางวัลของอันดับนี้ nil ถ้าไม่ได้รางวัล
+func seasonRewardFor(placement int) *SeasonReward {
+	for i, reward := range seasonSettings.Rewards {
+		if reward.MaxPlacement == 0 || placement <= reward.MaxPlacement {
+			return &seasonSettings.Rewards[i]
+		}
+	}
+	return nil
+}
+
+// grantSeasonRewards แจกรางวัลให้ทุกคนในฤดูกาลที่ปิดแล้วที่ยังไม่ได้รับ
+func grantSeasonRewards(st store.Store, seasonID int64) error {
+	const pageSize = 100
+	granted := 0
+	for offset := 0; ; offset += pageSize {
+		standings, err := st.Seasons().SeasonStandings(seasonID, offset, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, standing := range standings {
+			if standing.Rewarded {
+				continue
+			}
+
+			var reward SeasonReward
+			if r := seasonRewardFor(standing.Placement); r != nil {
+				reward = *r
+			}
+			// ไม่มีรางวัลก็ยังทำเครื่องหมายไว้ ครั้งหน้าจะได้ไม่ต้องตรวจซ้ำ
+			err := st.Seasons().GrantSeasonReward(seasonID, standing.UserID, reward.Cards, func(u *models.User) error {
+				applyRewards(u, 0, reward.Gold)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			granted++
+		}
+		if len(standings) < pageSize {
+			break
+		}
+	}
+	if granted > 0 {
+		log.Printf("[INFO] season rewards granted: %d", granted)
+	}
+	return nil
+}
+
+// recordSeasonResult บวก/ลบคะแนนฤดูกาลของทั้งสองฝั่งจากผลของ match PvP ที่จบแล้ว
+// match ที่จบหลังฤดูกาลหมดเวลา (ระหว่างรอ rollover) ไม่นับ
+func recordSeasonResult(st store.Store, state *PVPState, result engine.Result) {
+	season, err := st.Seasons().LatestSeason()
+	if err != nil {
+		if err != store.ErrNotFound {
+			log.Println("LatestSeason error:", err)
+		}
+		return
+	}
+	if season.Closed || !time.Now().Before(season.EndsAt) {
+		return
+	}
+
+	for slot, res := range map[string]string{"A": result.ResultA, "B": result.ResultB} {
+		points := seasonSettings.DrawPoints
+		switch res {
+		case "Win":
+			points = seasonSettings.WinPoints
+		case "Lose":
+			points = -seasonSettings.LossPoints
+		}
+		if err := st.Seasons().AddSeasonResult(season.ID, state.UserIDs[slot], res, points); err != nil {
+			log.Println("AddSeasonResult error:", err)
+		}
+	}
+}
+
+// SeasonHandler GET /api/pvp/season?offset=0&limit=20 ฤดูกาลปัจจุบัน อันดับของผู้เล่น และตารางอันดับ
+func SeasonHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+
+		offset, limit := 0, 20
+		if v := r.URL.Query().Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				httputil.WriteJSONError(w, http.StatusBadRequest, "offset must not be negative")
+				return
+			}
+			offset = n
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > 100 {
+				httputil.WriteJSONError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+				return
+			}
+			limit = n
+		}
+
+		season, err := st.Seasons().LatestSeason()
+		if err == store.ErrNotFound {
+			httputil.WriteJSONError(w, http.StatusNotFound, "No season yet")
+			return
+		} else if err != nil {
+			log.Println("LatestSeason error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+
+		standings, err := st.Seasons().SeasonStandings(season.ID, offset, limit)
+		if err != nil {
+			log.Println("SeasonStandings error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		if standings == nil {
+			standings = []models.SeasonStanding{}
+		}
+
+		// ยังไม่ได้เล่นในฤดูกาลนี้ก็ส่ง null
+		var mine *models.SeasonStanding
+		mine, err = st.Seasons().SeasonStanding(season.ID, userID)
+		if err != nil && err != store.ErrNotFound {
+			log.Println("SeasonStanding error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"season":    season,
+			"standing":  mine,
+			"standings": standings,
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package battle
+
+import (
+	"clash_and_card/store"
+	"log"
+	"time"
+)
+
+// ----------- PvP series and rematch -----------
+//
+// ห้อง private เลือกเล่นแบบ best-of-3 หรือ best-of-5 ได้ด้วย RoomRules.BestOf (ค่าเริ่มต้นคือเกมเดียว)
+// เกมในห้องจบแล้วถ้า series ยังไม่จบ ทั้งสองฝั่งได้ series_next_game แล้วเกมถัดไปเริ่มเองหลัง seriesNextGameDelay
+// โดยโหลด match ใหม่จากฐานข้อมูลทุกเกม (exp และเลเวลที่ได้จากเกมก่อนมีผลทันที) คะแนนอยู่ใน series ของ round_result และ initialData
+// series จบแล้วได้ rematch_available ฝั่งหนึ่งส่ง rematch_request (เลือก bestOf ได้) อีกฝั่งตอบ rematch_accept หรือ rematch_decline
+// ภายใน rematchWindow ตกลงกันได้ก็เริ่ม series ใหม่ในห้องเดิม ไม่งั้นห้องปิด ฟอร์ฟิตยังจบทั้ง series และปิดห้องทันที
+
+const seriesNextGameDelay = 5 * time.Second
+
+var rematchWindow = 30 * time.Second
+
+// ConfigureRematch ตั้งเวลาที่รอให้ทั้งสองฝั่งตกลง rematch หลัง series จบ
+func ConfigureRematch(window time.Duration) {
+	rematchWindow = window
+}
+
+// SeriesScore คะแนนของ series ในห้อง Game คือเกมปัจจุบัน เริ่มที่ 1
+type SeriesScore struct {
+	BestOf int `json:"bestOf"`
+	Game   int `json:"game"`
+	WinsA  int `json:"winsA"`
+	WinsB  int `json:"winsB"`
+	Draws  int `json:"draws"`
+}
+
+func newSeries(bestOf int) SeriesScore {
+	if bestOf < 1 {
+		bestOf = 1
+	}
+	return SeriesScore{BestOf: bestOf, Game: 1}
+}
+
+// Over series จบแล้วหรือยัง มีฝั่งที่ชนะเกินครึ่ง หรือเล่นจบครบ BestOf เกมแล้ว (เสมอกันได้ถ้ามีเกมเสมอ)
+func (s SeriesScore) Over() bool {
+	need := s.BestOf/2 + 1
+	return s.WinsA >= need || s.WinsB >= need || s.WinsA+s.WinsB+s.Draws >= s.BestOf
+}
+
+// Winner slot ที่ชนะ series "draw" ถ้าเสมอ ว่างถ้ายังไม่จบ
+func (s SeriesScore) Winner() string {
+	switch {
+	case !s.Over():
+		return ""
+	case s.WinsA > s.WinsB:
+		return "A"
+	case s.WinsB > s.WinsA:
+		return "B"
+	}
+	return "draw"
+}
+
+// record นับผลเกมปัจจุบันจากผลของ slot A
+func (s *SeriesScore) record(resultA string) {
+	switch resultA {
+	case "Win":
+		s.WinsA++
+	case "Lose":
+		s.WinsB++
+	default:
+		s.Draws++
+	}
+}
+
+// next series เดิมที่เลื่อนไปเกมถัดไป
+func (s SeriesScore) next() SeriesScore {
+	s.Game++
+	return s
+}
+
+// view คะแนนจากมุมมองของ slot ใช้ใน initialData และ round_result
+func (s SeriesScore) view(slot string) map[string]interface{} {
+	playerWins, opponentWins := s.WinsA, s.WinsB
+	if slot == "B" {
+		playerWins, opponentWins = s.WinsB, s.WinsA
+	}
+	winner := s.Winner()
+	switch winner {
+	case slot:
+		winner = "player"
+	case opponentSlotOf(slot):
+		winner = "opponent"
+	}
+	return map[string]interface{}{
+		"bestOf":       s.BestOf,
+		"game":         s.Game,
+		"playerWins":   playerWins,
+		"opponentWins": opponentWins,
+		"draws":        s.Draws,
+		"over":         s.Over(),
+		"winner":       winner,
+	}
+}
+
+// publicView คะแนนสำหรับผู้ชม ใช้ slot "A"/"B"
+func (s SeriesScore) publicView() map[string]interface{} {
+	return map[string]interface{}{
+		"bestOf": s.BestOf,
+		"game":   s.Game,
+		"winsA":  s.WinsA,
+		"winsB":  s.WinsB,
+		"draws":  s.Draws,
+		"over":   s.Over(),
+		"winner": s.Winner(),
+	}
+}
+
+func validBestOf(n int) bool {
+	return n == 1 || n == 3 || n == 5
+}
+
+// pvpAfterGame เรียกเมื่อเกมในห้องจบตามปกติ (ไม่ใช่ฟอร์ฟิต) เริ่มนับเวลาไปเกมถัดไปของ series หรือรอ rematch
+func pvpAfterGame(st store.Store, roomID string, state *PVPState) {
+	state.Lock()
+	series, matchID := state.Series, state.ID
+	state.Unlock()
+
+	pvpManager.lock.Lock()
+	defer pvpManager.lock.Unlock()
+	match, ok := pvpManager.rooms[roomID]
+	if !ok {
+		return
+	}
+	match.waitingGame = matchID
+
+	if !series.Over() {
+		startsAt := time.Now().Add(seriesNextGameDelay)
+		msg := map[string]interface{}{
+			"type":     "series_next_game",
+			"game":     series.Game + 1,
+			"startsAt": startsAt,
+		}
+		for _, client := range match.Clients {
+			pvpSend(client, msg)
+		}
+		pvpBroadcastSpectators(match, msg)
+		match.afterGame = time.AfterFunc(seriesNextGameDelay, func() {
+			pvpNextGame(st, roomID, matchID, series.next())
+		})
+		return
+	}
+
+	// คู่ในการแข่งขันจบแค่ series เดียว ไม่มี rematch
+	if match.tournament.MatchID != "" {
+		go recordTournamentResult(st, match.tournament, tournamentResultFor(series.Winner()))
+		for _, client := range match.Clients {
+			pvpSend(client, map[string]interface{}{"type": "rematch_unavailable", "reason": "tournament"})
+		}
+		pvpCloseSeries(roomID, match)
+		return
+	}
+
+	// ไม่มีใครอยู่รอ rematch แล้ว
+	if len(match.Clients) == 0 {
+		pvpCloseSeries(roomID, match)
+		return
+	}
+	match.rematch = make(map[string]bool)
+	// rematch ใช้จำนวนเกมเท่า series ที่เพิ่งจบ เว้นแต่ฝั่งที่ขอจะเลือกใหม่
+	match.rematchBestOf = series.BestOf
+	deadline := time.Now().Add(rematchWindow)
+	for _, client := range match.Clients {
+		pvpSend(client, map[string]interface{}{
+			"type":     "rematch_available",
+			"deadline": deadline,
+			"bestOf":   series.BestOf,
+		})
+	}
+	match.afterGame = time.AfterFunc(rematchWindow, func() {
+		pvpManager.lock.Lock()
+		defer pvpManager.lock.Unlock()
+		if match, ok := pvpManager.rooms[roomID]; ok && match.waitingGame == matchID {
+			for _, client := range match.Clients {
+				pvpSend(client, map[string]interface{}{"type": "rematch_unavailable", "reason": "timeout"})
+			}
+			pvpCloseSeries(roomID, match)
+		}
+	})
+}
+
+// pvpNextGame เริ่มเกมใหม่ของห้องต่อจากเกม matchID ที่จบแล้ว ด้วยคะแนน series
+// ถ้าห้องไม่ได้รอเกมนั้นอยู่แล้ว (เริ่มไปแล้วหรือปิดไปแล้ว) ก็ไม่ทำอะไร
+func pvpNextGame(st store.Store, roomID, matchID string, series SeriesScore) {
+	pvpManager.lock.Lock()
+	match, ok := pvpManager.rooms[roomID]
+	if !ok || match.waitingGame != matchID {
+		pvpManager.lock.Unlock()
+		return
+	}
+	match.waitingGame = ""
+	if match.afterGame != nil {
+		match.afterGame.Stop()
+		match.afterGame = nil
+	}
+	match.rematch = nil
+	userAID, userBID := match.Players["A"], match.Players["B"]
+	pvpManager.lock.Unlock()
+
+	state, err := pvpNewGame(st, roomID, userAID, userBID, &series)
+	if err != nil {
+		log.Println("loadPVPStateFromDB error:", err)
+		disconnectAllClients(roomID)
+		return
+	}
+
+	// state เดิมจบแล้ว snapshot ถูกลบไปตอนจบ แทนที่ใน pvpStates ได้เลย
+	pvpPublishGame(st, roomID, state, true)
+
+	// ฝั่งที่ไม่ได้เชื่อมต่ออยู่ตอนเกมเริ่มต้องกลับมาภายใน reconnectGrace เหมือนหลุดกลางเกม
+	pvpManager.lock.Lock()
+	if match, ok := pvpManager.rooms[roomID]; ok {
+		now := time.Now()
+		for _, slot := range []string{"A", "B"} {
+			if _, connected := match.Clients[slot]; !connected {
+				pvpMarkDisconnected(st, roomID, match, slot, now)
+			}
+		}
+	}
+	pvpManager.lock.Unlock()
+}
+
+// pvpRematchWindow ห้องของ c กำลังรอ rematch อยู่หรือไม่ ถ้าไม่ก็ส่ง error ให้ c ต้องถือ pvpManager.lock ก่อนเรียก
+func pvpRematchWindow(c *PVPClient) (*PVPMatch, bool) {
+	match, ok := pvpManager.rooms[c.roomID]
+	if !ok || match.waitingGame == "" || match.rematch == nil {
+		pvpSend(c, map[string]interface{}{"type": "error", "error": "no rematch available"})
+		return nil, false
+	}
+	return match, true
+}
+
+// pvpRematchRequest c ขอ rematch ถ้าอีกฝั่งขอไว้แล้วถือว่าตอบรับคำขอของอีกฝั่ง
+func pvpRematchRequest(c *PVPClient, bestOf int) {
+	pvpManager.lock.Lock()
+	match, ok := pvpRematchWindow(c)
+	if !ok {
+		pvpManager.lock.Unlock()
+		return
+	}
+	opponentSlot := opponentSlotOf(c.slot)
+	if match.rematch[opponentSlot] {
+		pvpManager.lock.Unlock()
+		pvpRematchAccept(c)
+		return
+	}
+	if bestOf != 0 && !validBestOf(bestOf) {
+		pvpManager.lock.Unlock()
+		pvpSend(c, map[string]interface{}{"type": "error", "error": "bestOf must be 1, 3 or 5"})
+		return
+	}
+	if bestOf != 0 {
+		match.rematchBestOf = bestOf
+	}
+	match.rematch[c.slot] = true
+	if opponent, ok := match.Clients[opponentSlot]; ok {
+		pvpSend(opponent, map[string]interface{}{
+			"type":   "rematch_requested",
+			"bestOf": newSeries(match.rematchBestOf).BestOf,
+		})
+	}
+	pvpManager.lock.Unlock()
+}
+
+// pvpRematchAccept c ตอบรับคำขอ rematch ของอีกฝั่ง แล้วเริ่ม series ใหม่
+func pvpRematchAccept(c *PVPClient) {
+	pvpManager.lock.Lock()
+	match, ok := pvpRematchWindow(c)
+	if !ok {
+		pvpManager.lock.Unlock()
+		return
+	}
+	if !match.rematch[opponentSlotOf(c.slot)] {
+		pvpManager.lock.Unlock()
+		pvpSend(c, map[string]interface{}{"type": "error", "error": "opponent has not requested a rematch"})
+		return
+	}
+	matchID, series := match.waitingGame, newSeries(match.rematchBestOf)
+	pvpManager.lock.Unlock()
+
+	pvpNextGame(c.st, c.roomID, matchID, series)
+}
+
+// pvpRematchDecline c ปฏิเสธ rematch แจ้งอีกฝั่งแล้วปิดห้อง
+func pvpRematchDecline(c *PVPClient) {
+	pvpManager.lock.Lock()
+	defer pvpManager.lock.Unlock()
+	match, ok := pvpRematchWindow(c)
+	if !ok {
+		return
+	}
+	if opponent, ok := match.Clients[opponentSlotOf(c.slot)]; ok {
+		pvpSend(opponent, map[string]interface{}{"type": "rematch_declined"})
+	}
+	pvpCloseSeries(c.roomID, match)
+}
+
+// pvpCloseSeries เลิกรอ rematch แล้วปิดห้องหลังส่งข้อความที่ค้างอยู่ ต้องถือ pvpManager.lock ก่อนเรียก
+func pvpCloseSeries(roomID string, match *PVPMatch) {
+	match.waitingGame = ""
+	match.rematch = nil
+	if match.afterGame != nil {
+		match.afterGame.Stop()
+		match.afterGame = nil
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		disconnectAllClients(roomID)
+	}()
+}
@@ -0,0 +1,170 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ----------- Live match snapshots -----------
+//
+// gameStates และ pvpStates อยู่ในหน่วยความจำ จึงบันทึก snapshot ของแต่ละ match ลง stateStore
+// หลังทุกรอบ และโหลดกลับตอนเริ่มเซิร์ฟเวอร์ด้วย RestoreLiveMatches
+// ค่าเริ่มต้นเก็บในหน่วยความจำ (หายเมื่อรีสตาร์ท) ตั้งเป็นฐานข้อมูลได้ด้วย ConfigureStateStore
+
+var stateStore store.LiveMatchRepository = store.NewMemory().LiveMatches()
+
+// ConfigureStateStore ตั้งที่เก็บ snapshot ต้องเรียกก่อน RestoreLiveMatches และก่อนเริ่มรับ request
+func ConfigureStateStore(repo store.LiveMatchRepository) {
+	stateStore = repo
+}
+
+type liveMatchState struct {
+	Match engine.Snapshot `json:"match"`
+
+	// campaign
+	OwnerID      string    `json:"ownerID,omitempty"`
+	PlayingLevel int       `json:"playingLevel,omitempty"`
+	LastActive   time.Time `json:"lastActive"`
+
+	// pvp
+	RoomID     string            `json:"roomID,omitempty"`
+	UserIDs    map[string]string `json:"userIDs,omitempty"`
+	Rules      *RoomRules        `json:"rules,omitempty"`
+	Unranked   bool              `json:"unranked,omitempty"`
+	Series     *SeriesScore      `json:"series,omitempty"`
+	Tournament *tournamentRef    `json:"tournament,omitempty"`
+}
+
+func saveLiveState(matchID, mode string, ls liveMatchState) {
+	data, err := json.Marshal(ls)
+	if err != nil {
+		fmt.Println("[ERROR] marshal live match:", matchID, err)
+		return
+	}
+	if err := stateStore.SaveLiveMatch(&models.LiveMatch{ID: matchID, Mode: mode, State: string(data)}); err != nil {
+		fmt.Println("[ERROR] save live match:", matchID, err)
+	}
+}
+
+// saveCampaignState บันทึก snapshot ของ match campaign ต้องถือ lock ของ gs ก่อนเรียก
+func saveCampaignState(gs *GameState) {
+	snap, err := gs.Snapshot()
+	if err != nil {
+		fmt.Println("[ERROR] snapshot campaign match:", gs.ID, err)
+		return
+	}
+	saveLiveState(gs.ID, modeCampaign, liveMatchState{
+		Match:        snap,
+		OwnerID:      gs.OwnerID,
+		PlayingLevel: gs.PlayingLevel,
+		LastActive:   gs.LastActive,
+	})
+}
+
+// savePVPState บันทึก snapshot ของ match PvP ต้องถือ lock ของ state ก่อนเรียก
+func savePVPState(state *PVPState) {
+	snap, err := state.Snapshot()
+	if err != nil {
+		fmt.Println("[ERROR] snapshot pvp match:", state.ID, err)
+		return
+	}
+	rules, series := state.Rules, state.Series
+	ls := liveMatchState{
+		Match:    snap,
+		RoomID:   state.RoomID,
+		UserIDs:  state.UserIDs,
+		Rules:    &rules,
+		Unranked: !state.Ranked,
+		Series:   &series,
+	}
+	if state.tournament.MatchID != "" {
+		ls.Tournament = &state.tournament
+	}
+	saveLiveState(state.ID, modePVP, ls)
+}
+
+// deleteLiveState ลบ snapshot ของ match ที่จบหรือถูกทิ้งแล้ว
+func deleteLiveState(matchID string) {
+	if err := stateStore.DeleteLiveMatch(matchID); err != nil {
+		fmt.Println("[ERROR] delete live match:", matchID, err)
+	}
+}
+
+// RestoreLiveMatches โหลด match ที่ยังเล่นไม่จบกลับเข้า gameStates และ pvpStates
+// match PvP จะสร้างห้องเดิมขึ้นใหม่และรอให้ผู้เล่นทั้งสองเชื่อมต่อเข้ามาอีกครั้ง
+func RestoreLiveMatches(st store.Store) (campaign, pvp int, err error) {
+	matches, err := stateStore.ListLiveMatches()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, lm := range matches {
+		var ls liveMatchState
+		if err := json.Unmarshal([]byte(lm.State), &ls); err != nil {
+			fmt.Println("[ERROR] decode live match:", lm.ID, err)
+			continue
+		}
+
+		if ls.Match.Status != engine.StatusOnGoing {
+			deleteLiveState(lm.ID)
+			continue
+		}
+
+		switch lm.Mode {
+		case modeCampaign:
+			// นับเวลาว่างใหม่ตั้งแต่เซิร์ฟเวอร์กลับมา ช่วงที่เซิร์ฟเวอร์ล่มไม่ใช่ความผิดของผู้เล่น
+			gs := &GameState{
+				PVPState:     PVPState{ID: lm.ID},
+				PlayingLevel: ls.PlayingLevel,
+				OwnerID:      ls.OwnerID,
+				LastActive:   time.Now(),
+			}
+			if err := gs.Restore(ls.Match); err != nil {
+				fmt.Println("[ERROR] restore live match:", lm.ID, err)
+				continue
+			}
+			if previous := addGameState(gs); previous != nil {
+				// ไม่ควรเกิด แต่ถ้ามี snapshot ของผู้เล่นคนเดียวกันซ้อนกันให้เก็บอันที่โหลดทีหลัง
+				deleteLiveState(previous.ID)
+			}
+			campaign++
+		case modePVP:
+			state := &PVPState{ID: lm.ID, RoomID: ls.RoomID, UserIDs: ls.UserIDs, Rules: defaultRoomRules(), Ranked: !ls.Unranked}
+			if ls.Rules != nil {
+				state.Rules = *ls.Rules
+			}
+			state.Series = newSeries(state.Rules.BestOf)
+			if ls.Series != nil {
+				state.Series = *ls.Series
+			}
+			if ls.Tournament != nil {
+				state.tournament = *ls.Tournament
+			}
+			if err := state.Restore(ls.Match); err != nil {
+				fmt.Println("[ERROR] restore live match:", lm.ID, err)
+				continue
+			}
+			state.Lock()
+			pvpStartTurn(st, state)
+			state.Unlock()
+			pvpStatesMu.Lock()
+			pvpStates[ls.RoomID] = state
+			pvpStatesMu.Unlock()
+
+			// ยังไม่มีใครเชื่อมต่อ ให้เวลากลับเข้ามาเท่ากับตอนหลุด ไม่งั้นห้องจะค้างตลอดไป
+			pvpManager.lock.Lock()
+			room := newPVPRoom(ls.RoomID, ls.UserIDs["A"], ls.UserIDs["B"])
+			room.Rules, room.Ranked, room.tournament = state.Rules, state.Ranked, state.tournament
+			now := time.Now()
+			pvpMarkDisconnected(st, ls.RoomID, room, "A", now)
+			pvpMarkDisconnected(st, ls.RoomID, room, "B", now)
+			pvpManager.lock.Unlock()
+			pvp++
+		}
+	}
+	return campaign, pvp, nil
+}
@@ -0,0 +1,227 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/store"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ----------- PvP spectators -----------
+//
+// ผู้ชมต่อ /ws/pvp?room=<roomID>&spectate=true ได้ทุกห้องที่ไม่ใช่ห้อง private และตัวเองไม่ได้เป็นผู้เล่น ส่งข้อความมาก็ไม่มีผลกับเกม
+// ได้ spectate_state เมื่อเข้าห้อง (และเมื่อเกมเริ่ม) round_result แบบสาธารณะทุกรอบ และ match_ended ถ้าจบแบบฟอร์ฟิต
+// มุมมองสาธารณะไม่มีการ์ดในมือของใครเลย มีแค่การ์ดที่ลงไปแล้ว HP และจำนวนการ์ดที่เหลือ
+
+const maxSpectators = 50
+
+// pvpPublicPlayer ข้อมูลของผู้เล่น slot ที่ผู้ชมเห็นได้ ต้องถือ lock ของ state ก่อนเรียก
+func pvpPublicPlayer(p *engine.PlayerData) map[string]interface{} {
+	return map[string]interface{}{
+		"name":          p.Name,
+		"level":         p.Level,
+		"class":         p.Class,
+		"currentHP":     p.CurrentHP,
+		"maxHP":         p.Stat.HP,
+		"handSize":      len(p.Hand),
+		"cardRemaining": engine.CountCards(append(p.Deck, p.Hand...)),
+		"trueSight":     p.TrueSight,
+	}
+}
+
+// pvpSpectateState สถานะปัจจุบันของเกมสำหรับผู้ชม ต้องถือ lock ของ state ก่อนเรียก
+func pvpSpectateState(state *PVPState) map[string]interface{} {
+	return map[string]interface{}{
+		"type":         "spectate_state",
+		"matchID":      state.ID,
+		"round":        state.Round,
+		"gameStatus":   state.Status,
+		"turnDeadline": state.turnDeadline,
+		"series":       state.Series.publicView(),
+		"players": map[string]interface{}{
+			"A": pvpPublicPlayer(&state.PlayerA),
+			"B": pvpPublicPlayer(&state.PlayerB),
+		},
+		"selected": map[string]bool{
+			"A": state.Selected["A"] != nil,
+			"B": state.Selected["B"] != nil,
+		},
+	}
+}
+
+// pvpPublicRoundResult round_result ของผู้ชม ใช้ slot "A"/"B" แทน player/opponent ต้องถือ lock ของ state ก่อนเรียก
+func pvpPublicRoundResult(state *PVPState, round *engine.RoundResult, autoPlayed map[string]bool) map[string]interface{} {
+	a := pvpPublicPlayer(&state.PlayerA)
+	a["cardPlayed"] = round.CardA
+	a["doDamage"] = round.DamageToB
+	a["specialEvent"] = round.SpecialEventA
+	a["autoPlayed"] = autoPlayed["A"]
+
+	b := pvpPublicPlayer(&state.PlayerB)
+	b["cardPlayed"] = round.CardB
+	b["doDamage"] = round.DamageToA
+	b["specialEvent"] = round.SpecialEventB
+	b["autoPlayed"] = autoPlayed["B"]
+
+	resp := map[string]interface{}{
+		"type":        "round_result",
+		"round":       round.Number,
+		"gameStatus":  state.Status,
+		"roundWinner": round.Winner,
+		"players":     map[string]interface{}{"A": a, "B": b},
+		"series":      state.Series.publicView(),
+	}
+	if state.Status == engine.StatusOnGoing {
+		resp["turnDeadline"] = state.turnDeadline
+	}
+	return resp
+}
+
+// pvpBroadcastSpectators ส่งข้อความให้ผู้ชมทุกคนในห้อง ต้องถือ pvpManager.lock ก่อนเรียก
+func pvpBroadcastSpectators(match *PVPMatch, v interface{}) {
+	if len(match.Spectators) == 0 {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	for c := range match.Spectators {
+		select {
+		case c.send <- data:
+		default:
+		}
+	}
+}
+
+// pvpJoinAsSpectator เพิ่ม connection เป็นผู้ชมของห้อง ถ้าเกมเริ่มแล้วส่ง spectate_state ให้ทันที
+func pvpJoinAsSpectator(st store.Store, conn *websocket.Conn, roomID, userID string) {
+	pvpManager.lock.Lock()
+	match, ok := pvpManager.rooms[roomID]
+	if !ok {
+		pvpManager.lock.Unlock()
+		pvpReject(conn, "room not found")
+		return
+	}
+	if match.InviteCode != "" {
+		pvpManager.lock.Unlock()
+		pvpReject(conn, "room is private")
+		return
+	}
+	if match.Players["A"] == userID || match.Players["B"] == userID {
+		pvpManager.lock.Unlock()
+		pvpReject(conn, "players cannot spectate their own match")
+		return
+	}
+	if len(match.Spectators) >= maxSpectators {
+		pvpManager.lock.Unlock()
+		pvpReject(conn, "too many spectators")
+		return
+	}
+
+	client := &PVPClient{
+		conn:   conn,
+		roomID: roomID,
+		userID: userID,
+		send:   make(chan []byte, 256),
+		st:     st,
+	}
+	match.Spectators[client] = true
+
+	// ส่งก่อนปล่อย lock เพื่อให้มาก่อน round_result ที่ broadcast ตามมาเสมอ
+	pvpSend(client, map[string]interface{}{"type": "spectating", "roomID": roomID})
+	pvpStatesMu.Lock()
+	state, started := pvpStates[roomID]
+	pvpStatesMu.Unlock()
+	if started {
+		state.Lock()
+		pvpSend(client, pvpSpectateState(state))
+		state.Unlock()
+	}
+	pvpManager.lock.Unlock()
+
+	go pvpSpectatorRead(client)
+	go pvpWrite(client)
+}
+
+// pvpSpectatorRead อ่านทิ้งจนกว่าผู้ชมจะปิด connection แล้วเอาออกจากห้อง
+func pvpSpectatorRead(c *PVPClient) {
+	defer func() {
+		c.conn.Close()
+		pvpManager.lock.Lock()
+		if match, ok := pvpManager.rooms[c.roomID]; ok {
+			delete(match.Spectators, c)
+		}
+		pvpManager.lock.Unlock()
+	}()
+
+	for {
+		if _, _, err := c.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+type liveMatchPlayer struct {
+	Name      string `json:"name"`
+	Level     int    `json:"level"`
+	Class     string `json:"class"`
+	CurrentHP int    `json:"currentHP"`
+}
+
+type liveMatch struct {
+	RoomID     string                     `json:"roomID"`
+	MatchID    string                     `json:"matchID"`
+	Round      int                        `json:"round"`
+	Spectators int                        `json:"spectators"`
+	StartedAt  time.Time                  `json:"startedAt"`
+	Players    map[string]liveMatchPlayer `json:"players"`
+}
+
+// LiveMatchesHandler GET /api/pvp/live รายการ match PvP ที่กำลังเล่นอยู่ ใช้เลือกห้องที่จะเข้าไปชม
+func LiveMatchesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		matches := []liveMatch{}
+
+		pvpManager.lock.Lock()
+		pvpStatesMu.Lock()
+		for roomID, match := range pvpManager.rooms {
+			state, ok := pvpStates[roomID]
+			if !ok || match.InviteCode != "" {
+				continue
+			}
+			state.Lock()
+			if state.Status == engine.StatusOnGoing {
+				matches = append(matches, liveMatch{
+					RoomID:     roomID,
+					MatchID:    state.ID,
+					Round:      state.Round,
+					Spectators: len(match.Spectators),
+					StartedAt:  match.CreatedAt,
+					Players: map[string]liveMatchPlayer{
+						"A": {state.PlayerA.Name, state.PlayerA.Level, state.PlayerA.Class, state.PlayerA.CurrentHP},
+						"B": {state.PlayerB.Name, state.PlayerB.Level, state.PlayerB.Class, state.PlayerB.CurrentHP},
+					},
+				})
+			}
+			state.Unlock()
+		}
+		pvpStatesMu.Unlock()
+		pvpManager.lock.Unlock()
+
+		// ห้องที่มีคนดูมากอยู่บนสุด
+		sort.Slice(matches, func(i, j int) bool {
+			if matches[i].Spectators != matches[j].Spectators {
+				return matches[i].Spectators > matches[j].Spectators
+			}
+			return matches[i].StartedAt.Before(matches[j].StartedAt)
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"matches": matches})
+	}
+}
@@ -0,0 +1,647 @@
+package battle
+
+import (
+	"clash_and_card/httputil"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"clash_and_card/user"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/bits"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ----------- Tournaments -----------
+//
+// ใครก็สร้างการแข่งขันได้ด้วย POST /api/tournaments แล้วผู้เล่นสมัครด้วย POST /api/tournaments/{id}/register
+// ผู้สร้างเริ่มด้วย POST /api/tournaments/{id}/start ผู้เล่นได้ seed ตาม rating แล้วจับคู่รอบแรก
+// แต่ละคู่ได้ห้อง PvP ของตัวเอง (ไม่นับ rating เล่น best-of ตามที่ตั้งไว้) roomID อยู่ใน GET /api/tournaments/{id}
+// ผู้เล่นทั้งสองต่อ /ws/pvp?room=<roomID> แล้วเกมเริ่มเองเหมือนห้องจาก matchmaking ผู้ชมเข้าชมได้ตามปกติ
+// series จบหรือฟอร์ฟิตแล้วผลถูกบันทึกเอง ทุกคู่ในรอบจบแล้วจึงจับคู่รอบถัดไป
+// คู่ที่ไม่มาภายใน tournamentNoShowTimeout ฝั่งที่เชื่อมต่ออยู่ชนะ ไม่มาทั้งคู่ถือว่าเสมอ
+//
+// single elimination: แพ้แล้วตกรอบ จำนวนคนไม่ครบกำลังสองให้ seed สูงได้ bye ในรอบแรก เสมอกันให้ slot A (seed ที่ดีกว่าในรอบแรก) ผ่าน
+// Swiss: ทุกคนเล่นครบทุกรอบ ชนะได้ tournamentWinPoints เสมอได้ tournamentDrawPoints จับคู่คนคะแนนใกล้กันที่ยังไม่เคยเจอกัน
+// จำนวนคนเป็นคี่ คนอันดับต่ำสุดที่ยังไม่เคยได้ bye ได้ bye (นับเป็นชนะ)
+
+const (
+	TournamentSingleElimination = "single_elimination"
+	TournamentSwiss             = "swiss"
+
+	tournamentRegistration = "registration"
+	tournamentRunning      = "running"
+	tournamentFinished     = "finished"
+
+	tournamentWinPoints  = 3
+	tournamentDrawPoints = 1
+
+	// ผลของคู่ นอกจาก slot ที่ชนะ ("A" หรือ "B")
+	tournamentDraw = "draw"
+	tournamentBye  = "bye"
+
+	maxTournamentPlayers = 64
+	maxSwissRounds       = 10
+)
+
+var tournamentNoShowTimeout = 10 * time.Minute
+
+// ConfigureTournaments ตั้งเวลาที่รอผู้เล่นเข้าห้องของคู่ตัวเองก่อนตัดสินว่าไม่มา
+func ConfigureTournaments(noShow time.Duration) {
+	tournamentNoShowTimeout = noShow
+}
+
+// tournamentsMu ให้การบันทึกผลและจับคู่ทำทีละครั้ง กันการจับคู่รอบถัดไปซ้ำเมื่อสองคู่จบพร้อมกัน
+// ห้ามถือ pvpManager.lock ขณะรอ lock นี้
+var tournamentsMu sync.Mutex
+
+// tournamentRef ห้อง PvP (และ match ในห้อง) เป็นของคู่ไหนในการแข่งขัน ว่างคือไม่ใช่ห้องการแข่งขัน
+type tournamentRef struct {
+	TournamentID string `json:"tournamentID"`
+	MatchID      string `json:"matchID"` // models.TournamentMatch.ID
+}
+
+// openTournamentRoom สร้างห้อง PvP ของคู่ m
+func openTournamentRoom(t *models.Tournament, m models.TournamentMatch) {
+	pvpManager.lock.Lock()
+	defer pvpManager.lock.Unlock()
+
+	if _, exists := pvpManager.rooms[m.RoomID]; exists {
+		return
+	}
+	room := newPVPRoom(m.RoomID, m.PlayerAID, m.PlayerBID)
+	room.Rules.BestOf = t.BestOf
+	room.Ranked = false
+	room.tournament = tournamentRef{TournamentID: t.ID, MatchID: m.ID}
+}
+
+// RestoreTournaments สร้างห้องของคู่ที่ยังไม่จบในการแข่งขันที่กำลังแข่งอยู่ขึ้นใหม่ ต้องเรียกหลัง RestoreLiveMatches
+func RestoreTournaments(st store.Store) (int, error) {
+	tournaments, err := st.Tournaments().ListTournaments(tournamentRunning)
+	if err != nil {
+		return 0, err
+	}
+	opened := 0
+	for i := range tournaments {
+		matches, err := st.Tournaments().ListTournamentMatches(tournaments[i].ID)
+		if err != nil {
+			return opened, err
+		}
+		for _, m := range matches {
+			if m.Result == "" && m.RoomID != "" {
+				openTournamentRoom(&tournaments[i], m)
+				opened++
+			}
+		}
+	}
+	return opened, nil
+}
+
+// recordTournamentResult บันทึกผลของคู่ ref (winner เป็น "A", "B" หรือ tournamentDraw)
+// ถ้าทุกคู่ในรอบจบแล้วก็จับคู่รอบถัดไปหรือจบการแข่งขัน คู่ที่บันทึกไปแล้วไม่บันทึกซ้ำ
+func recordTournamentResult(st store.Store, ref tournamentRef, winner string) {
+	tournamentsMu.Lock()
+	defer tournamentsMu.Unlock()
+
+	t, err := st.Tournaments().GetTournament(ref.TournamentID)
+	if err != nil {
+		log.Println("GetTournament error:", err)
+		return
+	}
+	if t.Status != tournamentRunning {
+		return
+	}
+	players, err := st.Tournaments().ListTournamentPlayers(t.ID)
+	if err != nil {
+		log.Println("ListTournamentPlayers error:", err)
+		return
+	}
+	matches, err := st.Tournaments().ListTournamentMatches(t.ID)
+	if err != nil {
+		log.Println("ListTournamentMatches error:", err)
+		return
+	}
+
+	var m *models.TournamentMatch
+	for i := range matches {
+		if matches[i].ID == ref.MatchID {
+			m = &matches[i]
+		}
+	}
+	if m == nil || m.Result != "" {
+		return
+	}
+	applyTournamentResult(t, players, m, winner)
+
+	var created []models.TournamentMatch
+	roundDone := true
+	for _, other := range matches {
+		if other.Round == t.CurrentRound && other.Result == "" {
+			roundDone = false
+		}
+	}
+	if roundDone {
+		created = nextTournamentRound(t, players, matches)
+	}
+
+	if err := st.Tournaments().SaveTournament(t, players, []models.TournamentMatch{*m}, created); err != nil {
+		log.Println("SaveTournament error:", err)
+		return
+	}
+	for _, c := range created {
+		if c.Result == "" {
+			openTournamentRoom(t, c)
+		}
+	}
+	if t.Status == tournamentFinished {
+		log.Printf("[INFO] tournament %s finished, winner %s", t.ID, t.WinnerID)
+	}
+}
+
+// applyTournamentResult กรอกผลของคู่ m และบวกผลรวมของผู้เล่นทั้งสอง
+func applyTournamentResult(t *models.Tournament, players []models.TournamentPlayer, m *models.TournamentMatch, winner string) {
+	a, b := tournamentPlayer(players, m.PlayerAID), tournamentPlayer(players, m.PlayerBID)
+	m.Result = winner
+	switch winner {
+	case "A":
+		m.WinnerID = m.PlayerAID
+		a.Wins++
+		a.Points += tournamentWinPoints
+		b.Losses++
+		b.Eliminated = t.Format == TournamentSingleElimination
+	case "B":
+		m.WinnerID = m.PlayerBID
+		b.Wins++
+		b.Points += tournamentWinPoints
+		a.Losses++
+		a.Eliminated = t.Format == TournamentSingleElimination
+	default:
+		a.Draws++
+		a.Points += tournamentDrawPoints
+		b.Draws++
+		b.Points += tournamentDrawPoints
+		if t.Format == TournamentSingleElimination {
+			// ต้องมีคนผ่าน ให้ slot A ผ่าน
+			m.WinnerID = m.PlayerAID
+			b.Eliminated = true
+		}
+	}
+}
+
+func tournamentPlayer(players []models.TournamentPlayer, userID string) *models.TournamentPlayer {
+	for i := range players {
+		if players[i].UserID == userID {
+			return &players[i]
+		}
+	}
+	return &models.TournamentPlayer{}
+}
+
+// nextTournamentRound จับคู่รอบถัดไปต่อจากรอบปัจจุบันที่จบแล้ว หรือจบการแข่งขันถ้าครบทุกรอบ
+// ก่อนเริ่ม (CurrentRound = 0) ผู้เล่นต้องมี Seed แล้ว
+func nextTournamentRound(t *models.Tournament, players []models.TournamentPlayer, matches []models.TournamentMatch) []models.TournamentMatch {
+	if t.CurrentRound >= t.Rounds {
+		t.Status = tournamentFinished
+		if standings := tournamentStandings(t, players); len(standings) > 0 {
+			t.WinnerID = standings[0].UserID
+		}
+		return nil
+	}
+
+	var created []models.TournamentMatch
+	if t.Format == TournamentSingleElimination {
+		created = eliminationPairings(t, players, matches)
+	} else {
+		created = swissPairings(t, players, matches)
+	}
+	t.CurrentRound++
+	for i := range created {
+		created[i].ID = uuid.New().String()
+		created[i].Round = t.CurrentRound
+		created[i].Table = i + 1
+		if created[i].PlayerBID == "" {
+			created[i].Result = tournamentBye
+			created[i].WinnerID = created[i].PlayerAID
+		} else {
+			created[i].RoomID = uuid.New().String()
+		}
+	}
+	return created
+}
+
+// eliminationRounds จำนวนรอบของ single elimination สำหรับผู้เล่น n คน
+func eliminationRounds(n int) int {
+	return bits.Len(uint(n - 1))
+}
+
+// bracketOrder ลำดับ seed ในสายของ bracket ขนาด size ให้ seed 1 กับ 2 เจอกันได้แค่รอบชิง เช่น 8 คือ 1 8 4 5 2 7 3 6
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+func eliminationPairings(t *models.Tournament, players []models.TournamentPlayer, matches []models.TournamentMatch) []models.TournamentMatch {
+	var created []models.TournamentMatch
+	if t.CurrentRound == 0 {
+		bySeed := make(map[int]string)
+		for _, p := range players {
+			bySeed[p.Seed] = p.UserID
+		}
+		order := bracketOrder(1 << eliminationRounds(len(players)))
+		for i := 0; i < len(order); i += 2 {
+			// seed ที่เกินจำนวนผู้เล่นคือ bye ซึ่งอยู่ตำแหน่งหลังของคู่เสมอ
+			created = append(created, models.TournamentMatch{PlayerAID: bySeed[order[i]], PlayerBID: bySeed[order[i+1]]})
+		}
+		return created
+	}
+
+	// ผู้ชนะโต๊ะ 1 เจอโต๊ะ 2, โต๊ะ 3 เจอโต๊ะ 4, ...
+	var winners []string
+	for _, m := range matches {
+		if m.Round == t.CurrentRound {
+			winners = append(winners, m.WinnerID)
+		}
+	}
+	for i := 0; i+1 < len(winners); i += 2 {
+		created = append(created, models.TournamentMatch{PlayerAID: winners[i], PlayerBID: winners[i+1]})
+	}
+	return created
+}
+
+func swissPairings(t *models.Tournament, players []models.TournamentPlayer, matches []models.TournamentMatch) []models.TournamentMatch {
+	played := make(map[[2]string]bool)
+	hadBye := make(map[string]bool)
+	for _, m := range matches {
+		if m.Result == tournamentBye {
+			hadBye[m.PlayerAID] = true
+			continue
+		}
+		played[[2]string{m.PlayerAID, m.PlayerBID}] = true
+		played[[2]string{m.PlayerBID, m.PlayerAID}] = true
+	}
+
+	standings := tournamentStandings(t, players)
+	var created []models.TournamentMatch
+	if len(standings)%2 == 1 {
+		bye := len(standings) - 1
+		for i := len(standings) - 1; i >= 0; i-- {
+			if !hadBye[standings[i].UserID] {
+				bye = i
+				break
+			}
+		}
+		p := tournamentPlayer(players, standings[bye].UserID)
+		p.Wins++
+		p.Points += tournamentWinPoints
+		created = append(created, models.TournamentMatch{PlayerAID: p.UserID})
+		standings = append(standings[:bye], standings[bye+1:]...)
+	}
+
+	// จับคนบนสุดที่เหลือกับคนถัดไปที่ยังไม่เคยเจอกัน ถ้าเจอกันหมดแล้วก็จับกับคนถัดไปเลย
+	paired := make([]bool, len(standings))
+	var pairs []models.TournamentMatch
+	for i := range standings {
+		if paired[i] {
+			continue
+		}
+		opponent := -1
+		for j := i + 1; j < len(standings); j++ {
+			if paired[j] {
+				continue
+			}
+			if opponent < 0 {
+				opponent = j
+			}
+			if !played[[2]string{standings[i].UserID, standings[j].UserID}] {
+				opponent = j
+				break
+			}
+		}
+		paired[i], paired[opponent] = true, true
+		pairs = append(pairs, models.TournamentMatch{PlayerAID: standings[i].UserID, PlayerBID: standings[opponent].UserID})
+	}
+	// โต๊ะ bye อยู่ท้ายสุด
+	return append(pairs, created...)
+}
+
+// tournamentStandings อันดับปัจจุบัน single elimination เรียงคนที่ยังไม่ตกรอบก่อน แล้วตามจำนวนชนะ
+// Swiss เรียงตามคะแนน แล้วจำนวนชนะ เท่ากันใช้ seed
+func tournamentStandings(t *models.Tournament, players []models.TournamentPlayer) []models.TournamentPlayer {
+	standings := make([]models.TournamentPlayer, len(players))
+	copy(standings, players)
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if t.Format == TournamentSingleElimination && a.Eliminated != b.Eliminated {
+			return !a.Eliminated
+		}
+		if t.Format == TournamentSwiss && a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.Seed < b.Seed
+	})
+	return standings
+}
+
+// tournamentResultFor ผลของคู่จากผู้ชนะของห้อง ("A", "B" หรือ "draw" แบบเดียวกับ SeriesScore.Winner)
+func tournamentResultFor(winnerSlot string) string {
+	if winnerSlot == "A" || winnerSlot == "B" {
+		return winnerSlot
+	}
+	return tournamentDraw
+}
+
+// CreateTournamentHandler POST /api/tournaments
+func CreateTournamentHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+
+		var req struct {
+			Name       string `json:"name"`
+			Format     string `json:"format"`
+			MaxPlayers int    `json:"maxPlayers"`
+			Rounds     int    `json:"rounds"` // Swiss เท่านั้น 0 คือคิดจากจำนวนผู้เล่นตอนเริ่ม
+			BestOf     int    `json:"bestOf"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.MaxPlayers == 0 {
+			req.MaxPlayers = 16
+		}
+		if req.BestOf == 0 {
+			req.BestOf = 1
+		}
+
+		var errMsg string
+		switch {
+		case req.Name == "" || len(req.Name) > 64:
+			errMsg = "name must be 1 to 64 characters"
+		case req.Format != TournamentSingleElimination && req.Format != TournamentSwiss:
+			errMsg = "format must be single_elimination or swiss"
+		case req.MaxPlayers < 2 || req.MaxPlayers > maxTournamentPlayers:
+			errMsg = fmt.Sprintf("maxPlayers must be between 2 and %d", maxTournamentPlayers)
+		case req.Rounds < 0 || req.Rounds > maxSwissRounds || (req.Format == TournamentSingleElimination && req.Rounds != 0):
+			errMsg = fmt.Sprintf("rounds must be between 0 and %d and only set for swiss", maxSwissRounds)
+		case !validBestOf(req.BestOf):
+			errMsg = "bestOf must be 1, 3 or 5"
+		}
+		if errMsg != "" {
+			httputil.WriteJSONError(w, http.StatusBadRequest, errMsg)
+			return
+		}
+
+		t := &models.Tournament{
+			ID:         uuid.New().String(),
+			Name:       req.Name,
+			Format:     req.Format,
+			Status:     tournamentRegistration,
+			BestOf:     req.BestOf,
+			MaxPlayers: req.MaxPlayers,
+			Rounds:     req.Rounds,
+			CreatedBy:  userID,
+			CreatedAt:  time.Now(),
+		}
+		if err := st.Tournaments().CreateTournament(t); err != nil {
+			log.Println("CreateTournament error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(t)
+	}
+}
+
+// ListTournamentsHandler GET /api/tournaments?status=registration (ค่าเริ่มต้น) running หรือ finished
+func ListTournamentsHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = tournamentRegistration
+		}
+		if status != tournamentRegistration && status != tournamentRunning && status != tournamentFinished {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "status must be registration, running or finished")
+			return
+		}
+
+		tournaments, err := st.Tournaments().ListTournaments(status)
+		if err != nil {
+			log.Println("ListTournaments error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		if tournaments == nil {
+			tournaments = []models.Tournament{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"tournaments": tournaments})
+	}
+}
+
+// RegisterTournamentHandler POST /api/tournaments/{id}/register
+func RegisterTournamentHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+		id := mux.Vars(r)["id"]
+
+		tournamentsMu.Lock()
+		defer tournamentsMu.Unlock()
+
+		t, err := st.Tournaments().GetTournament(id)
+		if err == store.ErrNotFound {
+			httputil.WriteJSONError(w, http.StatusNotFound, "Tournament not found")
+			return
+		} else if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		if t.Status != tournamentRegistration {
+			httputil.WriteJSONError(w, http.StatusConflict, "registration closed")
+			return
+		}
+		players, err := st.Tournaments().ListTournamentPlayers(id)
+		if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		for _, p := range players {
+			if p.UserID == userID {
+				httputil.WriteJSONError(w, http.StatusConflict, "already registered")
+				return
+			}
+		}
+		if len(players) >= t.MaxPlayers {
+			httputil.WriteJSONError(w, http.StatusConflict, "tournament full")
+			return
+		}
+		if err := st.Tournaments().AddTournamentPlayer(id, userID); err != nil {
+			log.Println("AddTournamentPlayer error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"tournamentID": id,
+			"registered":   true,
+			"players":      len(players) + 1,
+		})
+	}
+}
+
+// StartTournamentHandler POST /api/tournaments/{id}/start ผู้สร้างปิดรับสมัคร ให้ seed แล้วจับคู่รอบแรก
+func StartTournamentHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
+		id := mux.Vars(r)["id"]
+
+		tournamentsMu.Lock()
+		defer tournamentsMu.Unlock()
+
+		t, err := st.Tournaments().GetTournament(id)
+		if err == store.ErrNotFound {
+			httputil.WriteJSONError(w, http.StatusNotFound, "Tournament not found")
+			return
+		} else if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		if t.CreatedBy != userID {
+			httputil.WriteJSONError(w, http.StatusForbidden, "only the creator can start the tournament")
+			return
+		}
+		if t.Status != tournamentRegistration {
+			httputil.WriteJSONError(w, http.StatusConflict, "tournament already started")
+			return
+		}
+		players, err := st.Tournaments().ListTournamentPlayers(id)
+		if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		if len(players) < 2 {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "need at least 2 players")
+			return
+		}
+
+		// seed ตาม rating สูงไปต่ำ เท่ากันใช้ลำดับที่สมัคร
+		ratings := make(map[string]int)
+		for _, p := range players {
+			u, err := st.Users().GetUser(p.UserID)
+			if err != nil {
+				httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+				return
+			}
+			ratings[p.UserID] = u.Rating
+		}
+		sort.SliceStable(players, func(i, j int) bool {
+			return ratings[players[i].UserID] > ratings[players[j].UserID]
+		})
+		for i := range players {
+			players[i].Seed = i + 1
+		}
+
+		t.Status = tournamentRunning
+		if t.Format == TournamentSingleElimination {
+			t.Rounds = eliminationRounds(len(players))
+		} else if t.Rounds == 0 {
+			t.Rounds = max(eliminationRounds(len(players)), 1)
+		}
+		created := nextTournamentRound(t, players, nil)
+		if err := st.Tournaments().SaveTournament(t, players, nil, created); err != nil {
+			log.Println("SaveTournament error:", err)
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		for _, m := range created {
+			if m.Result == "" {
+				openTournamentRoom(t, m)
+			}
+		}
+
+		writeTournament(w, t, players, created)
+	}
+}
+
+// TournamentHandler GET /api/tournaments/{id} ข้อมูลการแข่งขัน อันดับ และคู่ของทุกรอบ (bracket)
+func TournamentHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		t, err := st.Tournaments().GetTournament(id)
+		if err == store.ErrNotFound {
+			httputil.WriteJSONError(w, http.StatusNotFound, "Tournament not found")
+			return
+		} else if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		players, err := st.Tournaments().ListTournamentPlayers(id)
+		if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		matches, err := st.Tournaments().ListTournamentMatches(id)
+		if err != nil {
+			httputil.WriteJSONError(w, http.StatusInternalServerError, "Server error")
+			return
+		}
+		writeTournament(w, t, players, matches)
+	}
+}
+
+type tournamentMatchView struct {
+	models.TournamentMatch
+	PlayerA string `json:"playerA"`
+	PlayerB string `json:"playerB,omitempty"`
+}
+
+func writeTournament(w http.ResponseWriter, t *models.Tournament, players []models.TournamentPlayer, matches []models.TournamentMatch) {
+	names := make(map[string]string)
+	for _, p := range players {
+		names[p.UserID] = p.Username
+	}
+
+	rounds := []map[string]interface{}{}
+	for _, m := range matches {
+		if len(rounds) < m.Round {
+			rounds = append(rounds, map[string]interface{}{"round": m.Round, "matches": []tournamentMatchView{}})
+		}
+		round := rounds[m.Round-1]
+		round["matches"] = append(round["matches"].([]tournamentMatchView),
+			tournamentMatchView{TournamentMatch: m, PlayerA: names[m.PlayerAID], PlayerB: names[m.PlayerBID]})
+	}
+
+	standings := tournamentStandings(t, players)
+	if standings == nil {
+		standings = []models.TournamentPlayer{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tournament": t,
+		"standings":  standings,
+		"rounds":     rounds,
+	})
+}
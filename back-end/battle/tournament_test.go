@@ -0,0 +1,207 @@
+package battle
+
+import (
+	"clash_and_card/models"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// seededPlayers ผู้เล่น n คน id "p1".."pn" ตาม seed
+func seededPlayers(n int) []models.TournamentPlayer {
+	players := make([]models.TournamentPlayer, n)
+	for i := range players {
+		players[i] = models.TournamentPlayer{UserID: "p" + strconv.Itoa(i+1), Seed: i + 1}
+	}
+	return players
+}
+
+// pairingNames แปลงคู่เป็น "A-B" (bye คือ "A-") เรียงตามโต๊ะ
+func pairingNames(matches []models.TournamentMatch) []string {
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.PlayerAID + "-" + m.PlayerBID
+	}
+	return names
+}
+
+func TestBracketOrder(t *testing.T) {
+	tests := []struct {
+		size int
+		want []int
+	}{
+		{1, []int{1}},
+		{2, []int{1, 2}},
+		{4, []int{1, 4, 2, 3}},
+		{8, []int{1, 8, 4, 5, 2, 7, 3, 6}},
+		{16, []int{1, 16, 8, 9, 4, 13, 5, 12, 2, 15, 7, 10, 3, 14, 6, 11}},
+	}
+	for _, tt := range tests {
+		if got := bracketOrder(tt.size); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("bracketOrder(%d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestSingleEliminationBracket(t *testing.T) {
+	// โต๊ะ 2 ของรอบแรก slot B ชนะ ที่เหลือ slot A ชนะ
+	tests := []struct {
+		players int
+		rounds  [][]string
+		winner  string
+	}{
+		{3, [][]string{{"p1-", "p2-p3"}, {"p1-p3"}}, "p1"},
+		{5, [][]string{{"p1-", "p4-p5", "p2-", "p3-"}, {"p1-p5", "p2-p3"}, {"p1-p2"}}, "p1"},
+		{8, [][]string{{"p1-p8", "p4-p5", "p2-p7", "p3-p6"}, {"p1-p5", "p2-p3"}, {"p1-p2"}}, "p1"},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.players)+" players", func(t *testing.T) {
+			tour := &models.Tournament{Format: TournamentSingleElimination, Status: tournamentRunning, Rounds: eliminationRounds(tt.players)}
+			players := seededPlayers(tt.players)
+
+			var matches []models.TournamentMatch
+			created := nextTournamentRound(tour, players, matches)
+			for round, want := range tt.rounds {
+				if got := pairingNames(created); !reflect.DeepEqual(got, want) {
+					t.Fatalf("round %d = %v, want %v", round+1, got, want)
+				}
+				for i := range created {
+					m := &created[i]
+					if m.Result == tournamentBye {
+						continue
+					}
+					winner := "A"
+					if m.Round == 1 && m.Table == 2 {
+						winner = "B"
+					}
+					applyTournamentResult(tour, players, m, winner)
+				}
+				matches = append(matches, created...)
+				created = nextTournamentRound(tour, players, matches)
+			}
+
+			if len(created) != 0 || tour.Status != tournamentFinished {
+				t.Fatalf("tournament not finished after %d rounds: status %q, next %v", len(tt.rounds), tour.Status, pairingNames(created))
+			}
+			if tour.WinnerID != tt.winner {
+				t.Fatalf("winner = %q, want %q", tour.WinnerID, tt.winner)
+			}
+			for _, p := range players {
+				if !p.Eliminated != (p.UserID == tt.winner) {
+					t.Errorf("%s eliminated = %v", p.UserID, p.Eliminated)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyTournamentResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		result     string
+		wantWinner string
+		wantA      models.TournamentPlayer
+		wantB      models.TournamentPlayer
+	}{
+		{"elimination A wins", TournamentSingleElimination, "A", "p1",
+			models.TournamentPlayer{Wins: 1, Points: tournamentWinPoints}, models.TournamentPlayer{Losses: 1, Eliminated: true}},
+		{"elimination B wins", TournamentSingleElimination, "B", "p2",
+			models.TournamentPlayer{Losses: 1, Eliminated: true}, models.TournamentPlayer{Wins: 1, Points: tournamentWinPoints}},
+		{"elimination draw advances A", TournamentSingleElimination, tournamentDraw, "p1",
+			models.TournamentPlayer{Draws: 1, Points: tournamentDrawPoints}, models.TournamentPlayer{Draws: 1, Points: tournamentDrawPoints, Eliminated: true}},
+		{"swiss loser stays in", TournamentSwiss, "B", "p2",
+			models.TournamentPlayer{Losses: 1}, models.TournamentPlayer{Wins: 1, Points: tournamentWinPoints}},
+		{"swiss draw", TournamentSwiss, tournamentDraw, "",
+			models.TournamentPlayer{Draws: 1, Points: tournamentDrawPoints}, models.TournamentPlayer{Draws: 1, Points: tournamentDrawPoints}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			players := []models.TournamentPlayer{{UserID: "p1"}, {UserID: "p2"}}
+			m := &models.TournamentMatch{PlayerAID: "p1", PlayerBID: "p2"}
+			applyTournamentResult(&models.Tournament{Format: tt.format}, players, m, tt.result)
+
+			if m.Result != tt.result || m.WinnerID != tt.wantWinner {
+				t.Fatalf("match result %q winner %q, want %q %q", m.Result, m.WinnerID, tt.result, tt.wantWinner)
+			}
+			tt.wantA.UserID, tt.wantB.UserID = "p1", "p2"
+			if players[0] != tt.wantA || players[1] != tt.wantB {
+				t.Fatalf("players = %+v, want %+v %+v", players, tt.wantA, tt.wantB)
+			}
+		})
+	}
+}
+
+func TestSwissPairings(t *testing.T) {
+	type standing struct {
+		id     string
+		points int
+	}
+	// ชนะ 3 เสมอ 1 จำนวนชนะคิดจากคะแนน (ไม่มีเสมอในเคสเหล่านี้)
+	tests := []struct {
+		name      string
+		standings []standing // เรียง seed 1 ลงไป
+		played    []string   // คู่ที่เคยเจอกัน "A-B" และ bye "A-"
+		want      []string
+	}{
+		{
+			name:      "odd count gives bye to lowest ranked",
+			standings: []standing{{"p1", 0}, {"p2", 0}, {"p3", 0}, {"p4", 0}, {"p5", 0}},
+			want:      []string{"p1-p2", "p3-p4", "p5-"},
+		},
+		{
+			name:      "bye goes to lowest ranked who has not had one",
+			standings: []standing{{"p1", 3}, {"p2", 0}, {"p3", 3}, {"p4", 0}, {"p5", 3}},
+			played:    []string{"p1-p2", "p3-p4", "p5-"},
+			want:      []string{"p1-p3", "p5-p2", "p4-"},
+		},
+		{
+			name:      "bye goes up the standings past every previous bye",
+			standings: []standing{{"p1", 6}, {"p2", 3}, {"p3", 3}, {"p4", 3}, {"p5", 3}},
+			played:    []string{"p1-p2", "p3-p4", "p5-", "p1-p3", "p5-p2", "p4-"},
+			want:      []string{"p1-p4", "p2-p5", "p3-"},
+		},
+		{
+			name:      "skips rematch when an unplayed opponent is available",
+			standings: []standing{{"p1", 6}, {"p2", 3}, {"p3", 3}, {"p4", 0}},
+			played:    []string{"p1-p2", "p3-p4", "p1-p3", "p2-p4"},
+			want:      []string{"p1-p4", "p2-p3"},
+		},
+		{
+			name:      "rematch only when everyone left has been played",
+			standings: []standing{{"p1", 6}, {"p2", 3}, {"p3", 3}, {"p4", 0}},
+			played:    []string{"p1-p2", "p3-p4", "p1-p3", "p2-p4", "p1-p4", "p2-p3"},
+			want:      []string{"p1-p2", "p3-p4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			players := make([]models.TournamentPlayer, len(tt.standings))
+			for i, s := range tt.standings {
+				players[i] = models.TournamentPlayer{UserID: s.id, Seed: i + 1, Points: s.points, Wins: s.points / tournamentWinPoints}
+			}
+			var matches []models.TournamentMatch
+			for _, p := range tt.played {
+				var m models.TournamentMatch
+				m.PlayerAID, m.PlayerBID, _ = strings.Cut(p, "-")
+				if m.PlayerBID == "" {
+					m.Result = tournamentBye
+				}
+				matches = append(matches, m)
+			}
+
+			created := swissPairings(&models.Tournament{Format: TournamentSwiss}, players, matches)
+			if got := pairingNames(created); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("pairings = %v, want %v", got, tt.want)
+			}
+			// bye นับเป็นชนะทันที
+			if bye := created[len(created)-1]; bye.PlayerBID == "" {
+				p := tournamentPlayer(players, bye.PlayerAID)
+				if want := tt.standings[p.Seed-1].points + tournamentWinPoints; p.Points != want {
+					t.Fatalf("bye player %s points = %d, want %d", p.UserID, p.Points, want)
+				}
+			}
+		})
+	}
+}
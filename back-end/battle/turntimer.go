@@ -0,0 +1,118 @@
+package battle
+
+import (
+	"clash_and_card/engine"
+	"clash_and_card/store"
+	"fmt"
+	"time"
+)
+
+// ----------- PvP turn timer -----------
+//
+// แต่ละรอบมีเวลาจำกัด turnTimeout (หรือ RoomRules.TurnTimeout ของห้อง private) ทั้งสองฝั่งได้ turnDeadline ใน initialData และ round_result
+// หมดเวลาแล้วผู้เล่นที่ยังไม่เลือกการ์ดจะถูกเลือกแทนตาม autoPlayPolicy (round_result บอกด้วย autoPlayed)
+// หมดเวลาติดกันครบ maxTimeouts ครั้งแพ้ฟอร์ฟิต เลือกการ์ดเองเมื่อไหร่ตัวนับก็เริ่มใหม่
+
+const (
+	AutoPlayRandom = "random" // สุ่มจากมือด้วย policy rng ของ match
+	AutoPlayFirst  = "first"  // ใบแรกในมือ
+)
+
+var (
+	turnTimeout    = 30 * time.Second
+	maxTimeouts    = 3
+	autoPlayPolicy = AutoPlayRandom
+)
+
+// ConfigureTurnTimer ตั้งเวลาต่อรอบ จำนวนครั้งที่หมดเวลาได้ก่อนแพ้ และวิธีเลือกการ์ดแทน
+// ต้องเรียกก่อน RestoreLiveMatches
+func ConfigureTurnTimer(timeout time.Duration, maxConsecutive int, policy string) {
+	turnTimeout = timeout
+	maxTimeouts = maxConsecutive
+	autoPlayPolicy = policy
+}
+
+// pvpStartTurn เริ่มนับเวลาของรอบปัจจุบันใหม่ ต้องถือ lock ของ state ก่อนเรียก
+func pvpStartTurn(st store.Store, state *PVPState) {
+	if state.turnTimer != nil {
+		state.turnTimer.Stop()
+	}
+	timeout := turnTimeout
+	if state.Rules.TurnTimeout > 0 {
+		timeout = time.Duration(state.Rules.TurnTimeout) * time.Second
+	}
+	state.autoPlayed = nil
+	state.turnDeadline = time.Now().Add(timeout)
+
+	roomID, round := state.RoomID, state.Round
+	state.turnTimer = time.AfterFunc(timeout, func() {
+		pvpTurnTimeout(st, roomID, round)
+	})
+}
+
+// pvpStopTurn หยุดนับเวลาเมื่อ match จบ ต้องถือ lock ของ state ก่อนเรียก
+func pvpStopTurn(state *PVPState) {
+	if state.turnTimer != nil {
+		state.turnTimer.Stop()
+		state.turnTimer = nil
+	}
+	state.turnDeadline = time.Time{}
+}
+
+// pvpTurnTimeout ทำงานเมื่อรอบ round หมดเวลา ถ้ารอบนั้นจบไปแล้วก็ไม่ทำอะไร
+func pvpTurnTimeout(st store.Store, roomID string, round int) {
+	pvpStatesMu.Lock()
+	state, ok := pvpStates[roomID]
+	pvpStatesMu.Unlock()
+	if !ok {
+		return
+	}
+
+	state.Lock()
+	if state.Status != engine.StatusOnGoing || state.Round != round {
+		state.Unlock()
+		return
+	}
+	if state.timeouts == nil {
+		state.timeouts = make(map[string]int)
+	}
+	var idle, losers []string
+	for _, slot := range []string{"A", "B"} {
+		if state.Selected[slot] != nil {
+			continue
+		}
+		idle = append(idle, slot)
+		state.timeouts[slot]++
+		if state.timeouts[slot] >= maxTimeouts {
+			losers = append(losers, slot)
+		}
+	}
+	state.Unlock()
+
+	switch len(losers) {
+	case 1:
+		pvpForfeit(st, roomID, losers[0], forfeitTimeout)
+		return
+	case 2:
+		pvpForfeit(st, roomID, "", forfeitTimeout)
+		return
+	}
+
+	for _, slot := range idle {
+		if err := pvpSelectCard(st, roomID, slot, "", true); err != nil {
+			fmt.Println("[ERROR] auto play:", roomID, slot, err)
+		}
+	}
+}
+
+// autoPlayCardID เลือกการ์ดแทนผู้เล่น slot ตาม autoPlayPolicy ต้องถือ lock ของ state ก่อนเรียก
+func autoPlayCardID(state *PVPState, slot string) string {
+	if autoPlayPolicy == AutoPlayFirst {
+		player, err := state.Player(slot)
+		if err != nil || len(player.Hand) == 0 {
+			return ""
+		}
+		return player.Hand[0].ID
+	}
+	return state.RandomCardID(slot)
+}
@@ -0,0 +1,405 @@
+// Package config โหลดค่าตั้งค่าของเซิร์ฟเวอร์จากไฟล์ JSON แล้วทับด้วย environment variable
+// ลำดับความสำคัญ: ค่าเริ่มต้น < ไฟล์ < env
+package config
+
+import (
+	"clash_and_card/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	// DefaultJWTSecret ค่าเดิมที่เคย hard-code ไว้ ห้ามใช้ใน production
+	DefaultJWTSecret = "SECRET_KEY"
+)
+
+// Duration รับค่าใน JSON เป็นข้อความแบบ time.ParseDuration เช่น "24h", "15m"
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+type Database struct {
+	// Driver: "mysql", "sqlite" หรือ "memory"
+	Driver string `json:"driver"`
+	// DSN ของ MySQL หรือ path ของไฟล์ SQLite (memory ไม่ใช้)
+	DSN string `json:"dsn"`
+}
+
+type ShopPrices struct {
+	Card int `json:"card"`
+}
+
+type Matches struct {
+	// CampaignIdleTimeout match campaign ที่ไม่มีการเล่นนานเท่านี้จะถูกตัดสินแพ้ฟอร์ฟิต
+	CampaignIdleTimeout Duration `json:"campaignIdleTimeout"`
+	// UnusedRoomTimeout ห้อง PvP ที่สร้างแล้วแต่ผู้เล่นไม่เข้ามาเริ่มเกมภายในเวลานี้จะถูกลบ
+	UnusedRoomTimeout Duration `json:"unusedRoomTimeout"`
+	// PrivateRoomTimeout ห้อง private ที่สร้างด้วย invite code อยู่ได้นานเท่านี้ถ้ายังไม่เริ่มเกม
+	PrivateRoomTimeout Duration `json:"privateRoomTimeout"`
+	// ReconnectGrace ผู้เล่น PvP ที่หลุดกลางเกมกลับเข้ามาเล่นต่อได้ภายในเวลานี้ เกินแล้วแพ้ฟอร์ฟิต
+	ReconnectGrace Duration `json:"reconnectGrace"`
+	// TurnTimeout เวลาที่ผู้เล่น PvP มีในแต่ละรอบ หมดเวลาแล้วเซิร์ฟเวอร์เลือกการ์ดแทนตาม AutoPlayPolicy
+	TurnTimeout Duration `json:"turnTimeout"`
+	// AutoPlayPolicy วิธีเลือกการ์ดแทนผู้เล่นที่หมดเวลา: "random" หรือ "first" (ใบแรกในมือ)
+	AutoPlayPolicy string `json:"autoPlayPolicy"`
+	// MaxTimeouts หมดเวลาติดกันครบจำนวนนี้แพ้ฟอร์ฟิต
+	MaxTimeouts int `json:"maxTimeouts"`
+	// RematchWindow หลัง series PvP จบ ผู้เล่นตกลง rematch กันได้ภายในเวลานี้ เกินแล้วห้องปิด
+	RematchWindow Duration `json:"rematchWindow"`
+	// TournamentNoShowTimeout ห้องของคู่ในการแข่งขันที่ยังไม่เริ่มเกมภายในเวลานี้ ฝั่งที่รออยู่ชนะ
+	TournamentNoShowTimeout Duration `json:"tournamentNoShowTimeout"`
+	// StateStore ที่เก็บ snapshot ของ match ที่ยังเล่นอยู่: "memory" (หายเมื่อรีสตาร์ท) หรือ "database"
+	StateStore string `json:"stateStore"`
+}
+
+// Matchmaking ช่วงผลต่าง rating ที่คิว PvP ยอมจับคู่ เริ่มจาก InitialBand แล้วกว้างขึ้น BandGrowth ทุก BandGrowthInterval ที่รอ
+type Matchmaking struct {
+	InitialBand        int      `json:"initialBand"`
+	BandGrowth         int      `json:"bandGrowth"`
+	BandGrowthInterval Duration `json:"bandGrowthInterval"`
+}
+
+// Rating ค่าคงที่ของ Elo rating PvP
+type Rating struct {
+	// KFactor rating ที่เปลี่ยนได้มากที่สุดต่อหนึ่งเกม
+	KFactor int `json:"kFactor"`
+}
+
+// SeasonReward รางวัลจบฤดูกาลของอันดับไม่เกิน MaxPlacement (0 = ทุกคนที่ได้เล่น) เรียงจากอันดับดีที่สุด
+type SeasonReward struct {
+	MaxPlacement int               `json:"maxPlacement"`
+	Gold         int               `json:"gold"`
+	Cards        []models.DeckCard `json:"cards"`
+}
+
+// Seasons ฤดูกาลแรงก์ PvP
+type Seasons struct {
+	Length     Duration `json:"length"`
+	WinPoints  int      `json:"winPoints"`
+	LossPoints int      `json:"lossPoints"` // คะแนนที่เสียเมื่อแพ้
+	DrawPoints int      `json:"drawPoints"`
+	// SoftResetKeep สัดส่วนของ rating ส่วนที่ห่างจากค่าเริ่มต้นที่เก็บไว้ข้ามฤดูกาล (0 = รีเซ็ตทั้งหมด, 1 = ไม่รีเซ็ต)
+	SoftResetKeep float64        `json:"softResetKeep"`
+	Rewards       []SeasonReward `json:"rewards"`
+}
+
+// Leaderboard ตารางอันดับถูกสร้างใหม่ทุก RefreshInterval เก็บไว้ Size อันดับแรกของแต่ละ board
+type Leaderboard struct {
+	RefreshInterval Duration `json:"refreshInterval"`
+	Size            int      `json:"size"`
+}
+
+type Config struct {
+	Env             string          `json:"env"`
+	ListenAddr      string          `json:"listenAddr"`
+	Database        Database        `json:"database"`
+	JWTSecret       string          `json:"jwtSecret"`
+	TokenTTL        Duration        `json:"tokenTTL"` // อายุของ access token ควรสั้น หมดแล้วใช้ refresh token ขอใหม่
+	RefreshTokenTTL Duration        `json:"refreshTokenTTL"`
+	CORSOrigins     []string        `json:"corsOrigins"`
+	StartingStats   models.UnitStat `json:"startingStats"`
+	ShopPrices      ShopPrices      `json:"shopPrices"`
+	Matches         Matches         `json:"matches"`
+	Matchmaking     Matchmaking     `json:"matchmaking"`
+	Rating          Rating          `json:"rating"`
+	Seasons         Seasons         `json:"seasons"`
+	Leaderboard     Leaderboard     `json:"leaderboard"`
+}
+
+// Default ค่าเริ่มต้นที่ตรงกับพฤติกรรมเดิมของเซิร์ฟเวอร์
+func Default() Config {
+	return Config{
+		Env:        EnvDevelopment,
+		ListenAddr: ":8080",
+		Database: Database{
+			Driver: "mysql",
+			DSN:    "root:1234@tcp(127.0.0.1:3306)/clash_and_card",
+		},
+		JWTSecret:       DefaultJWTSecret,
+		TokenTTL:        Duration{15 * time.Minute},
+		RefreshTokenTTL: Duration{30 * 24 * time.Hour},
+		CORSOrigins:     []string{"*"},
+		StartingStats:   models.UnitStat{Atk: 20, Def: 10, Spd: 10, HP: 50},
+		ShopPrices:      ShopPrices{Card: 500},
+		Matches: Matches{
+			CampaignIdleTimeout:     Duration{30 * time.Minute},
+			UnusedRoomTimeout:       Duration{2 * time.Minute},
+			PrivateRoomTimeout:      Duration{10 * time.Minute},
+			ReconnectGrace:          Duration{time.Minute},
+			TurnTimeout:             Duration{30 * time.Second},
+			AutoPlayPolicy:          "random",
+			MaxTimeouts:             3,
+			RematchWindow:           Duration{30 * time.Second},
+			TournamentNoShowTimeout: Duration{10 * time.Minute},
+			StateStore:              "memory",
+		},
+		Matchmaking: Matchmaking{
+			InitialBand:        100,
+			BandGrowth:         50,
+			BandGrowthInterval: Duration{10 * time.Second},
+		},
+		Rating:      Rating{KFactor: 32},
+		Leaderboard: Leaderboard{RefreshInterval: Duration{time.Minute}, Size: 1000},
+		Seasons: Seasons{
+			Length:        Duration{28 * 24 * time.Hour},
+			WinPoints:     25,
+			LossPoints:    15,
+			DrawPoints:    5,
+			SoftResetKeep: 0.5,
+			Rewards: []SeasonReward{
+				{MaxPlacement: 1, Gold: 2000, Cards: []models.DeckCard{{CardType: "rock", Quantity: 2}, {CardType: "paper", Quantity: 2}, {CardType: "scissors", Quantity: 2}}},
+				{MaxPlacement: 10, Gold: 1000, Cards: []models.DeckCard{{CardType: "rock", Quantity: 1}, {CardType: "paper", Quantity: 1}, {CardType: "scissors", Quantity: 1}}},
+				{MaxPlacement: 100, Gold: 300},
+				{MaxPlacement: 0, Gold: 50},
+			},
+		},
+	}
+}
+
+// Load อ่านค่าจากไฟล์ (ถ้า path ไม่ว่าง) แล้วทับด้วย env และตรวจสอบความถูกต้อง
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("read config: %v", err)
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("parse config %s: %v", path, err)
+		}
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func applyEnv(cfg *Config) error {
+	setString := func(key string, dst *string) {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = v
+		}
+	}
+	setInt := func(key string, dst *int) error {
+		if v, ok := os.LookupEnv(key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			*dst = n
+		}
+		return nil
+	}
+	setDuration := func(key string, dst *Duration) error {
+		if v, ok := os.LookupEnv(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			dst.Duration = d
+		}
+		return nil
+	}
+
+	setString("CLASH_ENV", &cfg.Env)
+	setString("CLASH_LISTEN_ADDR", &cfg.ListenAddr)
+	setString("CLASH_DB_DRIVER", &cfg.Database.Driver)
+	setString("CLASH_DB_DSN", &cfg.Database.DSN)
+	setString("CLASH_JWT_SECRET", &cfg.JWTSecret)
+	setString("CLASH_MATCH_STATE_STORE", &cfg.Matches.StateStore)
+	setString("CLASH_PVP_AUTO_PLAY_POLICY", &cfg.Matches.AutoPlayPolicy)
+	if v, ok := os.LookupEnv("CLASH_CORS_ORIGINS"); ok {
+		cfg.CORSOrigins = nil
+		for _, origin := range strings.Split(v, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				cfg.CORSOrigins = append(cfg.CORSOrigins, origin)
+			}
+		}
+	}
+
+	return errors.Join(
+		setDuration("CLASH_TOKEN_TTL", &cfg.TokenTTL),
+		setDuration("CLASH_REFRESH_TOKEN_TTL", &cfg.RefreshTokenTTL),
+		setDuration("CLASH_CAMPAIGN_IDLE_TIMEOUT", &cfg.Matches.CampaignIdleTimeout),
+		setDuration("CLASH_UNUSED_ROOM_TIMEOUT", &cfg.Matches.UnusedRoomTimeout),
+		setDuration("CLASH_PRIVATE_ROOM_TIMEOUT", &cfg.Matches.PrivateRoomTimeout),
+		setDuration("CLASH_PVP_RECONNECT_GRACE", &cfg.Matches.ReconnectGrace),
+		setDuration("CLASH_PVP_TURN_TIMEOUT", &cfg.Matches.TurnTimeout),
+		setInt("CLASH_PVP_MAX_TIMEOUTS", &cfg.Matches.MaxTimeouts),
+		setDuration("CLASH_PVP_REMATCH_WINDOW", &cfg.Matches.RematchWindow),
+		setDuration("CLASH_TOURNAMENT_NO_SHOW_TIMEOUT", &cfg.Matches.TournamentNoShowTimeout),
+		setDuration("CLASH_MATCHMAKING_BAND_INTERVAL", &cfg.Matchmaking.BandGrowthInterval),
+		setInt("CLASH_MATCHMAKING_INITIAL_BAND", &cfg.Matchmaking.InitialBand),
+		setInt("CLASH_MATCHMAKING_BAND_GROWTH", &cfg.Matchmaking.BandGrowth),
+		setInt("CLASH_RATING_K_FACTOR", &cfg.Rating.KFactor),
+		setDuration("CLASH_SEASON_LENGTH", &cfg.Seasons.Length),
+		setDuration("CLASH_LEADERBOARD_REFRESH", &cfg.Leaderboard.RefreshInterval),
+		setInt("CLASH_START_ATK", &cfg.StartingStats.Atk),
+		setInt("CLASH_START_DEF", &cfg.StartingStats.Def),
+		setInt("CLASH_START_SPD", &cfg.StartingStats.Spd),
+		setInt("CLASH_START_HP", &cfg.StartingStats.HP),
+		setInt("CLASH_SHOP_CARD_PRICE", &cfg.ShopPrices.Card),
+	)
+}
+
+// Validate ตรวจค่าทั้งหมด และไม่ยอมให้ใช้ secret เริ่มต้นใน production
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		errs = append(errs, fmt.Errorf("env must be %q or %q, got %q", EnvDevelopment, EnvProduction, c.Env))
+	}
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("listenAddr is required"))
+	}
+
+	switch c.Database.Driver {
+	case "mysql", "sqlite":
+		if c.Database.DSN == "" {
+			errs = append(errs, fmt.Errorf("database.dsn is required for driver %q", c.Database.Driver))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("database.driver must be mysql, sqlite or memory, got %q", c.Database.Driver))
+	}
+
+	if c.JWTSecret == "" {
+		errs = append(errs, errors.New("jwtSecret is required"))
+	}
+	if c.Env == EnvProduction && c.JWTSecret == DefaultJWTSecret {
+		errs = append(errs, errors.New("jwtSecret must be changed from the default in production"))
+	}
+	if c.TokenTTL.Duration <= 0 {
+		errs = append(errs, errors.New("tokenTTL must be positive"))
+	}
+	if c.RefreshTokenTTL.Duration <= c.TokenTTL.Duration {
+		errs = append(errs, errors.New("refreshTokenTTL must be longer than tokenTTL"))
+	}
+	if len(c.CORSOrigins) == 0 {
+		errs = append(errs, errors.New("corsOrigins must not be empty"))
+	}
+
+	s := c.StartingStats
+	if s.Atk <= 0 || s.Def < 0 || s.Spd < 0 || s.HP <= 0 {
+		errs = append(errs, errors.New("startingStats must have positive atk and hp and non-negative def and spd"))
+	}
+	if c.ShopPrices.Card < 0 {
+		errs = append(errs, errors.New("shopPrices.card must not be negative"))
+	}
+	if c.Matches.CampaignIdleTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("matches.campaignIdleTimeout must be positive"))
+	}
+	if c.Matches.UnusedRoomTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("matches.unusedRoomTimeout must be positive"))
+	}
+	if c.Matches.PrivateRoomTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("matches.privateRoomTimeout must be positive"))
+	}
+	if c.Matches.ReconnectGrace.Duration <= 0 {
+		errs = append(errs, errors.New("matches.reconnectGrace must be positive"))
+	}
+	if c.Matches.TurnTimeout.Duration < time.Second {
+		errs = append(errs, errors.New("matches.turnTimeout must be at least 1s"))
+	}
+	if c.Matches.AutoPlayPolicy != "random" && c.Matches.AutoPlayPolicy != "first" {
+		errs = append(errs, fmt.Errorf("matches.autoPlayPolicy must be random or first, got %q", c.Matches.AutoPlayPolicy))
+	}
+	if c.Matches.MaxTimeouts <= 0 {
+		errs = append(errs, errors.New("matches.maxTimeouts must be positive"))
+	}
+	if c.Matches.RematchWindow.Duration <= 0 {
+		errs = append(errs, errors.New("matches.rematchWindow must be positive"))
+	}
+	if c.Matches.TournamentNoShowTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("matches.tournamentNoShowTimeout must be positive"))
+	}
+	if c.Matches.StateStore != "memory" && c.Matches.StateStore != "database" {
+		errs = append(errs, fmt.Errorf("matches.stateStore must be memory or database, got %q", c.Matches.StateStore))
+	}
+	if c.Matchmaking.InitialBand < 0 || c.Matchmaking.BandGrowth < 0 {
+		errs = append(errs, errors.New("matchmaking.initialBand and matchmaking.bandGrowth must not be negative"))
+	}
+	if c.Matchmaking.BandGrowth > 0 && c.Matchmaking.BandGrowthInterval.Duration <= 0 {
+		errs = append(errs, errors.New("matchmaking.bandGrowthInterval must be positive"))
+	}
+	if c.Rating.KFactor <= 0 {
+		errs = append(errs, errors.New("rating.kFactor must be positive"))
+	}
+	if c.Leaderboard.RefreshInterval.Duration < time.Second {
+		errs = append(errs, errors.New("leaderboard.refreshInterval must be at least 1s"))
+	}
+	if c.Leaderboard.Size <= 0 {
+		errs = append(errs, errors.New("leaderboard.size must be positive"))
+	}
+	errs = append(errs, c.Seasons.validate()...)
+
+	return errors.Join(errs...)
+}
+
+func (s Seasons) validate() []error {
+	var errs []error
+	if s.Length.Duration < time.Hour {
+		errs = append(errs, errors.New("seasons.length must be at least 1h"))
+	}
+	if s.WinPoints < 0 || s.LossPoints < 0 || s.DrawPoints < 0 {
+		errs = append(errs, errors.New("seasons points must not be negative"))
+	}
+	if s.SoftResetKeep < 0 || s.SoftResetKeep > 1 {
+		errs = append(errs, errors.New("seasons.softResetKeep must be between 0 and 1"))
+	}
+
+	prev := 0
+	for i, reward := range s.Rewards {
+		switch {
+		case reward.MaxPlacement < 0:
+			errs = append(errs, fmt.Errorf("seasons.rewards[%d].maxPlacement must not be negative", i))
+		case prev == 0 && i > 0:
+			errs = append(errs, fmt.Errorf("seasons.rewards[%d] is unreachable after a reward for every placement", i))
+		case reward.MaxPlacement != 0 && reward.MaxPlacement <= prev:
+			errs = append(errs, fmt.Errorf("seasons.rewards[%d].maxPlacement must be greater than the previous tier", i))
+		}
+		prev = reward.MaxPlacement
+		if reward.Gold < 0 {
+			errs = append(errs, fmt.Errorf("seasons.rewards[%d].gold must not be negative", i))
+		}
+		for _, card := range reward.Cards {
+			if card.CardType != "rock" && card.CardType != "paper" && card.CardType != "scissors" {
+				errs = append(errs, fmt.Errorf("seasons.rewards[%d] has unknown card type %q", i, card.CardType))
+			}
+			if card.Quantity <= 0 {
+				errs = append(errs, fmt.Errorf("seasons.rewards[%d] card quantity must be positive", i))
+			}
+		}
+	}
+	return errs
+}
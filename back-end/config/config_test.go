@@ -0,0 +1,132 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig เขียนไฟล์ config ชั่วคราวและคืน path
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestEnvOverridesFile(t *testing.T) {
+	path := writeConfig(t, `{
+		"listenAddr": ":9000",
+		"database": {"driver": "sqlite", "dsn": "file.db"},
+		"tokenTTL": "10m",
+		"matches": {"maxTimeouts": 5},
+		"corsOrigins": ["https://file.example"]
+	}`)
+	t.Setenv("CLASH_LISTEN_ADDR", ":9100")
+	t.Setenv("CLASH_DB_DRIVER", "memory")
+	t.Setenv("CLASH_TOKEN_TTL", "5m")
+	t.Setenv("CLASH_PVP_MAX_TIMEOUTS", "7")
+	t.Setenv("CLASH_CORS_ORIGINS", " https://a.example , ,https://b.example")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.ListenAddr != ":9100" || cfg.Database.Driver != "memory" {
+		t.Errorf("listenAddr %q driver %q, want env values", cfg.ListenAddr, cfg.Database.Driver)
+	}
+	// env ทับเฉพาะค่าที่ตั้งไว้ ค่าอื่นจากไฟล์ยังอยู่
+	if cfg.Database.DSN != "file.db" {
+		t.Errorf("dsn = %q, want value from file", cfg.Database.DSN)
+	}
+	if cfg.TokenTTL.Duration != 5*time.Minute {
+		t.Errorf("tokenTTL = %v, want 5m", cfg.TokenTTL)
+	}
+	if cfg.Matches.MaxTimeouts != 7 {
+		t.Errorf("maxTimeouts = %d, want 7", cfg.Matches.MaxTimeouts)
+	}
+	if got := strings.Join(cfg.CORSOrigins, " "); got != "https://a.example https://b.example" {
+		t.Errorf("corsOrigins = %v", cfg.CORSOrigins)
+	}
+	// ค่าที่ไม่ได้ตั้งทั้งในไฟล์และ env ใช้ค่าเริ่มต้น
+	if cfg.Matches.TurnTimeout != Default().Matches.TurnTimeout {
+		t.Errorf("turnTimeout = %v, want default", cfg.Matches.TurnTimeout)
+	}
+}
+
+func TestInvalidEnv(t *testing.T) {
+	tests := []struct {
+		key, value string
+	}{
+		{"CLASH_TOKEN_TTL", "15"},
+		{"CLASH_PVP_TURN_TIMEOUT", "soon"},
+		{"CLASH_PVP_MAX_TIMEOUTS", "three"},
+		{"CLASH_SHOP_CARD_PRICE", "1.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			_, err := Load("")
+			if err == nil || !strings.Contains(err.Error(), tt.key) {
+				t.Fatalf("Load with %s=%q: err = %v, want error naming the variable", tt.key, tt.value, err)
+			}
+		})
+	}
+}
+
+func TestProductionRejectsDefaultSecret(t *testing.T) {
+	t.Setenv("CLASH_ENV", EnvProduction)
+
+	_, err := Load("")
+	if err == nil || !strings.Contains(err.Error(), "jwtSecret") {
+		t.Fatalf("production with default secret: err = %v", err)
+	}
+
+	t.Setenv("CLASH_JWT_SECRET", "a-real-secret")
+	if _, err := Load(""); err != nil {
+		t.Fatalf("production with own secret: %v", err)
+	}
+
+	// development ยังใช้ค่าเริ่มต้นได้
+	t.Setenv("CLASH_ENV", EnvDevelopment)
+	t.Setenv("CLASH_JWT_SECRET", DefaultJWTSecret)
+	if _, err := Load(""); err != nil {
+		t.Fatalf("development with default secret: %v", err)
+	}
+}
+
+func TestSeasonRewardTierOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		tiers   []int // MaxPlacement ของแต่ละ tier ตามลำดับ
+		wantErr string
+	}{
+		{"increasing then everyone", []int{1, 10, 100, 0}, ""},
+		{"only everyone", []int{0}, ""},
+		{"no rewards", nil, ""},
+		{"not increasing", []int{10, 5}, "rewards[1].maxPlacement must be greater"},
+		{"same placement twice", []int{10, 10}, "rewards[1].maxPlacement must be greater"},
+		{"tier after everyone", []int{1, 0, 100}, "rewards[2] is unreachable"},
+		{"negative", []int{-1}, "rewards[0].maxPlacement must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Seasons.Rewards = nil
+			for _, p := range tt.tiers {
+				cfg.Seasons.Rewards = append(cfg.Seasons.Rewards, SeasonReward{MaxPlacement: p, Gold: 10})
+			}
+			err := cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Validate: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Validate: err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
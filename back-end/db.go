@@ -1,15 +1,25 @@
 package main
 
 import (
+	"clash_and_card/config"
+	"clash_and_card/migrations"
+	"clash_and_card/store"
 	"database/sql"
 	"log"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
-func ConnectDB() *sql.DB {
-	dsn := "root:1234@tcp(127.0.0.1:3306)/clash_and_card"
-	db, err := sql.Open("mysql", dsn)
+// ConnectDB เปิด MySQL พร้อม multiStatements เพื่อให้ package migrations ส่งไฟล์ SQL ทั้งไฟล์ใน Exec เดียวได้
+// query อื่นทั้งหมดใช้ placeholder อยู่แล้ว จึงไม่เปิดช่องให้ฉีดคำสั่งเพิ่ม
+func ConnectDB(dsn string) *sql.DB {
+	mysqlCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		log.Fatal("Invalid MySQL DSN:", err)
+	}
+	mysqlCfg.MultiStatements = true
+
+	db, err := sql.Open("mysql", mysqlCfg.FormatDSN())
 	if err != nil {
 		log.Fatal("Connect error:", err)
 	}
@@ -18,3 +28,36 @@ func ConnectDB() *sql.DB {
 	}
 	return db
 }
+
+// openSQLStore เปิด MySQL หรือ SQLite ตาม database.driver
+// สำหรับ sqlite ค่า dsn คือ path ของไฟล์
+func openSQLStore(cfg config.Database) *store.SQLStore {
+	if cfg.Driver == "sqlite" {
+		st, err := store.OpenSQLite(cfg.DSN)
+		if err != nil {
+			log.Fatal("SQLite error:", err)
+		}
+		log.Println("Using SQLite store:", cfg.DSN)
+		return st
+	}
+	return store.NewMySQL(ConnectDB(cfg.DSN))
+}
+
+// OpenStore เลือก storage ตาม database.driver และอัปเกรด schema ให้เป็นเวอร์ชันล่าสุด
+// "memory" เก็บในหน่วยความจำ (ไม่ต้องมีฐานข้อมูล ข้อมูลหายเมื่อปิดเซิร์ฟเวอร์)
+func OpenStore(cfg config.Database) store.Store {
+	if cfg.Driver == "memory" {
+		log.Println("Using in-memory store")
+		return store.NewMemory()
+	}
+
+	st := openSQLStore(cfg)
+	ran, err := migrations.Up(st.DB(), st.Dialect())
+	if err != nil {
+		log.Fatal("Migration error:", err)
+	}
+	for _, m := range ran {
+		log.Printf("Applied migration %04d_%s\n", m.Version, m.Name)
+	}
+	return st
+}
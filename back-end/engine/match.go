@@ -0,0 +1,332 @@
+// Package engine เก็บกฎของเกม (การ์ด, ดาเมจ, ผลแพ้ชนะ) แยกจาก HTTP, websocket และฐานข้อมูล
+// ทั้ง campaign และ PvP เรียกใช้ผ่าน Match.Apply
+package engine
+
+import (
+	"errors"
+	"math/rand/v2"
+)
+
+const (
+	StatusOnGoing = "onGoing"
+	StatusEnd     = "end"
+)
+
+// HandSize จำนวนการ์ดในมือสูงสุดของผู้เล่นแต่ละฝั่ง
+const HandSize = 3
+
+var (
+	ErrInvalidSlot   = errors.New("invalid slot")
+	ErrCardNotInHand = errors.New("card not found in hand")
+	ErrNoTrueSight   = errors.New("no TrueSight left")
+	ErrNoSelection   = errors.New("no card selected")
+	ErrMatchEnded    = errors.New("match already ended")
+	ErrUnknownAction = errors.New("unknown action")
+)
+
+type Card struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+type Stat struct {
+	ATK int
+	DEF int
+	SPD int
+	HP  int
+}
+
+type PlayerData struct {
+	Name      string
+	Level     int
+	CurrentHP int
+	Deck      []Card
+	Hand      []Card
+	Stat      Stat
+	Class     string
+	TrueSight int
+}
+
+// Match สถานะของเกมหนึ่งเกมระหว่างผู้เล่น slot "A" และ "B"
+// การสุ่มทุกอย่างในเกม (สับไพ่, หลบหลีก, bot เลือกการ์ด) มาจาก Seed ของ match เอง
+// จึงเล่นซ้ำได้เหมือนเดิมทุกครั้งจาก Seed + History
+type Match struct {
+	PlayerA  PlayerData
+	PlayerB  PlayerData
+	Selected map[string]*Card // key: slot, value: การ์ดที่เลือกในรอบนี้
+	Round    int
+	Status   string
+	Seed     uint64
+	History  []Action // action ที่สำเร็จทั้งหมดตามลำดับ
+	// HandSize จำนวนการ์ดในมือของ match นี้ 0 คือใช้ค่า HandSize ของ package
+	HandSize int
+
+	rng    *rand.Rand // ใช้กับกฎของเกม (สับไพ่, หลบหลีก)
+	policy *rand.Rand // ใช้เลือกการ์ดแทนผู้เล่น แยกจาก rng เพื่อไม่ให้กระทบผลของกฎ
+
+	// source ของ rng และ policy เก็บไว้เพื่อบันทึกสถานะการสุ่มใน Snapshot
+	rngSrc    *rand.PCG
+	policySrc *rand.PCG
+}
+
+type ActionType string
+
+const (
+	ActionPlayCard     ActionType = "play_card"
+	ActionUseTrueSight ActionType = "use_true_sight"
+	// ActionWithdrawCard ยกเลิกการ์ดที่เลือกไว้ในรอบนี้ (เช่นผู้เล่นหลุด) การ์ดยังอยู่ในมือ
+	ActionWithdrawCard ActionType = "withdraw_card"
+)
+
+type Action struct {
+	Type   ActionType `json:"type"`
+	Slot   string     `json:"slot"`
+	CardID string     `json:"cardID,omitempty"`
+}
+
+type EventType string
+
+const (
+	EventCardSelected  EventType = "card_selected"
+	EventRoundResolved EventType = "round_resolved"
+	EventGameEnded     EventType = "game_ended"
+	EventTrueSightUsed EventType = "true_sight_used"
+	EventCardWithdrawn EventType = "card_withdrawn"
+)
+
+// RoundResult ผลของหนึ่งรอบหลังจากทั้งสองฝั่งลงการ์ด
+// DamageToX = -1 หมายถึงการโจมตีพลาด (miss)
+type RoundResult struct {
+	Number        int    `json:"number"`
+	CardA         Card   `json:"cardA"`
+	CardB         Card   `json:"cardB"`
+	Winner        string `json:"winner"` // "A", "B" หรือ "draw"
+	DamageToA     int    `json:"damageToA"`
+	DamageToB     int    `json:"damageToB"`
+	SpecialEventA string `json:"specialEventA"`
+	SpecialEventB string `json:"specialEventB"`
+	HPA           int    `json:"hpA"`
+	HPB           int    `json:"hpB"`
+}
+
+type Result struct {
+	ResultA string `json:"resultA"`
+	DetailA string `json:"detailA"`
+	ResultB string `json:"resultB"`
+	DetailB string `json:"detailB"`
+}
+
+type Event struct {
+	Type          EventType      `json:"type"`
+	Slot          string         `json:"slot,omitempty"`
+	Round         *RoundResult   `json:"round,omitempty"`
+	Result        *Result        `json:"result,omitempty"`
+	OpponentHand  map[string]int `json:"opponentHand,omitempty"`
+	TrueSightLeft int            `json:"trueSightLeft,omitempty"`
+}
+
+// Start ตั้ง seed, สับไพ่ทั้งสองฝั่งและแจกการ์ดเริ่มต้น
+func (m *Match) Start(seed uint64) {
+	m.Seed = seed
+	m.rngSrc = rand.NewPCG(seed, 0)
+	m.policySrc = rand.NewPCG(seed, 1)
+	m.rng = rand.New(m.rngSrc)
+	m.policy = rand.New(m.policySrc)
+	m.History = nil
+	m.Round = 0
+
+	shuffleCards(m.rng, m.PlayerA.Deck)
+	shuffleCards(m.rng, m.PlayerB.Deck)
+
+	m.PlayerA.Hand = drawCards(&m.PlayerA.Deck, m.handSize())
+	m.PlayerB.Hand = drawCards(&m.PlayerB.Deck, m.handSize())
+	m.Selected = make(map[string]*Card)
+	m.Status = StatusOnGoing
+}
+
+func (m *Match) handSize() int {
+	if m.HandSize > 0 {
+		return m.HandSize
+	}
+	return HandSize
+}
+
+// Player คืน PlayerData ของ slot ที่ระบุ
+func (m *Match) Player(slot string) (*PlayerData, error) {
+	switch slot {
+	case "A":
+		return &m.PlayerA, nil
+	case "B":
+		return &m.PlayerB, nil
+	}
+	return nil, ErrInvalidSlot
+}
+
+// Opponent คืน PlayerData ของฝั่งตรงข้ามกับ slot ที่ระบุ
+func (m *Match) Opponent(slot string) (*PlayerData, error) {
+	switch slot {
+	case "A":
+		return &m.PlayerB, nil
+	case "B":
+		return &m.PlayerA, nil
+	}
+	return nil, ErrInvalidSlot
+}
+
+// Apply ใช้ action กับ match และคืน event ที่เกิดขึ้นตามลำดับ
+// ถ้า action ไม่ถูกต้อง state จะไม่เปลี่ยนแปลง
+func (m *Match) Apply(a Action) ([]Event, error) {
+	if m.Status == StatusEnd {
+		return nil, ErrMatchEnded
+	}
+
+	var events []Event
+	var err error
+	switch a.Type {
+	case ActionPlayCard:
+		events, err = m.playCard(a.Slot, a.CardID)
+	case ActionUseTrueSight:
+		events, err = m.useTrueSight(a.Slot)
+	case ActionWithdrawCard:
+		events, err = m.withdrawCard(a.Slot)
+	default:
+		err = ErrUnknownAction
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m.History = append(m.History, a)
+	return events, nil
+}
+
+// RandomCardID สุ่มการ์ดจากมือของ slot ที่ระบุ (ใช้กับ bot)
+// คืนค่าว่างถ้าไม่มีการ์ดในมือ
+func (m *Match) RandomCardID(slot string) string {
+	player, err := m.Player(slot)
+	if err != nil || len(player.Hand) == 0 {
+		return ""
+	}
+	return player.Hand[m.policy.IntN(len(player.Hand))].ID
+}
+
+func (m *Match) playCard(slot, cardID string) ([]Event, error) {
+	player, err := m.Player(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected *Card
+	for _, card := range player.Hand {
+		if card.ID == cardID {
+			c := card
+			selected = &c
+			break
+		}
+	}
+	if selected == nil {
+		return nil, ErrCardNotInHand
+	}
+
+	if m.Selected == nil {
+		m.Selected = make(map[string]*Card)
+	}
+	m.Selected[slot] = selected
+
+	events := []Event{{Type: EventCardSelected, Slot: slot}}
+
+	// รอจนกว่าทั้งสองฝั่งจะเลือกการ์ด
+	if m.Selected["A"] == nil || m.Selected["B"] == nil {
+		return events, nil
+	}
+
+	return append(events, m.resolveRound()...), nil
+}
+
+func (m *Match) withdrawCard(slot string) ([]Event, error) {
+	if _, err := m.Player(slot); err != nil {
+		return nil, err
+	}
+	if m.Selected[slot] == nil {
+		return nil, ErrNoSelection
+	}
+	delete(m.Selected, slot)
+	return []Event{{Type: EventCardWithdrawn, Slot: slot}}, nil
+}
+
+func (m *Match) resolveRound() []Event {
+	cardA := *m.Selected["A"]
+	cardB := *m.Selected["B"]
+	m.Selected = make(map[string]*Card)
+	m.Round++
+
+	removeCardFromHand(&m.PlayerA.Hand, cardA.ID)
+	removeCardFromHand(&m.PlayerB.Hand, cardB.ID)
+
+	winner := findWinner(cardA, cardB)
+	damageToA, damageToB, specialEventA, specialEventB := doDamage(m, cardA, cardB, winner)
+
+	gameStatus, result := checkGameResult(m)
+	m.Status = gameStatus
+
+	// จั่วการ์ด
+	if gameStatus == StatusOnGoing {
+		if len(m.PlayerA.Deck) > 0 && len(m.PlayerA.Hand) < m.handSize() {
+			m.PlayerA.Hand = append(m.PlayerA.Hand, drawCards(&m.PlayerA.Deck, 1)...)
+		}
+		if len(m.PlayerB.Deck) > 0 && len(m.PlayerB.Hand) < m.handSize() {
+			m.PlayerB.Hand = append(m.PlayerB.Hand, drawCards(&m.PlayerB.Deck, 1)...)
+		}
+	}
+
+	events := []Event{{
+		Type: EventRoundResolved,
+		Round: &RoundResult{
+			Number:        m.Round,
+			CardA:         cardA,
+			CardB:         cardB,
+			Winner:        winner,
+			DamageToA:     damageToA,
+			DamageToB:     damageToB,
+			SpecialEventA: specialEventA,
+			SpecialEventB: specialEventB,
+			HPA:           m.PlayerA.CurrentHP,
+			HPB:           m.PlayerB.CurrentHP,
+		},
+	}}
+
+	if gameStatus == StatusEnd {
+		events = append(events, Event{Type: EventGameEnded, Result: &result})
+	}
+	return events
+}
+
+func (m *Match) useTrueSight(slot string) ([]Event, error) {
+	player, err := m.Player(slot)
+	if err != nil {
+		return nil, err
+	}
+	opponent, _ := m.Opponent(slot)
+
+	if player.TrueSight <= 0 {
+		return nil, ErrNoTrueSight
+	}
+	player.TrueSight--
+
+	return []Event{{
+		Type:          EventTrueSightUsed,
+		Slot:          slot,
+		OpponentHand:  CountCards(opponent.Hand),
+		TrueSightLeft: player.TrueSight,
+	}}, nil
+}
+
+// FindEvent คืน event แรกที่มีชนิดตรงกับ t หรือ nil ถ้าไม่พบ
+func FindEvent(events []Event, t EventType) *Event {
+	for i := range events {
+		if events[i].Type == t {
+			return &events[i]
+		}
+	}
+	return nil
+}
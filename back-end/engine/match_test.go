@@ -0,0 +1,310 @@
+package engine
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// newTestMatch สร้าง match ที่มือของแต่ละฝั่งเป็นการ์ดที่กำหนด และมีการ์ดสำรองในเด็คให้เกมยังไม่จบ
+func newTestMatch(classA, classB string, handA, handB []Card, seed uint64) *Match {
+	reserve := func(prefix string) []Card {
+		return []Card{{ID: prefix + "r1", Type: "rock"}, {ID: prefix + "r2", Type: "paper"}}
+	}
+	m := &Match{
+		PlayerA: PlayerData{Name: "A", CurrentHP: 100, Stat: Stat{ATK: 10, DEF: 4, SPD: 10, HP: 100}, Class: classA},
+		PlayerB: PlayerData{Name: "B", CurrentHP: 100, Stat: Stat{ATK: 10, DEF: 4, SPD: 10, HP: 100}, Class: classB},
+	}
+	m.Start(seed)
+	m.PlayerA.Hand, m.PlayerA.Deck = handA, reserve("a")
+	m.PlayerB.Hand, m.PlayerB.Deck = handB, reserve("b")
+	return m
+}
+
+func card(id, t string) Card { return Card{ID: id, Type: t} }
+
+// playRound ให้ทั้งสองฝั่งลงการ์ดใบแรกในมือแล้วคืนผลของรอบ
+func playRound(t *testing.T, m *Match) ([]Event, *RoundResult) {
+	t.Helper()
+	var events []Event
+	for _, slot := range []string{"A", "B"} {
+		p, _ := m.Player(slot)
+		ev, err := m.Apply(Action{Type: ActionPlayCard, Slot: slot, CardID: p.Hand[0].ID})
+		if err != nil {
+			t.Fatalf("Apply play_card %s: %v", slot, err)
+		}
+		events = append(events, ev...)
+	}
+	round := FindEvent(events, EventRoundResolved)
+	if round == nil {
+		t.Fatalf("round not resolved: %+v", events)
+	}
+	return events, round.Round
+}
+
+func TestApplyRoundWinner(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want string
+	}{
+		{"rock", "scissors", "A"},
+		{"scissors", "paper", "A"},
+		{"paper", "rock", "A"},
+		{"scissors", "rock", "B"},
+		{"paper", "scissors", "B"},
+		{"rock", "paper", "B"},
+		{"rock", "rock", "draw"},
+		{"paper", "paper", "draw"},
+		{"scissors", "scissors", "draw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"_vs_"+tt.b, func(t *testing.T) {
+			m := newTestMatch("none", "none", []Card{card("a1", tt.a)}, []Card{card("b1", tt.b)}, 1)
+			events, round := playRound(t, m)
+			if round.Winner != tt.want {
+				t.Fatalf("winner = %q, want %q", round.Winner, tt.want)
+			}
+			if len(events) != 3 || events[0].Type != EventCardSelected || events[1].Type != EventCardSelected {
+				t.Fatalf("unexpected events %+v", events)
+			}
+			if round.Number != 1 || m.Round != 1 {
+				t.Fatalf("round number = %d (match %d), want 1", round.Number, m.Round)
+			}
+		})
+	}
+}
+
+func TestApplyDamageAndMiss(t *testing.T) {
+	// ATK 10 - DEF 4 ชนะแล้วทำดาเมจ 6 หรือพลาด (-1) ตามการหลบหลีก ลองหลาย seed ให้เจอทั้งสองแบบ
+	var hits, misses int
+	for seed := uint64(1); seed <= 100; seed++ {
+		m := newTestMatch("none", "none", []Card{card("a1", "rock")}, []Card{card("b1", "scissors")}, seed)
+		_, round := playRound(t, m)
+
+		if round.DamageToA != 0 || m.PlayerA.CurrentHP != 100 {
+			t.Fatalf("seed %d: loser dealt damage %d (hp A %d)", seed, round.DamageToA, m.PlayerA.CurrentHP)
+		}
+		switch round.DamageToB {
+		case 6:
+			hits++
+			if m.PlayerB.CurrentHP != 94 || round.HPB != 94 {
+				t.Fatalf("seed %d: hp B = %d (event %d), want 94", seed, m.PlayerB.CurrentHP, round.HPB)
+			}
+		case -1:
+			misses++
+			if m.PlayerB.CurrentHP != 100 {
+				t.Fatalf("seed %d: missed attack changed hp B to %d", seed, m.PlayerB.CurrentHP)
+			}
+		default:
+			t.Fatalf("seed %d: damage to B = %d, want 6 or -1", seed, round.DamageToB)
+		}
+	}
+	if hits == 0 || misses == 0 {
+		t.Fatalf("hits = %d, misses = %d; want both", hits, misses)
+	}
+}
+
+func TestApplySpecialEvents(t *testing.T) {
+	tests := []struct {
+		name           string
+		classA, classB string
+		a, b           string
+		wantEventA     string
+		wantEventB     string
+		wantDamageToA  int
+		wantDamageToB  int
+		wantTrueSightA int
+	}{
+		// True Strike ไม่พลาดและไม่หัก DEF
+		{"assassin true strike", "assassin", "none", "scissors", "paper", "True Strike", "nothing", 0, 10, 0},
+		{"assassin true strike as B", "none", "assassin", "paper", "scissors", "nothing", "True Strike", 10, 0, 0},
+		// Warrior Blood ทำดาเมจครึ่งหนึ่งเมื่อเสมอด้วย rock และไม่พลาด
+		{"warrior blood", "warrior", "none", "rock", "rock", "Warrior Blood", "nothing", 0, 3, 0},
+		{"warrior blood both", "warrior", "warrior", "rock", "rock", "Warrior Blood", "Warrior Blood", 3, 3, 0},
+		{"warrior draw without rock", "warrior", "none", "paper", "paper", "nothing", "nothing", 0, 0, 0},
+		{"mage true sight", "mage", "none", "paper", "rock", "True Sight", "nothing", 0, -2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := uint64(1); seed <= 20; seed++ {
+				m := newTestMatch(tt.classA, tt.classB, []Card{card("a1", tt.a)}, []Card{card("b1", tt.b)}, seed)
+				_, round := playRound(t, m)
+
+				if round.SpecialEventA != tt.wantEventA || round.SpecialEventB != tt.wantEventB {
+					t.Fatalf("seed %d: special events = %q/%q, want %q/%q", seed, round.SpecialEventA, round.SpecialEventB, tt.wantEventA, tt.wantEventB)
+				}
+				// -2 คือดาเมจปกติที่อาจพลาดได้ ไม่ตรวจค่า
+				if tt.wantDamageToB != -2 && round.DamageToB != tt.wantDamageToB {
+					t.Fatalf("seed %d: damage to B = %d, want %d", seed, round.DamageToB, tt.wantDamageToB)
+				}
+				if round.DamageToA != tt.wantDamageToA {
+					t.Fatalf("seed %d: damage to A = %d, want %d", seed, round.DamageToA, tt.wantDamageToA)
+				}
+				if m.PlayerA.TrueSight != tt.wantTrueSightA {
+					t.Fatalf("seed %d: TrueSight A = %d, want %d", seed, m.PlayerA.TrueSight, tt.wantTrueSightA)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyTrueSightExhaustion(t *testing.T) {
+	m := newTestMatch("mage", "none", []Card{card("a1", "rock")}, []Card{card("b1", "paper"), card("b2", "paper"), card("b3", "scissors")}, 1)
+	m.PlayerA.TrueSight = 1
+
+	events, err := m.Apply(Action{Type: ActionUseTrueSight, Slot: "A"})
+	if err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	want := []Event{{
+		Type:          EventTrueSightUsed,
+		Slot:          "A",
+		OpponentHand:  map[string]int{"rock": 0, "paper": 2, "scissors": 1},
+		TrueSightLeft: 0,
+	}}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %+v, want %+v", events, want)
+	}
+
+	if _, err := m.Apply(Action{Type: ActionUseTrueSight, Slot: "A"}); !errors.Is(err, ErrNoTrueSight) {
+		t.Fatalf("second use err = %v, want %v", err, ErrNoTrueSight)
+	}
+	if m.PlayerA.TrueSight != 0 {
+		t.Fatalf("TrueSight = %d, want 0", m.PlayerA.TrueSight)
+	}
+	if len(m.History) != 1 {
+		t.Fatalf("history = %+v, failed action must not be recorded", m.History)
+	}
+}
+
+func TestApplyInvalidActions(t *testing.T) {
+	tests := []struct {
+		name   string
+		action Action
+		want   error
+	}{
+		{"play card invalid slot", Action{Type: ActionPlayCard, Slot: "C", CardID: "a1"}, ErrInvalidSlot},
+		{"true sight empty slot", Action{Type: ActionUseTrueSight, Slot: ""}, ErrInvalidSlot},
+		{"card not in hand", Action{Type: ActionPlayCard, Slot: "A", CardID: "b1"}, ErrCardNotInHand},
+		{"unknown action", Action{Type: "dance", Slot: "A"}, ErrUnknownAction},
+		{"withdraw without selection", Action{Type: ActionWithdrawCard, Slot: "A"}, ErrNoSelection},
+		{"withdraw invalid slot", Action{Type: ActionWithdrawCard, Slot: "C"}, ErrInvalidSlot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMatch("none", "none", []Card{card("a1", "rock")}, []Card{card("b1", "paper")}, 1)
+			m.PlayerA.TrueSight = 1
+			before, _ := m.Snapshot()
+
+			events, err := m.Apply(tt.action)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if events != nil {
+				t.Fatalf("events = %+v, want nil", events)
+			}
+			if after, _ := m.Snapshot(); !reflect.DeepEqual(before, after) {
+				t.Fatalf("state changed after invalid action")
+			}
+		})
+	}
+}
+
+func TestApplyWithdrawCard(t *testing.T) {
+	m := newTestMatch("none", "none", []Card{card("a1", "rock"), card("a2", "paper")}, []Card{card("b1", "rock")}, 1)
+
+	if _, err := m.Apply(Action{Type: ActionPlayCard, Slot: "A", CardID: "a1"}); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	events, err := m.Apply(Action{Type: ActionWithdrawCard, Slot: "A"})
+	if err != nil {
+		t.Fatalf("withdraw: %v", err)
+	}
+	if want := []Event{{Type: EventCardWithdrawn, Slot: "A"}}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %+v, want %+v", events, want)
+	}
+	if m.Selected["A"] != nil || len(m.PlayerA.Hand) != 2 {
+		t.Fatalf("selection not cleared: %+v, hand %+v", m.Selected, m.PlayerA.Hand)
+	}
+
+	// เลือกใหม่ได้ และรอบตัดสินด้วยการ์ดใบใหม่
+	if _, err := m.Apply(Action{Type: ActionPlayCard, Slot: "A", CardID: "a2"}); err != nil {
+		t.Fatalf("reselect: %v", err)
+	}
+	events, err = m.Apply(Action{Type: ActionPlayCard, Slot: "B", CardID: "b1"})
+	if err != nil {
+		t.Fatalf("play B: %v", err)
+	}
+	round := FindEvent(events, EventRoundResolved)
+	if round == nil || round.Round.CardA.ID != "a2" || round.Round.Winner != "A" {
+		t.Fatalf("round = %+v, want a2 to win", round)
+	}
+	if len(m.History) != 4 || m.History[1].Type != ActionWithdrawCard {
+		t.Fatalf("history = %+v", m.History)
+	}
+}
+
+func TestApplyGameEnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(m *Match)
+		classA  string
+		a, b    string
+		want    Result
+		wantEnd bool
+	}{
+		{
+			name:   "opponent out of HP",
+			setup:  func(m *Match) { m.PlayerB.CurrentHP = 5 },
+			classA: "assassin", a: "scissors", b: "paper",
+			want:    Result{ResultA: "Win", DetailA: "Opponent out of HP", ResultB: "Lose", DetailB: "You out of HP"},
+			wantEnd: true,
+		},
+		{
+			name:   "both out of card",
+			setup:  func(m *Match) { m.PlayerA.Deck, m.PlayerB.Deck = nil, nil },
+			classA: "none", a: "paper", b: "paper",
+			want:    Result{ResultA: "Draw", DetailA: "Both out of Card", ResultB: "Draw", DetailB: "Both out of Card"},
+			wantEnd: true,
+		},
+		{
+			name:   "player out of card",
+			setup:  func(m *Match) { m.PlayerA.Deck = nil },
+			classA: "none", a: "paper", b: "paper",
+			want:    Result{ResultA: "Lose", DetailA: "You out of Card", ResultB: "Win", DetailB: "Opponent out of Card"},
+			wantEnd: true,
+		},
+		{
+			name:   "still going",
+			setup:  func(m *Match) {},
+			classA: "none", a: "paper", b: "paper",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMatch(tt.classA, "none", []Card{card("a1", tt.a)}, []Card{card("b1", tt.b)}, 1)
+			tt.setup(m)
+			events, _ := playRound(t, m)
+
+			ended := FindEvent(events, EventGameEnded)
+			if !tt.wantEnd {
+				if ended != nil || m.Status != StatusOnGoing {
+					t.Fatalf("game ended unexpectedly: %+v", ended)
+				}
+				if len(m.PlayerA.Hand) != 1 || len(m.PlayerB.Hand) != 1 {
+					t.Fatalf("hands not refilled: %d/%d", len(m.PlayerA.Hand), len(m.PlayerB.Hand))
+				}
+				return
+			}
+			if ended == nil || m.Status != StatusEnd {
+				t.Fatalf("game did not end (status %s)", m.Status)
+			}
+			if *ended.Result != tt.want {
+				t.Fatalf("result = %+v, want %+v", *ended.Result, tt.want)
+			}
+			if _, err := m.Apply(Action{Type: ActionUseTrueSight, Slot: "A"}); !errors.Is(err, ErrMatchEnded) {
+				t.Fatalf("Apply after end err = %v, want %v", err, ErrMatchEnded)
+			}
+		})
+	}
+}
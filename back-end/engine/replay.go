@@ -0,0 +1,21 @@
+package engine
+
+// Replay สร้าง match ขึ้นใหม่จาก PlayerData ตอนเริ่มเกม (ก่อนสับไพ่), จำนวนการ์ดในมือ (0 = HandSize), seed และ action ตามลำดับ
+// คืน event ทั้งหมดที่เกิดขึ้น ซึ่งจะตรงกับเกมจริงทุกประการ
+func Replay(playerA, playerB PlayerData, handSize int, seed uint64, actions []Action) (*Match, []Event, error) {
+	playerA.Deck = append([]Card(nil), playerA.Deck...)
+	playerB.Deck = append([]Card(nil), playerB.Deck...)
+
+	m := &Match{PlayerA: playerA, PlayerB: playerB, HandSize: handSize}
+	m.Start(seed)
+
+	var events []Event
+	for _, a := range actions {
+		ev, err := m.Apply(a)
+		if err != nil {
+			return m, events, err
+		}
+		events = append(events, ev...)
+	}
+	return m, events, nil
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// replayPlayers ผู้เล่นตอนเริ่มเกม (ก่อนสับไพ่) สร้างใหม่ทุกครั้งเพราะ Start สับเด็คในที่
+// SPD ใกล้กันเพื่อให้มีการหลบหลีกเกิดขึ้นบ้าง
+func replayPlayers() (PlayerData, PlayerData) {
+	deck := func(prefix string) []Card {
+		var cards []Card
+		for i, t := range []string{"rock", "paper", "scissors", "rock", "paper", "scissors", "rock", "paper", "scissors"} {
+			cards = append(cards, Card{ID: prefix + strconv.Itoa(i), Type: t})
+		}
+		return cards
+	}
+	a := PlayerData{Name: "A", CurrentHP: 60, Deck: deck("a"), Stat: Stat{ATK: 12, DEF: 4, SPD: 10, HP: 60}, Class: "mage"}
+	b := PlayerData{Name: "B", CurrentHP: 60, Deck: deck("b"), Stat: Stat{ATK: 11, DEF: 5, SPD: 12, HP: 60}, Class: "warrior"}
+	return a, b
+}
+
+// playBotMatch เล่นจนจบ ทั้งสองฝั่งให้ bot เลือกการ์ดด้วย RandomCardID และ A ใช้ True Sight ทุกครั้งที่มี
+func playBotMatch(t *testing.T, seed uint64) (*Match, []Event) {
+	t.Helper()
+	a, b := replayPlayers()
+	m := &Match{PlayerA: a, PlayerB: b}
+	m.Start(seed)
+
+	var events []Event
+	for m.Status == StatusOnGoing {
+		var actions []Action
+		if m.PlayerA.TrueSight > 0 {
+			actions = append(actions, Action{Type: ActionUseTrueSight, Slot: "A"})
+		}
+		actions = append(actions,
+			Action{Type: ActionPlayCard, Slot: "A", CardID: m.RandomCardID("A")},
+			Action{Type: ActionPlayCard, Slot: "B", CardID: m.RandomCardID("B")},
+		)
+		for _, action := range actions {
+			ev, err := m.Apply(action)
+			if err != nil {
+				t.Fatalf("seed %d: Apply(%+v): %v", seed, action, err)
+			}
+			events = append(events, ev...)
+		}
+	}
+	return m, events
+}
+
+func TestReplayMatchesPlayedGame(t *testing.T) {
+	var misses, trueSights int
+	for seed := uint64(1); seed <= 20; seed++ {
+		played, events := playBotMatch(t, seed)
+
+		// bot เลือกการ์ดจาก seed ของ match เล่นด้วย seed เดิมต้องได้ action ชุดเดิม
+		again, _ := playBotMatch(t, seed)
+		if !reflect.DeepEqual(played.History, again.History) {
+			t.Fatalf("seed %d: bot picks differ between runs", seed)
+		}
+
+		a, b := replayPlayers()
+		replayed, replayEvents, err := Replay(a, b, 0, seed, played.History)
+		if err != nil {
+			t.Fatalf("seed %d: Replay: %v", seed, err)
+		}
+		if !reflect.DeepEqual(events, replayEvents) {
+			t.Fatalf("seed %d: replayed events differ\nplayed   %+v\nreplayed %+v", seed, events, replayEvents)
+		}
+		if !reflect.DeepEqual(played.PlayerA, replayed.PlayerA) || !reflect.DeepEqual(played.PlayerB, replayed.PlayerB) {
+			t.Fatalf("seed %d: replayed players differ", seed)
+		}
+		if replayed.Status != StatusEnd {
+			t.Fatalf("seed %d: replayed match did not end", seed)
+		}
+
+		for _, e := range events {
+			if e.Type == EventTrueSightUsed {
+				trueSights++
+			}
+			if e.Round != nil && (e.Round.DamageToA == -1 || e.Round.DamageToB == -1) {
+				misses++
+			}
+		}
+	}
+	// ต้องมีการหลบหลีกและ True Sight เกิดขึ้นจริง ไม่อย่างนั้นการทดสอบไม่ครอบคลุมการสุ่มของกฎ
+	if misses == 0 {
+		t.Fatal("no evasion roll produced a miss in any seed")
+	}
+	if trueSights == 0 {
+		t.Fatal("True Sight was never used in any seed")
+	}
+}
+
+func TestReplayRejectsInvalidAction(t *testing.T) {
+	a, b := replayPlayers()
+	_, events, err := Replay(a, b, 0, 1, []Action{{Type: ActionPlayCard, Slot: "A", CardID: "missing"}})
+	if err != ErrCardNotInHand {
+		t.Fatalf("err = %v, want %v", err, ErrCardNotInHand)
+	}
+	if len(events) != 0 {
+		t.Fatalf("events = %+v, want none", events)
+	}
+}
@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+func shuffleCards(rng *rand.Rand, cards []Card) {
+	rng.Shuffle(len(cards), func(i, j int) {
+		cards[i], cards[j] = cards[j], cards[i]
+	})
+}
+
+func drawCards(deck *[]Card, n int) []Card {
+	if len(*deck) < n {
+		n = len(*deck)
+	}
+	hand := (*deck)[:n]
+	*deck = (*deck)[n:]
+	return hand
+}
+
+// CountCards นับจำนวนการ์ดแต่ละประเภท (rock / paper / scissors)
+func CountCards(cards []Card) map[string]int {
+	countByType := map[string]int{
+		"rock":     0,
+		"paper":    0,
+		"scissors": 0,
+	}
+
+	for _, card := range cards {
+		countByType[card.Type]++
+	}
+
+	return countByType
+}
+
+func findWinner(cardA Card, cardB Card) (winner string) {
+	if cardA.Type == cardB.Type {
+		winner = "draw"
+	} else if cardA.Type == "rock" && cardB.Type == "scissors" {
+		winner = "A"
+	} else if cardA.Type == "scissors" && cardB.Type == "paper" {
+		winner = "A"
+	} else if cardA.Type == "paper" && cardB.Type == "rock" {
+		winner = "A"
+	} else {
+		winner = "B"
+	}
+	return winner
+}
+
+func doDamage(
+	m *Match,
+	cardA Card,
+	cardB Card,
+	winner string,
+) (damageToA int, damageToB int, specialEventA string, specialEventB string) {
+
+	damageToB = 0
+	damageToA = 0
+	specialEventA = "nothing"
+	specialEventB = "nothing"
+
+	evasionA := math.Max(0.15, math.Min(0.1+float64(m.PlayerA.Stat.SPD-m.PlayerB.Stat.SPD)*0.01, 0.75))
+	evasionB := math.Max(0.15, math.Min(0.1+float64(m.PlayerB.Stat.SPD-m.PlayerA.Stat.SPD)*0.01, 0.75))
+
+	attackToAMiss := m.rng.Float64() < evasionA
+	attackToBMiss := m.rng.Float64() < evasionB
+
+	switch winner {
+	case "A":
+		damageToB = int(math.Max(float64(m.PlayerA.Stat.ATK-m.PlayerB.Stat.DEF), 1))
+
+		if m.PlayerA.Class == "assassin" && cardA.Type == "scissors" {
+			damageToB = int(math.Max(float64(m.PlayerA.Stat.ATK), 1))
+			attackToBMiss = false
+			specialEventA = "True Strike"
+		} else if m.PlayerA.Class == "mage" && cardA.Type == "paper" {
+			m.PlayerA.TrueSight += 1
+			specialEventA = "True Sight"
+		}
+	case "B":
+		damageToA = int(math.Max(float64(m.PlayerB.Stat.ATK-m.PlayerA.Stat.DEF), 1))
+
+		if m.PlayerB.Class == "assassin" && cardB.Type == "scissors" {
+			damageToA = int(math.Max(float64(m.PlayerB.Stat.ATK), 1))
+			attackToAMiss = false
+			specialEventB = "True Strike"
+		} else if m.PlayerB.Class == "mage" && cardB.Type == "paper" {
+			m.PlayerB.TrueSight += 1
+			specialEventB = "True Sight"
+		}
+	case "draw":
+		if m.PlayerA.Class == "warrior" && cardA.Type == "rock" {
+			damageToB = int(math.Max(float64(m.PlayerA.Stat.ATK-m.PlayerB.Stat.DEF)/2, 1))
+			attackToBMiss = false
+			specialEventA = "Warrior Blood"
+		}
+		if m.PlayerB.Class == "warrior" && cardB.Type == "rock" {
+			damageToA = int(math.Max(float64(m.PlayerB.Stat.ATK-m.PlayerA.Stat.DEF)/2, 1))
+			attackToAMiss = false
+			specialEventB = "Warrior Blood"
+		}
+	}
+
+	if damageToA != 0 {
+		if attackToAMiss {
+			damageToA = -1
+		} else {
+			m.PlayerA.CurrentHP = int(math.Max(float64(m.PlayerA.CurrentHP-damageToA), 0))
+		}
+	}
+
+	if damageToB != 0 {
+		if attackToBMiss {
+			damageToB = -1
+		} else {
+			m.PlayerB.CurrentHP = int(math.Max(float64(m.PlayerB.CurrentHP-damageToB), 0))
+		}
+	}
+
+	return
+}
+
+func checkGameResult(m *Match) (gameStatus string, result Result) {
+	playerOutOfHP := m.PlayerA.CurrentHP == 0
+	opponentOutOfHP := m.PlayerB.CurrentHP == 0
+	playerOutOfCard := len(m.PlayerA.Deck)+len(m.PlayerA.Hand) == 0
+	opponentOutOfCard := len(m.PlayerB.Deck)+len(m.PlayerB.Hand) == 0
+	gameStatus = StatusOnGoing
+	switch {
+	case playerOutOfHP && opponentOutOfHP:
+		gameStatus = StatusEnd
+		result.ResultA, result.DetailA = "Draw", "Both out of HP"
+		result.ResultB, result.DetailB = "Draw", "Both out of HP"
+
+	case playerOutOfHP:
+		gameStatus = StatusEnd
+		result.ResultA, result.DetailA = "Lose", "You out of HP"
+		result.ResultB, result.DetailB = "Win", "Opponent out of HP"
+
+	case opponentOutOfHP:
+		gameStatus = StatusEnd
+		result.ResultA, result.DetailA = "Win", "Opponent out of HP"
+		result.ResultB, result.DetailB = "Lose", "You out of HP"
+
+	case playerOutOfCard && opponentOutOfCard:
+		gameStatus = StatusEnd
+		result.ResultA, result.DetailA = "Draw", "Both out of Card"
+		result.ResultB, result.DetailB = "Draw", "Both out of Card"
+
+	case playerOutOfCard:
+		gameStatus = StatusEnd
+		result.ResultA, result.DetailA = "Lose", "You out of Card"
+		result.ResultB, result.DetailB = "Win", "Opponent out of Card"
+
+	case opponentOutOfCard:
+		gameStatus = StatusEnd
+		result.ResultA, result.DetailA = "Win", "Opponent out of Card"
+		result.ResultB, result.DetailB = "Lose", "You out of Card"
+	}
+
+	return
+}
+
+func removeCardFromHand(hand *[]Card, cardID string) bool {
+	found := false
+	newHand := (*hand)[:0] // reuse slice memory
+
+	for _, card := range *hand {
+		if card.ID == cardID && !found {
+			found = true
+			continue // ข้ามอันนี้เพื่อ "ลบ"
+		}
+		newHand = append(newHand, card)
+	}
+
+	*hand = newHand
+	return found
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"errors"
+	"math/rand/v2"
+)
+
+var ErrNotStarted = errors.New("match not started")
+
+// Snapshot สถานะทั้งหมดของ match ณ ตอนนี้ รวมสถานะของตัวสุ่ม
+// บันทึกเป็น JSON แล้วโหลดกลับด้วย Restore จะเล่นต่อได้ผลเหมือนไม่เคยหยุด
+type Snapshot struct {
+	PlayerA  PlayerData       `json:"playerA"`
+	PlayerB  PlayerData       `json:"playerB"`
+	Selected map[string]*Card `json:"selected"`
+	Round    int              `json:"round"`
+	Status   string           `json:"status"`
+	Seed     uint64           `json:"seed"`
+	History  []Action         `json:"history"`
+	HandSize int              `json:"handSize,omitempty"`
+	RNG      []byte           `json:"rng"`
+	Policy   []byte           `json:"policy"`
+}
+
+// Snapshot คืนสำเนาสถานะของ match ต้องเรียกหลัง Start
+func (m *Match) Snapshot() (Snapshot, error) {
+	if m.rngSrc == nil || m.policySrc == nil {
+		return Snapshot{}, ErrNotStarted
+	}
+	rngState, err := m.rngSrc.MarshalBinary()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	policyState, err := m.policySrc.MarshalBinary()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	selected := make(map[string]*Card, len(m.Selected))
+	for slot, card := range m.Selected {
+		if card != nil {
+			c := *card
+			selected[slot] = &c
+		}
+	}
+
+	return Snapshot{
+		PlayerA:  copyPlayer(m.PlayerA),
+		PlayerB:  copyPlayer(m.PlayerB),
+		Selected: selected,
+		Round:    m.Round,
+		Status:   m.Status,
+		Seed:     m.Seed,
+		History:  append([]Action(nil), m.History...),
+		HandSize: m.HandSize,
+		RNG:      rngState,
+		Policy:   policyState,
+	}, nil
+}
+
+// Restore ตั้ง match ให้เป็นสถานะตาม snapshot (ใช้แทน Start)
+func (m *Match) Restore(s Snapshot) error {
+	rngSrc, policySrc := &rand.PCG{}, &rand.PCG{}
+	if err := rngSrc.UnmarshalBinary(s.RNG); err != nil {
+		return err
+	}
+	if err := policySrc.UnmarshalBinary(s.Policy); err != nil {
+		return err
+	}
+
+	m.PlayerA = copyPlayer(s.PlayerA)
+	m.PlayerB = copyPlayer(s.PlayerB)
+	m.Selected = make(map[string]*Card, len(s.Selected))
+	for slot, card := range s.Selected {
+		if card != nil {
+			c := *card
+			m.Selected[slot] = &c
+		}
+	}
+	m.Round = s.Round
+	m.Status = s.Status
+	m.Seed = s.Seed
+	m.History = append([]Action(nil), s.History...)
+	m.HandSize = s.HandSize
+	m.rngSrc, m.policySrc = rngSrc, policySrc
+	m.rng, m.policy = rand.New(rngSrc), rand.New(policySrc)
+	return nil
+}
+
+func copyPlayer(p PlayerData) PlayerData {
+	p.Deck = append([]Card(nil), p.Deck...)
+	p.Hand = append([]Card(nil), p.Hand...)
+	return p
+}
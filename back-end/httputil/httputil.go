@@ -0,0 +1,17 @@
+// Package httputil ตัวช่วยตอบ HTTP ที่ใช้ร่วมกันระหว่าง package ของ API
+package httputil
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// WriteJSONError ตอบ error เป็น JSON รูปแบบ {"type":"error","error":msg}
+func WriteJSONError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"type":  "error",
+		"error": msg,
+	})
+}
@@ -0,0 +1,145 @@
+// Package leaderboard ตารางอันดับผู้เล่น (ด่าน campaign, เลเวล, ทอง, ชนะ campaign ติดกัน) แยกตามคลาสได้
+// อันดับอ่านจาก cache ที่สร้างใหม่เป็นระยะในพื้นหลัง request ไม่ได้ query ตาราง users เอง
+package leaderboard
+
+import (
+	"clash_and_card/httputil"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	boards  = []string{store.BoardCampaign, store.BoardLevel, store.BoardGold, store.BoardStreak}
+	classes = []string{"", "warrior", "mage", "assassin"} // "" = ทุกคลาส
+)
+
+type boardKey struct {
+	board string
+	class string
+}
+
+// Cache เก็บอันดับ size คนแรกของทุก board และทุกคลาส
+type Cache struct {
+	st   store.Store
+	size int
+
+	mu          sync.RWMutex
+	boards      map[boardKey][]models.LeaderboardEntry
+	refreshedAt time.Time
+}
+
+func NewCache(st store.Store, size int) *Cache {
+	return &Cache{st: st, size: size, boards: make(map[boardKey][]models.LeaderboardEntry)}
+}
+
+// Refresh สร้างอันดับใหม่ทั้งหมดแล้วสลับเข้าไปทีเดียว request ที่อ่านอยู่ระหว่างนั้นยังเห็นชุดเดิมครบ
+func (c *Cache) Refresh() error {
+	next := make(map[boardKey][]models.LeaderboardEntry, len(boards)*len(classes))
+	for _, board := range boards {
+		for _, class := range classes {
+			entries, err := c.st.Leaderboards().TopUsers(board, class, c.size)
+			if err != nil {
+				return err
+			}
+			next[boardKey{board, class}] = entries
+		}
+	}
+
+	c.mu.Lock()
+	c.boards = next
+	c.refreshedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// Start สร้าง cache ครั้งแรกทันทีแล้วสร้างใหม่ทุก interval
+func (c *Cache) Start(interval time.Duration) {
+	if err := c.Refresh(); err != nil {
+		log.Println("Leaderboard refresh error:", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := c.Refresh(); err != nil {
+				log.Println("Leaderboard refresh error:", err)
+			}
+		}
+	}()
+}
+
+// Handler GET /api/leaderboard?board=level&class=mage&offset=0&limit=20
+func (c *Cache) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		board := q.Get("board")
+		if board == "" {
+			board = store.BoardLevel
+		}
+		class := q.Get("class")
+		if !contains(boards, board) {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "board must be campaign, level, gold or streak")
+			return
+		}
+		if !contains(classes, class) {
+			httputil.WriteJSONError(w, http.StatusBadRequest, "class must be warrior, mage or assassin")
+			return
+		}
+
+		offset, limit := 0, 20
+		if v := q.Get("offset"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				httputil.WriteJSONError(w, http.StatusBadRequest, "offset must not be negative")
+				return
+			}
+			offset = n
+		}
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > 100 {
+				httputil.WriteJSONError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+				return
+			}
+			limit = n
+		}
+
+		c.mu.RLock()
+		all := c.boards[boardKey{board, class}]
+		refreshedAt := c.refreshedAt
+		c.mu.RUnlock()
+
+		page := []models.LeaderboardEntry{}
+		if offset < len(all) {
+			page = all[offset:min(offset+limit, len(all))]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"board":       board,
+			"class":       class,
+			"total":       len(all),
+			"offset":      offset,
+			"limit":       limit,
+			"refreshedAt": refreshedAt,
+			"entries":     page,
+		})
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"clash_and_card/battle"
+	"clash_and_card/config"
+	"clash_and_card/leaderboard"
 	"clash_and_card/upgrade"
 	"clash_and_card/user"
 
+	"flag"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/gorilla/mux"
 )
@@ -14,46 +18,145 @@ import (
 // var db *sql.DB
 
 func main() {
-	db := ConnectDB()
-	defer db.Close()
+	configPath := flag.String("config", os.Getenv("CLASH_CONFIG"), "path to JSON config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal("Config error:\n", err)
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		runMigrate(cfg, args[1:])
+		return
+	}
+
+	st := OpenStore(cfg.Database)
+	defer st.Close()
+	user.ConfigureTokens(cfg.JWTSecret, cfg.TokenTTL.Duration, cfg.RefreshTokenTTL.Duration)
+	user.ConfigureSessions(st.Sessions())
+
+	battle.ConfigureReconnectGrace(cfg.Matches.ReconnectGrace.Duration)
+	battle.ConfigurePrivateRooms(cfg.Matches.PrivateRoomTimeout.Duration)
+	battle.ConfigureTurnTimer(cfg.Matches.TurnTimeout.Duration, cfg.Matches.MaxTimeouts, cfg.Matches.AutoPlayPolicy)
+	battle.ConfigureRematch(cfg.Matches.RematchWindow.Duration)
+	battle.ConfigureTournaments(cfg.Matches.TournamentNoShowTimeout.Duration)
+	if cfg.Matches.StateStore == "database" {
+		battle.ConfigureStateStore(st.LiveMatches())
+	}
+	campaign, pvp, err := battle.RestoreLiveMatches(st)
+	if err != nil {
+		log.Fatal("Restore live matches:", err)
+	}
+	log.Printf("Restored %d campaign and %d PvP matches\n", campaign, pvp)
+	tournamentRooms, err := battle.RestoreTournaments(st)
+	if err != nil {
+		log.Fatal("Restore tournaments:", err)
+	}
+	log.Printf("Reopened %d tournament rooms\n", tournamentRooms)
+	battle.StartMatchJanitor(st, cfg.Matches.CampaignIdleTimeout.Duration, cfg.Matches.UnusedRoomTimeout.Duration)
+	battle.ConfigureMatchmaking(cfg.Matchmaking.InitialBand, cfg.Matchmaking.BandGrowth, cfg.Matchmaking.BandGrowthInterval.Duration)
+	battle.ConfigureRating(cfg.Rating.KFactor)
+	battle.StartMatchmaker()
+
+	seasonRewards := make([]battle.SeasonReward, len(cfg.Seasons.Rewards))
+	for i, reward := range cfg.Seasons.Rewards {
+		seasonRewards[i] = battle.SeasonReward(reward)
+	}
+	battle.StartSeasonScheduler(st, battle.SeasonSettings{
+		Length:        cfg.Seasons.Length.Duration,
+		WinPoints:     cfg.Seasons.WinPoints,
+		LossPoints:    cfg.Seasons.LossPoints,
+		DrawPoints:    cfg.Seasons.DrawPoints,
+		SoftResetKeep: cfg.Seasons.SoftResetKeep,
+		Rewards:       seasonRewards,
+	})
+
+	leaderboards := leaderboard.NewCache(st, cfg.Leaderboard.Size)
+	leaderboards.Start(cfg.Leaderboard.RefreshInterval.Duration)
 
 	r := mux.NewRouter()
 
 	// เพิ่ม middleware CORS
-	r.Use(middlewareCORS)
-	r.HandleFunc("/api/login", user.LoginHandler(db)).Methods("POST", "OPTIONS")
+	r.Use(newCORSMiddleware(cfg.CORSOrigins))
+	r.HandleFunc("/api/login", user.LoginHandler(st)).Methods("POST", "OPTIONS")
+
+	r.HandleFunc("/api/check-email", user.CheckEmailHandler(st)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/register", user.RegisterHandler(st, cfg.StartingStats)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/token/refresh", user.RefreshTokenHandler(st)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/logout", user.LogoutHandler(st)).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/matches/live", battle.LiveMatchCountHandler()).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/leaderboard", leaderboards.Handler()).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/matches/{id}/replay", battle.MatchReplayHandler(st)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/tournaments", battle.ListTournamentsHandler(st)).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/tournaments/{id}", battle.TournamentHandler(st)).Methods("GET", "OPTIONS")
 
-	r.HandleFunc("/api/check-email", user.CheckEmailHandler(db)).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/register", user.RegisterHandler(db)).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/user", user.GetUserHandler(db)).Methods("GET", "OPTIONS")
-	r.HandleFunc("/api/deck", user.GetUserDeckHandler(db)).Methods("GET", "OPTIONS")
+	// route ที่ต้อง login ผ่าน RequireAuth ซึ่งใส่ user id ไว้ใน context
+	// (preflight OPTIONS ถูกตอบโดย CORS middleware ก่อนถึงตรงนี้)
+	auth := r.PathPrefix("/api").Subrouter()
+	auth.Use(user.RequireAuth)
 
-	r.HandleFunc("/api/battle/start", battle.StartBattleHandler(db)).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/battle/{matchID}/play", battle.PlayCardHandler(db)).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/battle/{matchID}/play/true-sight", battle.TrueSightHandler()).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/user", user.GetUserHandler(st)).Methods("GET", "OPTIONS")
+	auth.HandleFunc("/deck", user.GetUserDeckHandler(st)).Methods("GET", "OPTIONS")
 
-	r.HandleFunc("/api/upgrade-stat", upgrade.UpgradeStatHandler(db)).Methods("POST", "OPTIONS")
-	r.HandleFunc("/api/buy-card", upgrade.BuyCardHandler(db)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/battle/start", battle.StartBattleHandler(st)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/battle/active", battle.ActiveBattleHandler()).Methods("GET", "OPTIONS")
+	auth.HandleFunc("/battle/{matchID}/play", battle.PlayCardHandler(st)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/battle/{matchID}/play/true-sight", battle.TrueSightHandler(st)).Methods("POST", "OPTIONS")
 
-	r.HandleFunc("/ws/pvp", battle.HandlePVPWebSocket(db))
+	auth.HandleFunc("/pvp/rating", battle.RatingHandler(st)).Methods("GET", "OPTIONS")
+	auth.HandleFunc("/pvp/season", battle.SeasonHandler(st)).Methods("GET", "OPTIONS")
+	auth.HandleFunc("/pvp/live", battle.LiveMatchesHandler()).Methods("GET", "OPTIONS")
+	auth.HandleFunc("/pvp/rooms", battle.CreatePrivateRoomHandler()).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/pvp/rooms/join", battle.JoinPrivateRoomHandler(st)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/pvp/rooms/{roomID}/kick", battle.KickGuestHandler()).Methods("POST", "OPTIONS")
+
+	auth.HandleFunc("/tournaments", battle.CreateTournamentHandler(st)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/tournaments/{id}/register", battle.RegisterTournamentHandler(st)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/tournaments/{id}/start", battle.StartTournamentHandler(st)).Methods("POST", "OPTIONS")
+
+	auth.HandleFunc("/friends", battle.FriendsHandler(st)).Methods("GET", "OPTIONS")
+	auth.HandleFunc("/friends/requests", battle.SendFriendRequestHandler(st)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/friends/requests/{userID}/accept", battle.AcceptFriendRequestHandler(st)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/friends/requests/{userID}/decline", battle.DeclineFriendRequestHandler(st)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/friends/{userID}", battle.RemoveFriendHandler(st)).Methods("DELETE", "OPTIONS")
+	auth.HandleFunc("/friends/{userID}/challenge", battle.ChallengeFriendHandler(st)).Methods("POST", "OPTIONS")
+
+	auth.HandleFunc("/upgrade-stat", upgrade.UpgradeStatHandler(st)).Methods("POST", "OPTIONS")
+	auth.HandleFunc("/buy-card", upgrade.BuyCardHandler(st, cfg.ShopPrices.Card)).Methods("POST", "OPTIONS")
+
+	r.HandleFunc("/ws/pvp", battle.HandlePVPWebSocket(st))
+	r.HandleFunc("/ws/matchmaking", battle.HandleMatchmakingWebSocket(st))
+	r.HandleFunc("/ws/notifications", battle.HandleNotificationWebSocket(st))
 	//r.HandleFunc("/ws/pvp", HandlePVPWebSocket)
 
-	log.Println("Server running at :8080")
-	http.ListenAndServe(":8080", r)
+	log.Printf("Server running at %s (%s)\n", cfg.ListenAddr, cfg.Env)
+	log.Fatal(http.ListenAndServe(cfg.ListenAddr, r))
 }
 
-func middlewareCORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*") // หรือเจาะจง origin ที่ใช้
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Vary", "Origin")
+// newCORSMiddleware อนุญาตเฉพาะ origin ที่ตั้งค่าไว้ ("*" คืออนุญาตทุก origin)
+func newCORSMiddleware(origins []string) mux.MiddlewareFunc {
+	allowed := make(map[string]bool)
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if allowed["*"] {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else if origin := r.Header.Get("Origin"); allowed[origin] {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Vary", "Origin")
 
-		next.ServeHTTP(w, r)
-	})
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"clash_and_card/config"
+	"clash_and_card/migrations"
+)
+
+// runMigrate จัดการคำสั่ง `migrate up|down [n]|status`
+func runMigrate(cfg config.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: migrate up | down [steps] | status")
+		os.Exit(2)
+	}
+
+	if cfg.Database.Driver == "memory" {
+		log.Fatal("migrate needs a mysql or sqlite database")
+	}
+
+	st := openSQLStore(cfg.Database)
+	defer st.Close()
+	db, dialect := st.DB(), st.Dialect()
+
+	switch args[0] {
+	case "up":
+		ran, err := migrations.Up(db, dialect)
+		for _, m := range ran {
+			fmt.Printf("up   %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(ran) == 0 {
+			fmt.Println("schema is up to date")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				log.Fatal("invalid steps:", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(db, dialect, steps)
+		for _, m := range reverted {
+			fmt.Printf("down %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case "status":
+		statuses, err := migrations.List(db, dialect)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		fmt.Println("usage: migrate up | down [steps] | status")
+		os.Exit(2)
+	}
+}
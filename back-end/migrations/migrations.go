@@ -0,0 +1,234 @@
+// Package migrations สร้างและอัปเกรด schema ของฐานข้อมูลจากไฟล์ SQL ที่ฝังอยู่ในไบนารี
+//
+// ไฟล์อยู่ใน <dialect>/NNNN_name.up.sql และ NNNN_name.down.sql
+// เวอร์ชันที่ใช้ไปแล้วเก็บในตาราง schema_migrations ฟีเจอร์ใหม่ที่ต้องการตารางเพิ่ม
+// ให้เพิ่มไฟล์เวอร์ชันถัดไปทั้งของ mysql และ sqlite
+//
+// ไฟล์หนึ่งถูกส่งให้ฐานข้อมูลทั้งไฟล์ใน Exec เดียว ฐานข้อมูลแยกคำสั่งเอง (semicolon ใน string หรือ comment ไม่มีปัญหา)
+// MySQL ต้องเปิด multiStatements ใน DSN (main.ConnectDB เปิดให้เอง)
+//
+// SQLite ใช้ทั้งไฟล์ใน transaction เดียว ล้มเหลวกลางทางจะไม่มีอะไรเปลี่ยน
+// MySQL commit DDL (CREATE, ALTER, DROP) ทันทีทีละคำสั่ง migration ที่ล้มกลางไฟล์จะค้างครึ่งทางและไม่ถูกบันทึกเวอร์ชัน
+// ต้องแก้ schema ให้กลับเป็นแบบก่อนหน้าเองก่อนรันใหม่ จึงควรเขียนให้รันซ้ำได้ (IF NOT EXISTS, IF EXISTS) เท่าที่ทำได้
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+}
+
+// load อ่าน migration ทั้งหมดของ dialect เรียงตามเวอร์ชัน
+func load(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("unknown dialect %q", dialect)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		name := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		versionStr, migrationName, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q", name)
+		}
+
+		body, err := fs.ReadFile(files, path.Join(dialect, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: migrationName}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func ensureTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INT NOT NULL PRIMARY KEY,
+			name       VARCHAR(255) NOT NULL,
+			applied_at VARCHAR(32) NOT NULL
+		)`)
+	return err
+}
+
+func applied(db *sql.DB) (map[int]string, error) {
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		versions[version] = at
+	}
+	return versions, rows.Err()
+}
+
+func run(db *sql.DB, body string, record func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(body); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Up ใช้ migration ที่ยังไม่เคยใช้ทั้งหมดตามลำดับ คืน migration ที่เพิ่งใช้
+func Up(db *sql.DB, dialect string) ([]Migration, error) {
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, m := range migrations {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		err := run(db, m.up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				m.Version, m.Name, time.Now().UTC().Format(time.RFC3339))
+			return err
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %04d_%s: %v", m.Version, m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// Down ย้อน migration ล่าสุดที่ใช้ไปแล้วจำนวน steps ครั้ง คืน migration ที่ย้อนไป
+func Down(db *sql.DB, dialect string, steps int) ([]Migration, error) {
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := migrations[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		if m.down == "" {
+			return reverted, fmt.Errorf("migration %04d_%s has no down file", m.Version, m.Name)
+		}
+		err := run(db, m.down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+			return err
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("migration %04d_%s: %v", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// List คืนสถานะของ migration ทุกตัว
+func List(db *sql.DB, dialect string) ([]Status, error) {
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, m := range migrations {
+		at, ok := done[m.Version]
+		statuses = append(statuses, Status{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: at})
+	}
+	return statuses, nil
+}
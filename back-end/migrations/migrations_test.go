@@ -0,0 +1,142 @@
+package migrations_test
+
+import (
+	"clash_and_card/migrations"
+	"clash_and_card/store"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sqliteSchema คืนตาราง index และคอลัมน์ทั้งหมด ไว้เทียบว่า schema หลัง up ครั้งที่สองเหมือนครั้งแรก
+func sqliteSchema(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`
+		SELECT m.type || ' ' || m.name || ' ' || COALESCE(p.name, '') || ' ' || COALESCE(p.type, '')
+		FROM sqlite_master m LEFT JOIN pragma_table_info(m.name) p ON m.type = 'table'
+		WHERE m.name NOT LIKE 'sqlite_%' AND m.name <> 'schema_migrations'
+		ORDER BY m.type, m.name, p.cid`)
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	defer rows.Close()
+
+	var schema []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatalf("read schema: %v", err)
+		}
+		schema = append(schema, s)
+	}
+	return schema
+}
+
+func TestSQLiteUpDownUp(t *testing.T) {
+	st, err := store.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer st.Close()
+	db := st.DB()
+
+	ran, err := migrations.Up(db, "sqlite")
+	if err != nil {
+		t.Fatalf("first up: %v", err)
+	}
+	statuses, err := migrations.List(db, "sqlite")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(ran) == 0 || len(ran) != len(statuses) {
+		t.Fatalf("first up ran %d of %d migrations", len(ran), len(statuses))
+	}
+	schema := sqliteSchema(t, db)
+
+	// ไฟล์ที่มีหลายคำสั่งต้องรันครบทุกคำสั่ง ไม่ใช่แค่คำสั่งแรก
+	for _, table := range []string{"decks", "refresh_tokens", "tournament_matches"} {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&n); err != nil || n != 1 {
+			t.Fatalf("table %s missing after up (err %v)", table, err)
+		}
+	}
+
+	reverted, err := migrations.Down(db, "sqlite", len(ran))
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != len(ran) {
+		t.Fatalf("down reverted %d of %d migrations", len(reverted), len(ran))
+	}
+	if left := sqliteSchema(t, db); len(left) != 0 {
+		t.Fatalf("schema left after down: %v", left)
+	}
+
+	again, err := migrations.Up(db, "sqlite")
+	if err != nil {
+		t.Fatalf("second up: %v", err)
+	}
+	if len(again) != len(ran) {
+		t.Fatalf("second up ran %d of %d migrations", len(again), len(ran))
+	}
+	if got := sqliteSchema(t, db); !reflect.DeepEqual(got, schema) {
+		t.Fatalf("schema after second up differs\nfirst  %v\nsecond %v", schema, got)
+	}
+
+	if ran, err := migrations.Up(db, "sqlite"); err != nil || len(ran) != 0 {
+		t.Fatalf("up on current schema ran %d migrations (err %v)", len(ran), err)
+	}
+}
+
+func TestSQLiteTimestampsConvertBothWays(t *testing.T) {
+	st, err := store.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer st.Close()
+	db := st.DB()
+
+	if _, err := migrations.Up(db, "sqlite"); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	// ถอยกลับไปก่อน 0012 แล้วใส่ข้อมูลแบบ unix timestamp เดิม
+	if _, err := migrations.Down(db, "sqlite", 1); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	startsAt := time.Date(2026, 3, 1, 12, 30, 0, 0, time.Local)
+	if _, err := db.Exec(`INSERT INTO seasons (number, starts_at, ends_at) VALUES (1, ?, ?)`,
+		startsAt.Unix(), startsAt.Add(24*time.Hour).Unix()); err != nil {
+		t.Fatalf("insert season: %v", err)
+	}
+
+	if _, err := migrations.Up(db, "sqlite"); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	var stored string
+	if err := db.QueryRow(`SELECT starts_at FROM seasons WHERE number = 1`).Scan(&stored); err != nil {
+		t.Fatalf("read season: %v", err)
+	}
+	if want := startsAt.Format("2006-01-02 15:04:05"); stored != want {
+		t.Fatalf("starts_at = %q, want %q", stored, want)
+	}
+	season, err := st.Seasons().LatestSeason()
+	if err != nil {
+		t.Fatalf("LatestSeason: %v", err)
+	}
+	if !season.StartsAt.Equal(startsAt) || !season.EndsAt.Equal(startsAt.Add(24*time.Hour)) {
+		t.Fatalf("season = %v - %v, want %v", season.StartsAt, season.EndsAt, startsAt)
+	}
+
+	if _, err := migrations.Down(db, "sqlite", 1); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	var unix int64
+	if err := db.QueryRow(`SELECT starts_at FROM seasons WHERE number = 1`).Scan(&unix); err != nil {
+		t.Fatalf("read season: %v", err)
+	}
+	if unix != startsAt.Unix() {
+		t.Fatalf("starts_at after down = %d, want %d", unix, startsAt.Unix())
+	}
+}
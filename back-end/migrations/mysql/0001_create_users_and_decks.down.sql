@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS decks;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS users (
+    id                     VARCHAR(36)  NOT NULL PRIMARY KEY,
+    username               VARCHAR(64)  NOT NULL,
+    email                  VARCHAR(255) NOT NULL UNIQUE,
+    password               VARCHAR(255) NOT NULL,
+    atk                    INT          NOT NULL DEFAULT 0,
+    def                    INT          NOT NULL DEFAULT 0,
+    spd                    INT          NOT NULL DEFAULT 0,
+    hp                     INT          NOT NULL DEFAULT 0,
+    level                  INT          NOT NULL DEFAULT 1,
+    current_campaign_level INT          NOT NULL DEFAULT 1,
+    exp                    INT          NOT NULL DEFAULT 0,
+    gold                   INT          NOT NULL DEFAULT 0,
+    created_at             DATETIME     NOT NULL,
+    class                  VARCHAR(16)  NOT NULL,
+    stat_point             INT          NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS decks (
+    user_id   VARCHAR(36) NOT NULL,
+    card_type VARCHAR(16) NOT NULL,
+    quantity  INT         NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, card_type),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS matches (
+    id          VARCHAR(36)     NOT NULL PRIMARY KEY,
+    mode        VARCHAR(16)     NOT NULL,
+    seed        BIGINT UNSIGNED NOT NULL,
+    player_a_id VARCHAR(36)     NOT NULL,
+    player_b_id VARCHAR(36)     NULL,
+    player_a    TEXT            NOT NULL,
+    player_b    TEXT            NOT NULL,
+    status      VARCHAR(16)     NOT NULL,
+    result_a    VARCHAR(16)     NULL,
+    detail_a    VARCHAR(64)     NULL,
+    result_b    VARCHAR(16)     NULL,
+    detail_b    VARCHAR(64)     NULL,
+    created_at  DATETIME        NOT NULL,
+    ended_at    DATETIME        NULL
+);
+
+CREATE TABLE IF NOT EXISTS match_events (
+    id         BIGINT      NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    match_id   VARCHAR(36) NOT NULL,
+    round      INT         NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    payload    TEXT        NOT NULL,
+    created_at DATETIME    NOT NULL,
+    INDEX idx_match_events_match_id (match_id),
+    FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id         VARCHAR(36) NOT NULL PRIMARY KEY,
+    user_id    VARCHAR(36) NOT NULL,
+    created_at DATETIME    NOT NULL,
+    revoked_at DATETIME    NULL,
+    INDEX idx_sessions_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash CHAR(64)    NOT NULL PRIMARY KEY,
+    session_id VARCHAR(36) NOT NULL,
+    expires_at BIGINT      NOT NULL,
+    created_at DATETIME    NOT NULL,
+    used_at    DATETIME    NULL,
+    INDEX idx_refresh_tokens_session_id (session_id),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
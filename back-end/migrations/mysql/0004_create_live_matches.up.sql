@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS live_matches (
+    id         VARCHAR(64) NOT NULL PRIMARY KEY,
+    mode       VARCHAR(16) NOT NULL,
+    state      MEDIUMTEXT  NOT NULL,
+    updated_at DATETIME    NOT NULL
+);
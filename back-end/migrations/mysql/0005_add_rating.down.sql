@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS rating_history;
+ALTER TABLE users DROP COLUMN rating;
@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN rating INT NOT NULL DEFAULT 1000;
+
+CREATE TABLE IF NOT EXISTS rating_history (
+    id            BIGINT      NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id       VARCHAR(36) NOT NULL,
+    opponent_id   VARCHAR(36) NOT NULL,
+    match_id      VARCHAR(36) NOT NULL,
+    result        VARCHAR(16) NOT NULL,
+    rating_before INT         NOT NULL,
+    rating_after  INT         NOT NULL,
+    created_at    DATETIME    NOT NULL,
+    INDEX idx_rating_history_user_id (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS seasons (
+    id        BIGINT   NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    number    INT      NOT NULL UNIQUE,
+    starts_at BIGINT   NOT NULL,
+    ends_at   BIGINT   NOT NULL,
+    closed_at DATETIME NULL
+);
+
+CREATE TABLE IF NOT EXISTS season_standings (
+    season_id   BIGINT      NOT NULL,
+    user_id     VARCHAR(36) NOT NULL,
+    points      INT         NOT NULL DEFAULT 0,
+    wins        INT         NOT NULL DEFAULT 0,
+    losses      INT         NOT NULL DEFAULT 0,
+    draws       INT         NOT NULL DEFAULT 0,
+    placement   INT         NULL,
+    rewarded_at DATETIME    NULL,
+    PRIMARY KEY (season_id, user_id),
+    INDEX idx_season_standings_points (season_id, points),
+    FOREIGN KEY (season_id) REFERENCES seasons(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+DROP INDEX idx_users_best_campaign_win_streak ON users;
+DROP INDEX idx_users_gold ON users;
+DROP INDEX idx_users_level ON users;
+DROP INDEX idx_users_current_campaign_level ON users;
+
+ALTER TABLE users DROP COLUMN best_campaign_win_streak;
+ALTER TABLE users DROP COLUMN campaign_win_streak;
@@ -0,0 +1,7 @@
+ALTER TABLE users ADD COLUMN campaign_win_streak INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN best_campaign_win_streak INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_users_current_campaign_level ON users (current_campaign_level);
+CREATE INDEX idx_users_level ON users (level);
+CREATE INDEX idx_users_gold ON users (gold);
+CREATE INDEX idx_users_best_campaign_win_streak ON users (best_campaign_win_streak);
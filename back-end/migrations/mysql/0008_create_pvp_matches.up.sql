@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS pvp_matches (
+    id          VARCHAR(36) NOT NULL PRIMARY KEY,
+    room_id     VARCHAR(36) NOT NULL,
+    player_a_id VARCHAR(36) NOT NULL,
+    player_b_id VARCHAR(36) NOT NULL,
+    winner_id   VARCHAR(36),
+    reason      VARCHAR(16) NOT NULL,
+    ended_at    DATETIME    NOT NULL,
+    INDEX idx_pvp_matches_player_a_id (player_a_id),
+    INDEX idx_pvp_matches_player_b_id (player_b_id)
+);
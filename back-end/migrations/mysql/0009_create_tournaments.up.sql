@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS tournaments (
+    id            VARCHAR(36)  NOT NULL PRIMARY KEY,
+    name          VARCHAR(64)  NOT NULL,
+    format        VARCHAR(32)  NOT NULL,
+    status        VARCHAR(16)  NOT NULL,
+    best_of       INT          NOT NULL DEFAULT 1,
+    max_players   INT          NOT NULL,
+    rounds        INT          NOT NULL DEFAULT 0,
+    current_round INT          NOT NULL DEFAULT 0,
+    created_by    VARCHAR(36)  NOT NULL,
+    winner_id     VARCHAR(36)  NULL,
+    created_at    BIGINT       NOT NULL,
+    INDEX idx_tournaments_status (status),
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tournament_players (
+    tournament_id VARCHAR(36) NOT NULL,
+    user_id       VARCHAR(36) NOT NULL,
+    seed          INT         NOT NULL DEFAULT 0,
+    points        INT         NOT NULL DEFAULT 0,
+    wins          INT         NOT NULL DEFAULT 0,
+    losses        INT         NOT NULL DEFAULT 0,
+    draws         INT         NOT NULL DEFAULT 0,
+    eliminated    INT         NOT NULL DEFAULT 0,
+    registered_at BIGINT      NOT NULL,
+    PRIMARY KEY (tournament_id, user_id),
+    FOREIGN KEY (tournament_id) REFERENCES tournaments(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tournament_matches (
+    id            VARCHAR(36) NOT NULL PRIMARY KEY,
+    tournament_id VARCHAR(36) NOT NULL,
+    round         INT         NOT NULL,
+    table_no      INT         NOT NULL,
+    player_a_id   VARCHAR(36) NOT NULL,
+    player_b_id   VARCHAR(36) NULL,
+    room_id       VARCHAR(36) NULL,
+    result        VARCHAR(8)  NOT NULL DEFAULT '',
+    winner_id     VARCHAR(36) NULL,
+    INDEX idx_tournament_matches_round (tournament_id, round, table_no),
+    FOREIGN KEY (tournament_id) REFERENCES tournaments(id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS friendships (
+    requester_id VARCHAR(36) NOT NULL,
+    addressee_id VARCHAR(36) NOT NULL,
+    status       VARCHAR(16) NOT NULL,
+    created_at   BIGINT      NOT NULL,
+    accepted_at  BIGINT      NULL,
+    PRIMARY KEY (requester_id, addressee_id),
+    INDEX idx_friendships_addressee_id (addressee_id),
+    FOREIGN KEY (requester_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (addressee_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,34 @@
+ALTER TABLE refresh_tokens ADD COLUMN expires_at_old BIGINT NULL;
+UPDATE refresh_tokens SET expires_at_old = TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', expires_at) WHERE expires_at IS NOT NULL;
+ALTER TABLE refresh_tokens DROP COLUMN expires_at;
+ALTER TABLE refresh_tokens CHANGE COLUMN expires_at_old expires_at BIGINT NOT NULL;
+
+ALTER TABLE seasons ADD COLUMN starts_at_old BIGINT NULL;
+UPDATE seasons SET starts_at_old = TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', starts_at) WHERE starts_at IS NOT NULL;
+ALTER TABLE seasons DROP COLUMN starts_at;
+ALTER TABLE seasons CHANGE COLUMN starts_at_old starts_at BIGINT NOT NULL;
+
+ALTER TABLE seasons ADD COLUMN ends_at_old BIGINT NULL;
+UPDATE seasons SET ends_at_old = TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', ends_at) WHERE ends_at IS NOT NULL;
+ALTER TABLE seasons DROP COLUMN ends_at;
+ALTER TABLE seasons CHANGE COLUMN ends_at_old ends_at BIGINT NOT NULL;
+
+ALTER TABLE tournaments ADD COLUMN created_at_old BIGINT NULL;
+UPDATE tournaments SET created_at_old = TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', created_at) WHERE created_at IS NOT NULL;
+ALTER TABLE tournaments DROP COLUMN created_at;
+ALTER TABLE tournaments CHANGE COLUMN created_at_old created_at BIGINT NOT NULL;
+
+ALTER TABLE tournament_players ADD COLUMN registered_at_old BIGINT NULL;
+UPDATE tournament_players SET registered_at_old = TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', registered_at) WHERE registered_at IS NOT NULL;
+ALTER TABLE tournament_players DROP COLUMN registered_at;
+ALTER TABLE tournament_players CHANGE COLUMN registered_at_old registered_at BIGINT NOT NULL;
+
+ALTER TABLE friendships ADD COLUMN created_at_old BIGINT NULL;
+UPDATE friendships SET created_at_old = TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', created_at) WHERE created_at IS NOT NULL;
+ALTER TABLE friendships DROP COLUMN created_at;
+ALTER TABLE friendships CHANGE COLUMN created_at_old created_at BIGINT NOT NULL;
+
+ALTER TABLE friendships ADD COLUMN accepted_at_old BIGINT NULL;
+UPDATE friendships SET accepted_at_old = TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', accepted_at) WHERE accepted_at IS NOT NULL;
+ALTER TABLE friendships DROP COLUMN accepted_at;
+ALTER TABLE friendships CHANGE COLUMN accepted_at_old accepted_at BIGINT NULL;
@@ -0,0 +1,36 @@
+-- คอลัมน์เวลาเก็บเป็น DATETIME (UTC) แบบเดียวกับ users.created_at แทน unix timestamp
+
+ALTER TABLE refresh_tokens ADD COLUMN expires_at_new DATETIME NULL;
+UPDATE refresh_tokens SET expires_at_new = DATE_ADD('1970-01-01 00:00:00', INTERVAL expires_at SECOND) WHERE expires_at IS NOT NULL;
+ALTER TABLE refresh_tokens DROP COLUMN expires_at;
+ALTER TABLE refresh_tokens CHANGE COLUMN expires_at_new expires_at DATETIME NOT NULL;
+
+ALTER TABLE seasons ADD COLUMN starts_at_new DATETIME NULL;
+UPDATE seasons SET starts_at_new = DATE_ADD('1970-01-01 00:00:00', INTERVAL starts_at SECOND) WHERE starts_at IS NOT NULL;
+ALTER TABLE seasons DROP COLUMN starts_at;
+ALTER TABLE seasons CHANGE COLUMN starts_at_new starts_at DATETIME NOT NULL;
+
+ALTER TABLE seasons ADD COLUMN ends_at_new DATETIME NULL;
+UPDATE seasons SET ends_at_new = DATE_ADD('1970-01-01 00:00:00', INTERVAL ends_at SECOND) WHERE ends_at IS NOT NULL;
+ALTER TABLE seasons DROP COLUMN ends_at;
+ALTER TABLE seasons CHANGE COLUMN ends_at_new ends_at DATETIME NOT NULL;
+
+ALTER TABLE tournaments ADD COLUMN created_at_new DATETIME NULL;
+UPDATE tournaments SET created_at_new = DATE_ADD('1970-01-01 00:00:00', INTERVAL created_at SECOND) WHERE created_at IS NOT NULL;
+ALTER TABLE tournaments DROP COLUMN created_at;
+ALTER TABLE tournaments CHANGE COLUMN created_at_new created_at DATETIME NOT NULL;
+
+ALTER TABLE tournament_players ADD COLUMN registered_at_new DATETIME NULL;
+UPDATE tournament_players SET registered_at_new = DATE_ADD('1970-01-01 00:00:00', INTERVAL registered_at SECOND) WHERE registered_at IS NOT NULL;
+ALTER TABLE tournament_players DROP COLUMN registered_at;
+ALTER TABLE tournament_players CHANGE COLUMN registered_at_new registered_at DATETIME NOT NULL;
+
+ALTER TABLE friendships ADD COLUMN created_at_new DATETIME NULL;
+UPDATE friendships SET created_at_new = DATE_ADD('1970-01-01 00:00:00', INTERVAL created_at SECOND) WHERE created_at IS NOT NULL;
+ALTER TABLE friendships DROP COLUMN created_at;
+ALTER TABLE friendships CHANGE COLUMN created_at_new created_at DATETIME NOT NULL;
+
+ALTER TABLE friendships ADD COLUMN accepted_at_new DATETIME NULL;
+UPDATE friendships SET accepted_at_new = DATE_ADD('1970-01-01 00:00:00', INTERVAL accepted_at SECOND) WHERE accepted_at IS NOT NULL;
+ALTER TABLE friendships DROP COLUMN accepted_at;
+ALTER TABLE friendships CHANGE COLUMN accepted_at_new accepted_at DATETIME NULL;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS users (
+    id                     TEXT PRIMARY KEY,
+    username               TEXT NOT NULL,
+    email                  TEXT NOT NULL UNIQUE,
+    password               TEXT NOT NULL,
+    atk                    INTEGER NOT NULL DEFAULT 0,
+    def                    INTEGER NOT NULL DEFAULT 0,
+    spd                    INTEGER NOT NULL DEFAULT 0,
+    hp                     INTEGER NOT NULL DEFAULT 0,
+    level                  INTEGER NOT NULL DEFAULT 1,
+    current_campaign_level INTEGER NOT NULL DEFAULT 1,
+    exp                    INTEGER NOT NULL DEFAULT 0,
+    gold                   INTEGER NOT NULL DEFAULT 0,
+    created_at             TEXT NOT NULL,
+    class                  TEXT NOT NULL,
+    stat_point             INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS decks (
+    user_id   TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    card_type TEXT NOT NULL,
+    quantity  INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, card_type)
+);
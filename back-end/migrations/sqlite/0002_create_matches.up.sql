@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS matches (
+    id          TEXT PRIMARY KEY,
+    mode        TEXT NOT NULL,
+    seed        TEXT NOT NULL,
+    player_a_id TEXT NOT NULL,
+    player_b_id TEXT,
+    player_a    TEXT NOT NULL,
+    player_b    TEXT NOT NULL,
+    status      TEXT NOT NULL,
+    result_a    TEXT,
+    detail_a    TEXT,
+    result_b    TEXT,
+    detail_b    TEXT,
+    created_at  TEXT NOT NULL,
+    ended_at    TEXT
+);
+
+CREATE TABLE IF NOT EXISTS match_events (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    match_id   TEXT NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+    round      INTEGER NOT NULL,
+    event_type TEXT NOT NULL,
+    payload    TEXT NOT NULL,
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_events_match_id ON match_events (match_id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS live_matches (
+    id         TEXT PRIMARY KEY,
+    mode       TEXT NOT NULL,
+    state      TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
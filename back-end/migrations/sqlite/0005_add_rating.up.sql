@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN rating INTEGER NOT NULL DEFAULT 1000;
+
+CREATE TABLE IF NOT EXISTS rating_history (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id       TEXT    NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    opponent_id   TEXT    NOT NULL,
+    match_id      TEXT    NOT NULL,
+    result        TEXT    NOT NULL,
+    rating_before INTEGER NOT NULL,
+    rating_after  INTEGER NOT NULL,
+    created_at    TEXT    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rating_history_user_id ON rating_history (user_id);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS seasons (
+    id        INTEGER PRIMARY KEY AUTOINCREMENT,
+    number    INTEGER NOT NULL UNIQUE,
+    starts_at INTEGER NOT NULL,
+    ends_at   INTEGER NOT NULL,
+    closed_at TEXT
+);
+
+CREATE TABLE IF NOT EXISTS season_standings (
+    season_id   INTEGER NOT NULL REFERENCES seasons(id) ON DELETE CASCADE,
+    user_id     TEXT    NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    points      INTEGER NOT NULL DEFAULT 0,
+    wins        INTEGER NOT NULL DEFAULT 0,
+    losses      INTEGER NOT NULL DEFAULT 0,
+    draws       INTEGER NOT NULL DEFAULT 0,
+    placement   INTEGER,
+    rewarded_at TEXT,
+    PRIMARY KEY (season_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_season_standings_points ON season_standings (season_id, points);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_users_best_campaign_win_streak;
+DROP INDEX IF EXISTS idx_users_gold;
+DROP INDEX IF EXISTS idx_users_level;
+DROP INDEX IF EXISTS idx_users_current_campaign_level;
+
+ALTER TABLE users DROP COLUMN best_campaign_win_streak;
+ALTER TABLE users DROP COLUMN campaign_win_streak;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS pvp_matches (
+    id          TEXT PRIMARY KEY,
+    room_id     TEXT NOT NULL,
+    player_a_id TEXT NOT NULL,
+    player_b_id TEXT NOT NULL,
+    winner_id   TEXT,
+    reason      TEXT NOT NULL,
+    ended_at    TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pvp_matches_player_a_id ON pvp_matches (player_a_id);
+CREATE INDEX IF NOT EXISTS idx_pvp_matches_player_b_id ON pvp_matches (player_b_id);
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS tournaments (
+    id            TEXT    PRIMARY KEY,
+    name          TEXT    NOT NULL,
+    format        TEXT    NOT NULL,
+    status        TEXT    NOT NULL,
+    best_of       INTEGER NOT NULL DEFAULT 1,
+    max_players   INTEGER NOT NULL,
+    rounds        INTEGER NOT NULL DEFAULT 0,
+    current_round INTEGER NOT NULL DEFAULT 0,
+    created_by    TEXT    NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    winner_id     TEXT,
+    created_at    INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tournaments_status ON tournaments (status);
+
+CREATE TABLE IF NOT EXISTS tournament_players (
+    tournament_id TEXT    NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    user_id       TEXT    NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    seed          INTEGER NOT NULL DEFAULT 0,
+    points        INTEGER NOT NULL DEFAULT 0,
+    wins          INTEGER NOT NULL DEFAULT 0,
+    losses        INTEGER NOT NULL DEFAULT 0,
+    draws         INTEGER NOT NULL DEFAULT 0,
+    eliminated    INTEGER NOT NULL DEFAULT 0,
+    registered_at INTEGER NOT NULL,
+    PRIMARY KEY (tournament_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS tournament_matches (
+    id            TEXT    PRIMARY KEY,
+    tournament_id TEXT    NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    round         INTEGER NOT NULL,
+    table_no      INTEGER NOT NULL,
+    player_a_id   TEXT    NOT NULL,
+    player_b_id   TEXT,
+    room_id       TEXT,
+    result        TEXT    NOT NULL DEFAULT '',
+    winner_id     TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_tournament_matches_round ON tournament_matches (tournament_id, round, table_no);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS friendships (
+    requester_id TEXT    NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    addressee_id TEXT    NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status       TEXT    NOT NULL,
+    created_at   INTEGER NOT NULL,
+    accepted_at  INTEGER,
+    PRIMARY KEY (requester_id, addressee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_friendships_addressee_id ON friendships (addressee_id);
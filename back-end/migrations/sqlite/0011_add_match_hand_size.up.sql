@@ -0,0 +1 @@
+ALTER TABLE matches ADD COLUMN hand_size INTEGER NOT NULL DEFAULT 0;
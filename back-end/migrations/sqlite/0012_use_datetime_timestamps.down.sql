@@ -0,0 +1,34 @@
+ALTER TABLE refresh_tokens ADD COLUMN expires_at_old INTEGER NOT NULL DEFAULT 0;
+UPDATE refresh_tokens SET expires_at_old = CAST(strftime('%s', expires_at, 'utc') AS INTEGER) WHERE expires_at IS NOT NULL;
+ALTER TABLE refresh_tokens DROP COLUMN expires_at;
+ALTER TABLE refresh_tokens RENAME COLUMN expires_at_old TO expires_at;
+
+ALTER TABLE seasons ADD COLUMN starts_at_old INTEGER NOT NULL DEFAULT 0;
+UPDATE seasons SET starts_at_old = CAST(strftime('%s', starts_at, 'utc') AS INTEGER) WHERE starts_at IS NOT NULL;
+ALTER TABLE seasons DROP COLUMN starts_at;
+ALTER TABLE seasons RENAME COLUMN starts_at_old TO starts_at;
+
+ALTER TABLE seasons ADD COLUMN ends_at_old INTEGER NOT NULL DEFAULT 0;
+UPDATE seasons SET ends_at_old = CAST(strftime('%s', ends_at, 'utc') AS INTEGER) WHERE ends_at IS NOT NULL;
+ALTER TABLE seasons DROP COLUMN ends_at;
+ALTER TABLE seasons RENAME COLUMN ends_at_old TO ends_at;
+
+ALTER TABLE tournaments ADD COLUMN created_at_old INTEGER NOT NULL DEFAULT 0;
+UPDATE tournaments SET created_at_old = CAST(strftime('%s', created_at, 'utc') AS INTEGER) WHERE created_at IS NOT NULL;
+ALTER TABLE tournaments DROP COLUMN created_at;
+ALTER TABLE tournaments RENAME COLUMN created_at_old TO created_at;
+
+ALTER TABLE tournament_players ADD COLUMN registered_at_old INTEGER NOT NULL DEFAULT 0;
+UPDATE tournament_players SET registered_at_old = CAST(strftime('%s', registered_at, 'utc') AS INTEGER) WHERE registered_at IS NOT NULL;
+ALTER TABLE tournament_players DROP COLUMN registered_at;
+ALTER TABLE tournament_players RENAME COLUMN registered_at_old TO registered_at;
+
+ALTER TABLE friendships ADD COLUMN created_at_old INTEGER NOT NULL DEFAULT 0;
+UPDATE friendships SET created_at_old = CAST(strftime('%s', created_at, 'utc') AS INTEGER) WHERE created_at IS NOT NULL;
+ALTER TABLE friendships DROP COLUMN created_at;
+ALTER TABLE friendships RENAME COLUMN created_at_old TO created_at;
+
+ALTER TABLE friendships ADD COLUMN accepted_at_old INTEGER;
+UPDATE friendships SET accepted_at_old = CAST(strftime('%s', accepted_at, 'utc') AS INTEGER) WHERE accepted_at IS NOT NULL;
+ALTER TABLE friendships DROP COLUMN accepted_at;
+ALTER TABLE friendships RENAME COLUMN accepted_at_old TO accepted_at;
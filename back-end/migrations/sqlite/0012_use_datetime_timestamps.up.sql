@@ -0,0 +1,36 @@
+-- คอลัมน์เวลาเก็บเป็น TEXT (เวลาท้องถิ่น 'YYYY-MM-DD HH:MM:SS') แบบเดียวกับ users.created_at แทน unix timestamp
+
+ALTER TABLE refresh_tokens ADD COLUMN expires_at_new TEXT NOT NULL DEFAULT '';
+UPDATE refresh_tokens SET expires_at_new = datetime(expires_at, 'unixepoch', 'localtime') WHERE expires_at IS NOT NULL;
+ALTER TABLE refresh_tokens DROP COLUMN expires_at;
+ALTER TABLE refresh_tokens RENAME COLUMN expires_at_new TO expires_at;
+
+ALTER TABLE seasons ADD COLUMN starts_at_new TEXT NOT NULL DEFAULT '';
+UPDATE seasons SET starts_at_new = datetime(starts_at, 'unixepoch', 'localtime') WHERE starts_at IS NOT NULL;
+ALTER TABLE seasons DROP COLUMN starts_at;
+ALTER TABLE seasons RENAME COLUMN starts_at_new TO starts_at;
+
+ALTER TABLE seasons ADD COLUMN ends_at_new TEXT NOT NULL DEFAULT '';
+UPDATE seasons SET ends_at_new = datetime(ends_at, 'unixepoch', 'localtime') WHERE ends_at IS NOT NULL;
+ALTER TABLE seasons DROP COLUMN ends_at;
+ALTER TABLE seasons RENAME COLUMN ends_at_new TO ends_at;
+
+ALTER TABLE tournaments ADD COLUMN created_at_new TEXT NOT NULL DEFAULT '';
+UPDATE tournaments SET created_at_new = datetime(created_at, 'unixepoch', 'localtime') WHERE created_at IS NOT NULL;
+ALTER TABLE tournaments DROP COLUMN created_at;
+ALTER TABLE tournaments RENAME COLUMN created_at_new TO created_at;
+
+ALTER TABLE tournament_players ADD COLUMN registered_at_new TEXT NOT NULL DEFAULT '';
+UPDATE tournament_players SET registered_at_new = datetime(registered_at, 'unixepoch', 'localtime') WHERE registered_at IS NOT NULL;
+ALTER TABLE tournament_players DROP COLUMN registered_at;
+ALTER TABLE tournament_players RENAME COLUMN registered_at_new TO registered_at;
+
+ALTER TABLE friendships ADD COLUMN created_at_new TEXT NOT NULL DEFAULT '';
+UPDATE friendships SET created_at_new = datetime(created_at, 'unixepoch', 'localtime') WHERE created_at IS NOT NULL;
+ALTER TABLE friendships DROP COLUMN created_at;
+ALTER TABLE friendships RENAME COLUMN created_at_new TO created_at;
+
+ALTER TABLE friendships ADD COLUMN accepted_at_new TEXT;
+UPDATE friendships SET accepted_at_new = datetime(accepted_at, 'unixepoch', 'localtime') WHERE accepted_at IS NOT NULL;
+ALTER TABLE friendships DROP COLUMN accepted_at;
+ALTER TABLE friendships RENAME COLUMN accepted_at_new TO accepted_at;
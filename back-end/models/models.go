@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type UnitStat struct {
 	Atk int `json:"atk"`
 	Def int `json:"def"`
@@ -18,8 +20,16 @@ type User struct {
 	Gold                 int      `json:"gold"`
 	CreatedAt            string   `json:"createdAt"`
 	Class                string   `json:"class"`
+	StatPoint            int      `json:"statPoint"`
+	Rating               int      `json:"rating"` // rating PvP แก้ผ่าน RatingRepository เท่านั้น
+	// CampaignWinStreak จำนวน campaign ที่ชนะติดกันตอนนี้ แพ้ เสมอ หรือทิ้งเกมจะกลับเป็น 0
+	CampaignWinStreak     int `json:"campaignWinStreak"`
+	BestCampaignWinStreak int `json:"bestCampaignWinStreak"`
 }
 
+// InitialRating rating ของผู้เล่นใหม่ (ตรงกับค่า default ของคอลัมน์ users.rating)
+const InitialRating = 1000
+
 type DeckCard struct {
 	CardType string `json:"card_type"`
 	Quantity int    `json:"quantity"`
@@ -33,3 +43,161 @@ type PostGameDetail struct {
 	LvlUp    int      `json:"lvlUp"`
 	StatGain UnitStat `json:"statGain"`
 }
+
+// MatchRecord ข้อมูล match หนึ่งเกมที่บันทึกไว้สำหรับ replay
+// PlayerA / PlayerB เป็น JSON ของผู้เล่นตอนเริ่มเกม
+type MatchRecord struct {
+	ID        string
+	Mode      string
+	Seed      string
+	PlayerAID string
+	PlayerBID string
+	PlayerA   string
+	PlayerB   string
+	HandSize  int // จำนวนการ์ดในมือตามกติกาของห้อง 0 คือ engine.HandSize
+	Status    string
+	ResultA   string
+	DetailA   string
+	ResultB   string
+	DetailB   string
+	CreatedAt string
+	EndedAt   string
+}
+
+type MatchEvent struct {
+	Round     int
+	Type      string
+	Payload   string
+	CreatedAt string
+}
+
+// RatingChange การเปลี่ยน rating ของผู้เล่นหนึ่งคนจาก match PvP หนึ่งเกม
+type RatingChange struct {
+	UserID     string `json:"-"`
+	OpponentID string `json:"opponentID"`
+	MatchID    string `json:"matchID"`
+	Result     string `json:"result"` // "Win", "Lose" หรือ "Draw" จากมุมมองของ UserID
+	Before     int    `json:"before"`
+	After      int    `json:"after"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// PVPMatchResult ผลของ match PvP ที่จบแล้ว หนึ่งแถวต่อ match
+type PVPMatchResult struct {
+	ID        string `json:"id"` // match id เดียวกับ MatchRecord
+	RoomID    string `json:"roomID"`
+	PlayerAID string `json:"playerAID"`
+	PlayerBID string `json:"playerBID"`
+	WinnerID  string `json:"winnerID"` // ว่างถ้าเสมอ
+	Reason    string `json:"reason"`   // "hp", "deck", "disconnect" หรือ "timeout"
+	EndedAt   string `json:"endedAt"`
+}
+
+// Season ฤดูกาลแรงก์ PvP หนึ่งฤดูกาล
+type Season struct {
+	ID       int64     `json:"id"`
+	Number   int       `json:"number"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+	Closed   bool      `json:"closed"`
+}
+
+// SeasonStanding คะแนนและอันดับของผู้เล่นหนึ่งคนในฤดูกาล
+type SeasonStanding struct {
+	SeasonID  int64  `json:"-"`
+	UserID    string `json:"userID"`
+	Username  string `json:"username"`
+	Points    int    `json:"points"`
+	Wins      int    `json:"wins"`
+	Losses    int    `json:"losses"`
+	Draws     int    `json:"draws"`
+	Placement int    `json:"placement"`
+	Rewarded  bool   `json:"rewarded"`
+}
+
+// Tournament การแข่งขันแบบ single elimination หรือ Swiss ที่จัดบน PvP
+type Tournament struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Format       string    `json:"format"` // "single_elimination" หรือ "swiss"
+	Status       string    `json:"status"` // "registration", "running" หรือ "finished"
+	BestOf       int       `json:"bestOf"` // จำนวนเกมสูงสุดต่อคู่
+	MaxPlayers   int       `json:"maxPlayers"`
+	Rounds       int       `json:"rounds"` // จำนวนรอบทั้งหมด กำหนดตอนเริ่ม (Swiss ตั้งเองได้)
+	CurrentRound int       `json:"currentRound"`
+	CreatedBy    string    `json:"createdBy"`
+	WinnerID     string    `json:"winnerID,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// TournamentPlayer ผู้เล่นที่สมัครในการแข่งขันพร้อมผลรวม Seed เป็น 0 จนกว่าการแข่งขันจะเริ่ม
+type TournamentPlayer struct {
+	TournamentID string    `json:"-"`
+	UserID       string    `json:"userID"`
+	Username     string    `json:"username"`
+	Seed         int       `json:"seed"`
+	Points       int       `json:"points"`
+	Wins         int       `json:"wins"`
+	Losses       int       `json:"losses"`
+	Draws        int       `json:"draws"`
+	Eliminated   bool      `json:"eliminated"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+// TournamentMatch คู่หนึ่งในรอบของการแข่งขัน PlayerBID ว่างคือได้ bye
+type TournamentMatch struct {
+	ID           string `json:"id"`
+	TournamentID string `json:"-"`
+	Round        int    `json:"round"`
+	Table        int    `json:"table"`
+	PlayerAID    string `json:"playerAID"`
+	PlayerBID    string `json:"playerBID,omitempty"`
+	RoomID       string `json:"roomID,omitempty"`
+	Result       string `json:"result"` // ว่างคือยังไม่จบ "A", "B", "draw" หรือ "bye"
+	WinnerID     string `json:"winnerID,omitempty"`
+}
+
+// สถานะของ Friendship
+const (
+	FriendPending  = "pending"
+	FriendAccepted = "accepted"
+)
+
+// Friendship ความสัมพันธ์ระหว่างผู้เล่นสองคน RequesterID คือคนที่ส่งคำขอ
+type Friendship struct {
+	RequesterID string    `json:"requesterID"`
+	AddresseeID string    `json:"addresseeID"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Friend เพื่อนหรือคำขอเป็นเพื่อนหนึ่งรายการจากมุมมองของผู้เล่น
+// Outgoing คือผู้เล่นเป็นคนส่งคำขอ Since คือเวลาที่เป็นเพื่อนกัน (หรือเวลาที่ส่งคำขอถ้ายังรออยู่)
+type Friend struct {
+	UserID   string    `json:"userID"`
+	Username string    `json:"username"`
+	Level    int       `json:"level"`
+	Class    string    `json:"class"`
+	Status   string    `json:"status"`
+	Outgoing bool      `json:"outgoing"`
+	Since    time.Time `json:"since"`
+	Presence string    `json:"presence,omitempty"` // กรอกโดย package battle จาก connection ที่เปิดอยู่
+}
+
+// LeaderboardEntry ผู้เล่นหนึ่งแถวใน leaderboard Value คือค่าที่ใช้จัดอันดับของ board นั้น
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	UserID   string `json:"userID"`
+	Username string `json:"username"`
+	Class    string `json:"class"`
+	Level    int    `json:"level"`
+	Value    int    `json:"value"`
+}
+
+// LiveMatch snapshot ล่าสุดของ match ที่ยังเล่นอยู่ ใช้โหลดกลับหลังรีสตาร์ทเซิร์ฟเวอร์
+type LiveMatch struct {
+	ID        string
+	Mode      string
+	State     string // JSON ของสถานะเกม
+	UpdatedAt string
+}
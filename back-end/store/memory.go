@@ -0,0 +1,925 @@
+package store
+
+import (
+	"clash_and_card/models"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const memoryTimeFormat = "2006-01-02 15:04:05"
+
+// MemoryStore เก็บข้อมูลทั้งหมดใน map ใช้สำหรับเทสและรันในเครื่องโดยไม่ต้องมีฐานข้อมูล
+// ข้อมูลหายเมื่อปิดโปรแกรม
+type MemoryStore struct {
+	mu          sync.Mutex
+	users       map[string]*memoryUser
+	emails      map[string]string         // email -> user id
+	decks       map[string]map[string]int // user id -> card type -> quantity
+	matches     map[string]*models.MatchRecord
+	matchEvents map[string][]models.MatchEvent
+	sessions    map[string]*memorySession
+	tokens      map[string]*memoryRefreshToken // token hash -> token
+	liveMatches map[string]models.LiveMatch
+	ratings     map[string][]models.RatingChange // user id -> ประวัติ เก่าก่อน
+	pvpMatches  map[string]models.PVPMatchResult
+	seasons     []*memorySeason // เรียงตามหมายเลขฤดูกาล
+	tournaments map[string]*memoryTournament
+	friendships map[[2]string]*memoryFriendship // [requester, addressee] -> ความสัมพันธ์
+}
+
+type memoryUser struct {
+	user         models.User
+	passwordHash string
+}
+
+type memorySeason struct {
+	season    models.Season
+	standings map[string]*models.SeasonStanding // user id -> คะแนน
+}
+
+type memoryTournament struct {
+	tournament models.Tournament
+	players    []models.TournamentPlayer // เรียงตามลำดับที่สมัคร
+	matches    []models.TournamentMatch  // เรียงตามรอบและโต๊ะ
+}
+
+type memoryFriendship struct {
+	friendship models.Friendship
+	since      time.Time
+}
+
+type memorySession struct {
+	userID  string
+	revoked bool
+}
+
+type memoryRefreshToken struct {
+	sessionID string
+	expiresAt time.Time
+	used      bool
+}
+
+func NewMemory() *MemoryStore {
+	return &MemoryStore{
+		users:       make(map[string]*memoryUser),
+		emails:      make(map[string]string),
+		decks:       make(map[string]map[string]int),
+		matches:     make(map[string]*models.MatchRecord),
+		matchEvents: make(map[string][]models.MatchEvent),
+		sessions:    make(map[string]*memorySession),
+		tokens:      make(map[string]*memoryRefreshToken),
+		liveMatches: make(map[string]models.LiveMatch),
+		ratings:     make(map[string][]models.RatingChange),
+		pvpMatches:  make(map[string]models.PVPMatchResult),
+		tournaments: make(map[string]*memoryTournament),
+		friendships: make(map[[2]string]*memoryFriendship),
+	}
+}
+
+func (s *MemoryStore) Users() UserRepository               { return memoryUsers{s} }
+func (s *MemoryStore) Decks() DeckRepository               { return memoryDecks{s} }
+func (s *MemoryStore) Wallets() WalletRepository           { return memoryWallets{s} }
+func (s *MemoryStore) Matches() MatchRepository            { return memoryMatches{s} }
+func (s *MemoryStore) Sessions() SessionRepository         { return memorySessions{s} }
+func (s *MemoryStore) LiveMatches() LiveMatchRepository    { return memoryLiveMatches{s} }
+func (s *MemoryStore) Ratings() RatingRepository           { return memoryRatings{s} }
+func (s *MemoryStore) PVPMatches() PVPMatchRepository      { return memoryPVPMatches{s} }
+func (s *MemoryStore) Seasons() SeasonRepository           { return memorySeasons{s} }
+func (s *MemoryStore) Tournaments() TournamentRepository   { return memoryTournaments{s} }
+func (s *MemoryStore) Friends() FriendRepository           { return memoryFriends{s} }
+func (s *MemoryStore) Leaderboards() LeaderboardRepository { return memoryLeaderboards{s} }
+func (s *MemoryStore) Close() error                        { return nil }
+
+// ----------- users -----------
+
+type memoryUsers struct {
+	s *MemoryStore
+}
+
+func (r memoryUsers) GetUser(id string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	mu, ok := r.s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	u := mu.user
+	return &u, nil
+}
+
+func (r memoryUsers) GetCredentials(email string) (string, string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	id, ok := r.s.emails[email]
+	if !ok {
+		return "", "", ErrNotFound
+	}
+	return id, r.s.users[id].passwordHash, nil
+}
+
+func (r memoryUsers) EmailExists(email string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	_, ok := r.s.emails[email]
+	return ok, nil
+}
+
+func (r memoryUsers) CreateUser(u *models.User, passwordHash string, deck []models.DeckCard) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored := *u
+	stored.CreatedAt = time.Now().Format(memoryTimeFormat)
+	r.s.users[u.ID] = &memoryUser{user: stored, passwordHash: passwordHash}
+	r.s.emails[u.Email] = u.ID
+
+	cards := make(map[string]int)
+	for _, card := range deck {
+		cards[card.CardType] += card.Quantity
+	}
+	r.s.decks[u.ID] = cards
+	return nil
+}
+
+func (r memoryUsers) UpdateUser(id string, fn func(u *models.User) error) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	mu, ok := r.s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	u := mu.user
+	if err := fn(&u); err != nil {
+		return err
+	}
+	u.ID = id
+	u.Rating = mu.user.Rating // เหมือน SQL: rating แก้ผ่าน RatingRepository เท่านั้น
+	mu.user = u
+	return nil
+}
+
+// ----------- decks -----------
+
+type memoryDecks struct {
+	s *MemoryStore
+}
+
+func (r memoryDecks) GetDeck(userID string) ([]models.DeckCard, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var deck []models.DeckCard
+	for cardType, quantity := range r.s.decks[userID] {
+		deck = append(deck, models.DeckCard{CardType: cardType, Quantity: quantity})
+	}
+	sort.Slice(deck, func(i, j int) bool { return deck[i].CardType < deck[j].CardType })
+	return deck, nil
+}
+
+// ----------- wallets -----------
+
+type memoryWallets struct {
+	s *MemoryStore
+}
+
+func (r memoryWallets) BuyCard(userID, cardType string, price int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	mu, ok := r.s.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	if mu.user.Gold < price {
+		return ErrNotEnoughGold
+	}
+
+	mu.user.Gold -= price
+	if r.s.decks[userID] == nil {
+		r.s.decks[userID] = make(map[string]int)
+	}
+	r.s.decks[userID][cardType]++
+	return nil
+}
+
+// ----------- matches -----------
+
+type memoryMatches struct {
+	s *MemoryStore
+}
+
+func (r memoryMatches) CreateMatch(m *models.MatchRecord) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored := *m
+	stored.CreatedAt = time.Now().Format(memoryTimeFormat)
+	r.s.matches[m.ID] = &stored
+	return nil
+}
+
+func (r memoryMatches) AppendMatchEvent(matchID string, round int, eventType string, payload string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.matchEvents[matchID] = append(r.s.matchEvents[matchID], models.MatchEvent{
+		Round:     round,
+		Type:      eventType,
+		Payload:   payload,
+		CreatedAt: time.Now().Format(memoryTimeFormat),
+	})
+	return nil
+}
+
+func (r memoryMatches) FinishMatch(matchID, status, resultA, detailA, resultB, detailB string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	m, ok := r.s.matches[matchID]
+	if !ok {
+		return ErrNotFound
+	}
+	m.Status = status
+	m.ResultA, m.DetailA = resultA, detailA
+	m.ResultB, m.DetailB = resultB, detailB
+	m.EndedAt = time.Now().Format(memoryTimeFormat)
+	return nil
+}
+
+func (r memoryMatches) GetMatch(matchID string) (*models.MatchRecord, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	m, ok := r.s.matches[matchID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *m
+	return &copied, nil
+}
+
+func (r memoryMatches) ListMatchEvents(matchID string) ([]models.MatchEvent, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return append([]models.MatchEvent(nil), r.s.matchEvents[matchID]...), nil
+}
+
+// ----------- sessions -----------
+
+type memorySessions struct {
+	s *MemoryStore
+}
+
+func (r memorySessions) CreateSession(sessionID, userID, tokenHash string, expiresAt time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.sessions[sessionID] = &memorySession{userID: userID}
+	r.s.tokens[tokenHash] = &memoryRefreshToken{sessionID: sessionID, expiresAt: expiresAt}
+	return nil
+}
+
+func (r memorySessions) RotateRefreshToken(oldHash, newHash string, expiresAt time.Time) (string, string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tokens[oldHash]
+	if !ok {
+		return "", "", ErrNotFound
+	}
+	sess := r.s.sessions[t.sessionID]
+
+	switch {
+	case sess.revoked:
+		return "", "", ErrSessionRevoked
+	case t.used:
+		sess.revoked = true
+		return "", "", ErrTokenReused
+	case !time.Now().Before(t.expiresAt):
+		return "", "", ErrTokenExpired
+	}
+
+	t.used = true
+	r.s.tokens[newHash] = &memoryRefreshToken{sessionID: t.sessionID, expiresAt: expiresAt}
+	return t.sessionID, sess.userID, nil
+}
+
+func (r memorySessions) RevokeSessionByToken(tokenHash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t, ok := r.s.tokens[tokenHash]
+	if !ok {
+		return ErrNotFound
+	}
+	r.s.sessions[t.sessionID].revoked = true
+	return nil
+}
+
+func (r memorySessions) SessionActive(sessionID string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	sess, ok := r.s.sessions[sessionID]
+	return ok && !sess.revoked, nil
+}
+
+// ----------- live matches -----------
+
+type memoryLiveMatches struct {
+	s *MemoryStore
+}
+
+func (r memoryLiveMatches) SaveLiveMatch(m *models.LiveMatch) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored := *m
+	stored.UpdatedAt = time.Now().Format(memoryTimeFormat)
+	r.s.liveMatches[m.ID] = stored
+	return nil
+}
+
+func (r memoryLiveMatches) DeleteLiveMatch(id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.liveMatches, id)
+	return nil
+}
+
+func (r memoryLiveMatches) ListLiveMatches() ([]models.LiveMatch, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var matches []models.LiveMatch
+	for _, m := range r.s.liveMatches {
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// ----------- ratings -----------
+
+type memoryRatings struct {
+	s *MemoryStore
+}
+
+func (r memoryRatings) UpdateRatings(matchID, userAID, userBID string, fn func(a, b *models.RatingChange)) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	userA, okA := r.s.users[userAID]
+	userB, okB := r.s.users[userBID]
+	if !okA || !okB {
+		return ErrNotFound
+	}
+
+	a := &models.RatingChange{UserID: userAID, OpponentID: userBID, MatchID: matchID, Before: userA.user.Rating}
+	b := &models.RatingChange{UserID: userBID, OpponentID: userAID, MatchID: matchID, Before: userB.user.Rating}
+	fn(a, b)
+
+	now := time.Now().Format(memoryTimeFormat)
+	for _, c := range []*models.RatingChange{a, b} {
+		c.CreatedAt = now
+		r.s.users[c.UserID].user.Rating = c.After
+		r.s.ratings[c.UserID] = append(r.s.ratings[c.UserID], *c)
+	}
+	return nil
+}
+
+func (r memoryRatings) RatingHistory(userID string, limit int) ([]models.RatingChange, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	all := r.s.ratings[userID]
+	var history []models.RatingChange
+	for i := len(all) - 1; i >= 0 && len(history) < limit; i-- {
+		history = append(history, all[i])
+	}
+	return history, nil
+}
+
+// ----------- pvp matches -----------
+
+type memoryPVPMatches struct {
+	s *MemoryStore
+}
+
+func (r memoryPVPMatches) RecordPVPResult(m *models.PVPMatchResult, fn func(slot string, u *models.User) error) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, exists := r.s.pvpMatches[m.ID]; exists {
+		return fmt.Errorf("pvp match %s already recorded", m.ID)
+	}
+	userA, okA := r.s.users[m.PlayerAID]
+	userB, okB := r.s.users[m.PlayerBID]
+	if !okA || !okB {
+		return ErrNotFound
+	}
+
+	// แก้สำเนาก่อน บันทึกเมื่อ fn ผ่านทั้งสองคนเท่านั้น
+	a, b := userA.user, userB.user
+	if err := fn("A", &a); err != nil {
+		return err
+	}
+	if err := fn("B", &b); err != nil {
+		return err
+	}
+	a.ID, a.Rating = userA.user.ID, userA.user.Rating
+	b.ID, b.Rating = userB.user.ID, userB.user.Rating
+	userA.user, userB.user = a, b
+
+	stored := *m
+	stored.EndedAt = time.Now().Format(memoryTimeFormat)
+	r.s.pvpMatches[m.ID] = stored
+	return nil
+}
+
+// ----------- seasons -----------
+
+type memorySeasons struct {
+	s *MemoryStore
+}
+
+func (r memorySeasons) find(seasonID int64) (*memorySeason, error) {
+	for _, ms := range r.s.seasons {
+		if ms.season.ID == seasonID {
+			return ms, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// ranked คืนคะแนนทุกคนเรียงตามอันดับ (ลำดับเดียวกับ SQL) พร้อมกรอก Username และ Placement
+func (r memorySeasons) ranked(ms *memorySeason) []models.SeasonStanding {
+	standings := make([]models.SeasonStanding, 0, len(ms.standings))
+	for _, st := range ms.standings {
+		c := *st
+		c.Username = r.s.users[c.UserID].user.Username
+		standings = append(standings, c)
+	}
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		return a.UserID < b.UserID
+	})
+	for i := range standings {
+		standings[i].Placement = i + 1
+	}
+	return standings
+}
+
+func (r memorySeasons) LatestSeason() (*models.Season, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if len(r.s.seasons) == 0 {
+		return nil, ErrNotFound
+	}
+	s := r.s.seasons[len(r.s.seasons)-1].season
+	return &s, nil
+}
+
+func (r memorySeasons) CreateSeason(s *models.Season) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	s.ID = int64(len(r.s.seasons) + 1)
+	r.s.seasons = append(r.s.seasons, &memorySeason{season: *s, standings: make(map[string]*models.SeasonStanding)})
+	return nil
+}
+
+func (r memorySeasons) AddSeasonResult(seasonID int64, userID, result string, points int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ms, err := r.find(seasonID)
+	if err != nil {
+		return err
+	}
+	st, ok := ms.standings[userID]
+	if !ok {
+		st = &models.SeasonStanding{SeasonID: seasonID, UserID: userID}
+		ms.standings[userID] = st
+	}
+
+	switch result {
+	case "Win":
+		st.Wins++
+	case "Lose":
+		st.Losses++
+	case "Draw":
+		st.Draws++
+	default:
+		return fmt.Errorf("unknown result %q", result)
+	}
+	st.Points = max(st.Points+points, 0)
+	return nil
+}
+
+func (r memorySeasons) SeasonStandings(seasonID int64, offset, limit int) ([]models.SeasonStanding, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ms, err := r.find(seasonID)
+	if err != nil {
+		return nil, nil
+	}
+	standings := r.ranked(ms)
+	if offset >= len(standings) {
+		return nil, nil
+	}
+	return standings[offset:min(offset+limit, len(standings))], nil
+}
+
+func (r memorySeasons) SeasonStanding(seasonID int64, userID string) (*models.SeasonStanding, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ms, err := r.find(seasonID)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range r.ranked(ms) {
+		if st.UserID == userID {
+			return &st, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memorySeasons) CloseSeason(seasonID int64, initialRating int, keep float64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ms, err := r.find(seasonID)
+	if err != nil {
+		return err
+	}
+	if ms.season.Closed {
+		return nil
+	}
+
+	for _, st := range r.ranked(ms) {
+		ms.standings[st.UserID].Placement = st.Placement
+	}
+	for _, mu := range r.s.users {
+		mu.user.Rating = initialRating + int(math.Round(float64(mu.user.Rating-initialRating)*keep))
+	}
+	ms.season.Closed = true
+	return nil
+}
+
+func (r memorySeasons) GrantSeasonReward(seasonID int64, userID string, cards []models.DeckCard, fn func(u *models.User) error) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ms, err := r.find(seasonID)
+	if err != nil {
+		return err
+	}
+	st, ok := ms.standings[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	if st.Rewarded {
+		return nil
+	}
+
+	mu, ok := r.s.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	u := mu.user
+	if err := fn(&u); err != nil {
+		return err
+	}
+	u.ID, u.Rating = userID, mu.user.Rating
+	mu.user = u
+
+	if r.s.decks[userID] == nil {
+		r.s.decks[userID] = make(map[string]int)
+	}
+	for _, card := range cards {
+		r.s.decks[userID][card.CardType] += card.Quantity
+	}
+	st.Rewarded = true
+	return nil
+}
+
+// ----------- tournaments -----------
+
+type memoryTournaments struct {
+	s *MemoryStore
+}
+
+func (r memoryTournaments) CreateTournament(t *models.Tournament) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, exists := r.s.tournaments[t.ID]; exists {
+		return fmt.Errorf("tournament %s already exists", t.ID)
+	}
+	r.s.tournaments[t.ID] = &memoryTournament{tournament: *t}
+	return nil
+}
+
+func (r memoryTournaments) GetTournament(id string) (*models.Tournament, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	mt, ok := r.s.tournaments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	t := mt.tournament
+	return &t, nil
+}
+
+func (r memoryTournaments) ListTournaments(status string) ([]models.Tournament, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var tournaments []models.Tournament
+	for _, mt := range r.s.tournaments {
+		if mt.tournament.Status == status {
+			tournaments = append(tournaments, mt.tournament)
+		}
+	}
+	sort.Slice(tournaments, func(i, j int) bool {
+		if !tournaments[i].CreatedAt.Equal(tournaments[j].CreatedAt) {
+			return tournaments[i].CreatedAt.After(tournaments[j].CreatedAt)
+		}
+		return tournaments[i].ID < tournaments[j].ID
+	})
+	return tournaments, nil
+}
+
+func (r memoryTournaments) AddTournamentPlayer(tournamentID, userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	mt, ok := r.s.tournaments[tournamentID]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, ok := r.s.users[userID]; !ok {
+		return ErrNotFound
+	}
+	for _, p := range mt.players {
+		if p.UserID == userID {
+			return fmt.Errorf("user %s already registered", userID)
+		}
+	}
+	mt.players = append(mt.players, models.TournamentPlayer{TournamentID: tournamentID, UserID: userID, RegisteredAt: time.Now()})
+	return nil
+}
+
+func (r memoryTournaments) ListTournamentPlayers(tournamentID string) ([]models.TournamentPlayer, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	mt, ok := r.s.tournaments[tournamentID]
+	if !ok {
+		return nil, nil
+	}
+	players := make([]models.TournamentPlayer, len(mt.players))
+	copy(players, mt.players)
+	for i := range players {
+		players[i].Username = r.s.users[players[i].UserID].user.Username
+	}
+	return players, nil
+}
+
+func (r memoryTournaments) ListTournamentMatches(tournamentID string) ([]models.TournamentMatch, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	mt, ok := r.s.tournaments[tournamentID]
+	if !ok {
+		return nil, nil
+	}
+	matches := make([]models.TournamentMatch, len(mt.matches))
+	copy(matches, mt.matches)
+	return matches, nil
+}
+
+func (r memoryTournaments) SaveTournament(t *models.Tournament, players []models.TournamentPlayer, finished, created []models.TournamentMatch) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	mt, ok := r.s.tournaments[t.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	mt.tournament.Status = t.Status
+	mt.tournament.Rounds = t.Rounds
+	mt.tournament.CurrentRound = t.CurrentRound
+	mt.tournament.WinnerID = t.WinnerID
+	for _, p := range players {
+		for i := range mt.players {
+			if mt.players[i].UserID == p.UserID {
+				stored := mt.players[i]
+				stored.Seed, stored.Points, stored.Wins, stored.Losses, stored.Draws, stored.Eliminated =
+					p.Seed, p.Points, p.Wins, p.Losses, p.Draws, p.Eliminated
+				mt.players[i] = stored
+			}
+		}
+	}
+	for _, m := range finished {
+		for i := range mt.matches {
+			if mt.matches[i].ID == m.ID {
+				mt.matches[i].Result, mt.matches[i].WinnerID = m.Result, m.WinnerID
+			}
+		}
+	}
+	for _, m := range created {
+		m.TournamentID = t.ID
+		mt.matches = append(mt.matches, m)
+	}
+	sort.SliceStable(mt.matches, func(i, j int) bool {
+		if mt.matches[i].Round != mt.matches[j].Round {
+			return mt.matches[i].Round < mt.matches[j].Round
+		}
+		return mt.matches[i].Table < mt.matches[j].Table
+	})
+	return nil
+}
+
+// ----------- friends -----------
+
+type memoryFriends struct {
+	s *MemoryStore
+}
+
+// find คืนความสัมพันธ์ระหว่างสองคนไม่ว่าใครเป็นคนขอ
+func (r memoryFriends) find(userA, userB string) (*memoryFriendship, [2]string, bool) {
+	for _, key := range [][2]string{{userA, userB}, {userB, userA}} {
+		if f, ok := r.s.friendships[key]; ok {
+			return f, key, true
+		}
+	}
+	return nil, [2]string{}, false
+}
+
+func (r memoryFriends) GetFriendship(userA, userB string) (*models.Friendship, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	f, _, ok := r.find(userA, userB)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	friendship := f.friendship
+	return &friendship, nil
+}
+
+func (r memoryFriends) CreateFriendRequest(requesterID, addresseeID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[requesterID]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := r.s.users[addresseeID]; !ok {
+		return ErrNotFound
+	}
+	if _, _, exists := r.find(requesterID, addresseeID); exists {
+		return fmt.Errorf("friendship %s-%s already exists", requesterID, addresseeID)
+	}
+	now := time.Now()
+	r.s.friendships[[2]string{requesterID, addresseeID}] = &memoryFriendship{
+		friendship: models.Friendship{RequesterID: requesterID, AddresseeID: addresseeID, Status: models.FriendPending, CreatedAt: now},
+		since:      now,
+	}
+	return nil
+}
+
+func (r memoryFriends) AcceptFriendRequest(requesterID, addresseeID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	f, ok := r.s.friendships[[2]string{requesterID, addresseeID}]
+	if !ok || f.friendship.Status != models.FriendPending {
+		return ErrNotFound
+	}
+	f.friendship.Status = models.FriendAccepted
+	f.since = time.Now()
+	return nil
+}
+
+func (r memoryFriends) DeleteFriendship(userA, userB string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	_, key, ok := r.find(userA, userB)
+	if !ok {
+		return ErrNotFound
+	}
+	delete(r.s.friendships, key)
+	return nil
+}
+
+func (r memoryFriends) ListFriends(userID string) ([]models.Friend, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var friends []models.Friend
+	for key, f := range r.s.friendships {
+		otherID := key[1]
+		if key[1] == userID {
+			otherID = key[0]
+		} else if key[0] != userID {
+			continue
+		}
+		other := r.s.users[otherID].user
+		friends = append(friends, models.Friend{
+			UserID:   otherID,
+			Username: other.Username,
+			Level:    other.Level,
+			Class:    other.Class,
+			Status:   f.friendship.Status,
+			Outgoing: key[0] == userID,
+			Since:    f.since.Truncate(time.Second),
+		})
+	}
+	sort.Slice(friends, func(i, j int) bool {
+		if friends[i].Username != friends[j].Username {
+			return friends[i].Username < friends[j].Username
+		}
+		return friends[i].UserID < friends[j].UserID
+	})
+	return friends, nil
+}
+
+// ----------- leaderboards -----------
+
+type memoryLeaderboards struct {
+	s *MemoryStore
+}
+
+func (r memoryLeaderboards) TopUsers(board, class string, limit int) ([]models.LeaderboardEntry, error) {
+	// คีย์เรียงลำดับเดียวกับ leaderboardColumns ของ SQL (ไม่รวม id)
+	var keys func(u *models.User) []int
+	switch board {
+	case BoardCampaign:
+		keys = func(u *models.User) []int { return []int{u.CurrentCampaignLevel, u.Level, u.Exp} }
+	case BoardLevel:
+		keys = func(u *models.User) []int { return []int{u.Level, u.Exp} }
+	case BoardGold:
+		keys = func(u *models.User) []int { return []int{u.Gold} }
+	case BoardStreak:
+		keys = func(u *models.User) []int { return []int{u.BestCampaignWinStreak, u.CampaignWinStreak} }
+	default:
+		return nil, fmt.Errorf("unknown leaderboard %q", board)
+	}
+
+	r.s.mu.Lock()
+	var users []models.User
+	for _, mu := range r.s.users {
+		if class == "" || mu.user.Class == class {
+			users = append(users, mu.user)
+		}
+	}
+	r.s.mu.Unlock()
+
+	sort.Slice(users, func(i, j int) bool {
+		a, b := keys(&users[i]), keys(&users[j])
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] > b[k]
+			}
+		}
+		return users[i].ID < users[j].ID
+	})
+
+	entries := make([]models.LeaderboardEntry, 0, min(limit, len(users)))
+	for i := 0; i < len(users) && i < limit; i++ {
+		u := &users[i]
+		entries = append(entries, models.LeaderboardEntry{
+			Rank:     i + 1,
+			UserID:   u.ID,
+			Username: u.Username,
+			Class:    u.Class,
+			Level:    u.Level,
+			Value:    keys(u)[0],
+		})
+	}
+	return entries, nil
+}
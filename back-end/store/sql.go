@@ -0,0 +1,1120 @@
+package store
+
+import (
+	"clash_and_card/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// dialect เก็บส่วนที่ต่างกันของ SQL แต่ละ backend
+type dialect struct {
+	name string
+	// forUpdate ต่อท้าย SELECT ที่ต้องล็อกแถวไว้จนจบ transaction
+	// SQLite ไม่มี FOR UPDATE แต่เปิด transaction แบบ IMMEDIATE แทน (ดู sqlite.go)
+	forUpdate string
+	now       func() interface{}
+	// loc timezone ของค่าในคอลัมน์เวลา (DATETIME ของ MySQL, TEXT ของ SQLite) ทุกคอลัมน์เก็บแบบเดียวกับ users.created_at
+	// MySQL driver เขียน time.Time เป็น UTC (loc เริ่มต้นของ DSN) SQLite เขียนเวลาท้องถิ่น
+	loc *time.Location
+}
+
+// sqlTimeLayout รูปแบบของค่าในคอลัมน์เวลาเมื่อ scan ออกมาเป็น string
+const sqlTimeLayout = "2006-01-02 15:04:05"
+
+// timeArg แปลงเวลาเป็นค่าที่เขียนลงคอลัมน์เวลา ได้ผลแบบเดียวกับ now
+func (d dialect) timeArg(t time.Time) interface{} {
+	return t.In(d.loc).Format(sqlTimeLayout)
+}
+
+// parseTime อ่านค่าจากคอลัมน์เวลาที่ scan มาเป็น string
+func (d dialect) parseTime(s string) (time.Time, error) {
+	return time.ParseInLocation(sqlTimeLayout, s, d.loc)
+}
+
+var mysqlDialect = dialect{
+	name:      "mysql",
+	forUpdate: " FOR UPDATE",
+	now:       func() interface{} { return time.Now() },
+	loc:       time.UTC,
+}
+
+// SQLStore ใช้ได้ทั้ง MySQL และ SQLite ต่างกันแค่ dialect
+type SQLStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewMySQL(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: mysqlDialect}
+}
+
+// DB และ Dialect ใช้กับ package migrations
+func (s *SQLStore) DB() *sql.DB     { return s.db }
+func (s *SQLStore) Dialect() string { return s.dialect.name }
+
+func (s *SQLStore) Users() UserRepository               { return sqlUsers{s.db, s.dialect} }
+func (s *SQLStore) Decks() DeckRepository               { return sqlDecks{s.db} }
+func (s *SQLStore) Wallets() WalletRepository           { return sqlWallets{s.db, s.dialect} }
+func (s *SQLStore) Matches() MatchRepository            { return sqlMatches{s.db, s.dialect} }
+func (s *SQLStore) Sessions() SessionRepository         { return sqlSessions{s.db, s.dialect} }
+func (s *SQLStore) LiveMatches() LiveMatchRepository    { return sqlLiveMatches{s.db, s.dialect} }
+func (s *SQLStore) Ratings() RatingRepository           { return sqlRatings{s.db, s.dialect} }
+func (s *SQLStore) PVPMatches() PVPMatchRepository      { return sqlPVPMatches{s.db, s.dialect} }
+func (s *SQLStore) Seasons() SeasonRepository           { return sqlSeasons{s.db, s.dialect} }
+func (s *SQLStore) Tournaments() TournamentRepository   { return sqlTournaments{s.db, s.dialect} }
+func (s *SQLStore) Friends() FriendRepository           { return sqlFriends{s.db, s.dialect} }
+func (s *SQLStore) Leaderboards() LeaderboardRepository { return sqlLeaderboards{s.db} }
+func (s *SQLStore) Close() error                        { return s.db.Close() }
+
+// ----------- users -----------
+
+type sqlUsers struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+const userColumns = `id, username, email, atk, def, hp, spd, level, current_campaign_level, exp, gold, created_at, class, stat_point, rating, campaign_win_streak, best_campaign_win_streak`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var u models.User
+	err := row.Scan(
+		&u.ID,
+		&u.Username,
+		&u.Email,
+		&u.Stat.Atk,
+		&u.Stat.Def,
+		&u.Stat.HP,
+		&u.Stat.Spd,
+		&u.Level,
+		&u.CurrentCampaignLevel,
+		&u.Exp,
+		&u.Gold,
+		&u.CreatedAt,
+		&u.Class,
+		&u.StatPoint,
+		&u.Rating,
+		&u.CampaignWinStreak,
+		&u.BestCampaignWinStreak,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r sqlUsers) GetUser(id string) (*models.User, error) {
+	return scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+}
+
+func (r sqlUsers) GetCredentials(email string) (string, string, error) {
+	var id, hash string
+	err := r.db.QueryRow(`SELECT id, password FROM users WHERE email = ?`, email).Scan(&id, &hash)
+	if err == sql.ErrNoRows {
+		return "", "", ErrNotFound
+	}
+	return id, hash, err
+}
+
+func (r sqlUsers) EmailExists(email string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", email).Scan(&exists)
+	return exists, err
+}
+
+func (r sqlUsers) CreateUser(u *models.User, passwordHash string, deck []models.DeckCard) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO users (
+			id, username, email, password,
+			atk, def, spd, hp,
+			level, current_campaign_level,
+			exp, gold, created_at,
+			class, stat_point, rating
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		u.ID, u.Username, u.Email, passwordHash,
+		u.Stat.Atk, u.Stat.Def, u.Stat.Spd, u.Stat.HP,
+		u.Level, u.CurrentCampaignLevel,
+		u.Exp, u.Gold, r.dialect.now(),
+		u.Class, u.StatPoint, u.Rating,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, card := range deck {
+		_, err = tx.Exec("INSERT INTO decks (user_id, card_type, quantity) VALUES (?, ?, ?)",
+			u.ID, card.CardType, card.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r sqlUsers) UpdateUser(id string, fn func(u *models.User) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateUserTx(tx, r.dialect, id, fn); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateUserTx ส่วนของ UpdateUser ที่ทำใน transaction ที่เปิดไว้แล้ว ใช้ร่วมกับ repository อื่นที่ต้องแก้ผู้ใช้ด้วย
+func updateUserTx(tx *sql.Tx, d dialect, id string, fn func(u *models.User) error) error {
+	u, err := scanUser(tx.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`+d.forUpdate, id))
+	if err != nil {
+		return err
+	}
+
+	if err := fn(u); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users
+		SET username = ?, atk = ?, def = ?, hp = ?, spd = ?,
+			level = ?, current_campaign_level = ?, exp = ?, gold = ?,
+			class = ?, stat_point = ?,
+			campaign_win_streak = ?, best_campaign_win_streak = ?
+		WHERE id = ?`,
+		u.Username, u.Stat.Atk, u.Stat.Def, u.Stat.HP, u.Stat.Spd,
+		u.Level, u.CurrentCampaignLevel, u.Exp, u.Gold,
+		u.Class, u.StatPoint,
+		u.CampaignWinStreak, u.BestCampaignWinStreak,
+		id,
+	)
+	return err
+}
+
+// ----------- decks -----------
+
+type sqlDecks struct {
+	db *sql.DB
+}
+
+func (r sqlDecks) GetDeck(userID string) ([]models.DeckCard, error) {
+	rows, err := r.db.Query("SELECT card_type, quantity FROM decks WHERE user_id = ? ORDER BY card_type", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deck []models.DeckCard
+	for rows.Next() {
+		var card models.DeckCard
+		if err := rows.Scan(&card.CardType, &card.Quantity); err != nil {
+			return nil, err
+		}
+		deck = append(deck, card)
+	}
+	return deck, rows.Err()
+}
+
+// ----------- wallets -----------
+
+type sqlWallets struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func (r sqlWallets) BuyCard(userID, cardType string, price int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var gold int
+	err = tx.QueryRow(`SELECT gold FROM users WHERE id = ?`+r.dialect.forUpdate, userID).Scan(&gold)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if gold < price {
+		return ErrNotEnoughGold
+	}
+
+	if _, err = tx.Exec(`UPDATE users SET gold = gold - ? WHERE id = ?`, price, userID); err != nil {
+		return err
+	}
+
+	if err := addCardTx(tx, userID, cardType, 1); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// addCardTx เพิ่มการ์ดลงเด็คใน transaction ที่เปิดไว้แล้ว
+func addCardTx(tx *sql.Tx, userID, cardType string, quantity int) error {
+	res, err := tx.Exec(`UPDATE decks SET quantity = quantity + ? WHERE user_id = ? AND card_type = ?`, quantity, userID, cardType)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		_, err = tx.Exec(`INSERT INTO decks (user_id, card_type, quantity) VALUES (?, ?, ?)`, userID, cardType, quantity)
+	}
+	return err
+}
+
+// ----------- matches -----------
+
+type sqlMatches struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func (r sqlMatches) CreateMatch(m *models.MatchRecord) error {
+	var userB interface{}
+	if m.PlayerBID != "" {
+		userB = m.PlayerBID
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO matches (id, mode, seed, player_a_id, player_b_id, player_a, player_b, hand_size, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.ID, m.Mode, m.Seed, m.PlayerAID, userB, m.PlayerA, m.PlayerB, m.HandSize, m.Status, r.dialect.now(),
+	)
+	return err
+}
+
+func (r sqlMatches) AppendMatchEvent(matchID string, round int, eventType string, payload string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO match_events (match_id, round, event_type, payload, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		matchID, round, eventType, payload, r.dialect.now(),
+	)
+	return err
+}
+
+func (r sqlMatches) FinishMatch(matchID, status, resultA, detailA, resultB, detailB string) error {
+	_, err := r.db.Exec(`
+		UPDATE matches
+		SET status = ?, result_a = ?, detail_a = ?, result_b = ?, detail_b = ?, ended_at = ?
+		WHERE id = ?`,
+		status, resultA, detailA, resultB, detailB, r.dialect.now(), matchID,
+	)
+	return err
+}
+
+func (r sqlMatches) GetMatch(matchID string) (*models.MatchRecord, error) {
+	var m models.MatchRecord
+	var playerBID, resultA, detailA, resultB, detailB, endedAt sql.NullString
+	err := r.db.QueryRow(`
+		SELECT id, mode, seed, player_a_id, player_b_id, player_a, player_b, hand_size, status,
+			result_a, detail_a, result_b, detail_b, created_at, ended_at
+		FROM matches WHERE id = ?`, matchID,
+	).Scan(&m.ID, &m.Mode, &m.Seed, &m.PlayerAID, &playerBID, &m.PlayerA, &m.PlayerB, &m.HandSize, &m.Status,
+		&resultA, &detailA, &resultB, &detailB, &m.CreatedAt, &endedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	m.PlayerBID = playerBID.String
+	m.ResultA, m.DetailA = resultA.String, detailA.String
+	m.ResultB, m.DetailB = resultB.String, detailB.String
+	m.EndedAt = endedAt.String
+	return &m, nil
+}
+
+func (r sqlMatches) ListMatchEvents(matchID string) ([]models.MatchEvent, error) {
+	rows, err := r.db.Query(`
+		SELECT round, event_type, payload, created_at FROM match_events
+		WHERE match_id = ? ORDER BY id`, matchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.MatchEvent
+	for rows.Next() {
+		var ev models.MatchEvent
+		if err := rows.Scan(&ev.Round, &ev.Type, &ev.Payload, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// ----------- sessions -----------
+
+type sqlSessions struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func (r sqlSessions) CreateSession(sessionID, userID, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO sessions (id, user_id, created_at) VALUES (?, ?, ?)`,
+		sessionID, userID, r.dialect.now()); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO refresh_tokens (token_hash, session_id, expires_at, created_at) VALUES (?, ?, ?, ?)`,
+		tokenHash, sessionID, r.dialect.timeArg(expiresAt), r.dialect.now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r sqlSessions) RotateRefreshToken(oldHash, newHash string, expiresAt time.Time) (string, string, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	var sessionID, userID, tokenExpiresAt string
+	var used, revoked bool
+	err = tx.QueryRow(`
+		SELECT t.session_id, s.user_id, t.expires_at, t.used_at IS NOT NULL, s.revoked_at IS NOT NULL
+		FROM refresh_tokens t JOIN sessions s ON s.id = t.session_id
+		WHERE t.token_hash = ?`+r.dialect.forUpdate, oldHash,
+	).Scan(&sessionID, &userID, &tokenExpiresAt, &used, &revoked)
+	if err == sql.ErrNoRows {
+		return "", "", ErrNotFound
+	} else if err != nil {
+		return "", "", err
+	}
+	expires, err := r.dialect.parseTime(tokenExpiresAt)
+	if err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case revoked:
+		return "", "", ErrSessionRevoked
+	case used:
+		// token ถูกขโมยหรือส่งซ้ำ ตัดทั้ง session ทิ้ง
+		if _, err := tx.Exec(`UPDATE sessions SET revoked_at = ? WHERE id = ?`, r.dialect.now(), sessionID); err != nil {
+			return "", "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return "", "", err
+		}
+		return "", "", ErrTokenReused
+	case !time.Now().Before(expires):
+		return "", "", ErrTokenExpired
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET used_at = ? WHERE token_hash = ?`, r.dialect.now(), oldHash); err != nil {
+		return "", "", err
+	}
+	if _, err := tx.Exec(`INSERT INTO refresh_tokens (token_hash, session_id, expires_at, created_at) VALUES (?, ?, ?, ?)`,
+		newHash, sessionID, r.dialect.timeArg(expiresAt), r.dialect.now()); err != nil {
+		return "", "", err
+	}
+	return sessionID, userID, tx.Commit()
+}
+
+func (r sqlSessions) RevokeSessionByToken(tokenHash string) error {
+	var sessionID string
+	err := r.db.QueryRow(`SELECT session_id FROM refresh_tokens WHERE token_hash = ?`, tokenHash).Scan(&sessionID)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`UPDATE sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, r.dialect.now(), sessionID)
+	return err
+}
+
+func (r sqlSessions) SessionActive(sessionID string) (bool, error) {
+	var active bool
+	err := r.db.QueryRow(`SELECT revoked_at IS NULL FROM sessions WHERE id = ?`, sessionID).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return active, err
+}
+
+// ----------- live matches -----------
+
+type sqlLiveMatches struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func (r sqlLiveMatches) SaveLiveMatch(m *models.LiveMatch) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// ลบแล้วเขียนใหม่ ใช้ได้ทั้ง MySQL และ SQLite โดยไม่ต้องพึ่ง upsert ของแต่ละ dialect
+	if _, err := tx.Exec(`DELETE FROM live_matches WHERE id = ?`, m.ID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO live_matches (id, mode, state, updated_at) VALUES (?, ?, ?, ?)`,
+		m.ID, m.Mode, m.State, r.dialect.now()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r sqlLiveMatches) DeleteLiveMatch(id string) error {
+	_, err := r.db.Exec(`DELETE FROM live_matches WHERE id = ?`, id)
+	return err
+}
+
+func (r sqlLiveMatches) ListLiveMatches() ([]models.LiveMatch, error) {
+	rows, err := r.db.Query(`SELECT id, mode, state, updated_at FROM live_matches`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []models.LiveMatch
+	for rows.Next() {
+		var m models.LiveMatch
+		if err := rows.Scan(&m.ID, &m.Mode, &m.State, &m.UpdatedAt); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// ----------- ratings -----------
+
+type sqlRatings struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func (r sqlRatings) UpdateRatings(matchID, userAID, userBID string, fn func(a, b *models.RatingChange)) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	a := &models.RatingChange{UserID: userAID, OpponentID: userBID, MatchID: matchID}
+	b := &models.RatingChange{UserID: userBID, OpponentID: userAID, MatchID: matchID}
+
+	// ล็อกตามลำดับ id เสมอ กัน deadlock เมื่อสอง match ล็อกผู้เล่นคู่เดียวกัน
+	first, second := a, b
+	if userBID < userAID {
+		first, second = b, a
+	}
+	for _, c := range []*models.RatingChange{first, second} {
+		err := tx.QueryRow(`SELECT rating FROM users WHERE id = ?`+r.dialect.forUpdate, c.UserID).Scan(&c.Before)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		} else if err != nil {
+			return err
+		}
+	}
+
+	fn(a, b)
+
+	for _, c := range []*models.RatingChange{a, b} {
+		if _, err := tx.Exec(`UPDATE users SET rating = ? WHERE id = ?`, c.After, c.UserID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO rating_history (user_id, opponent_id, match_id, result, rating_before, rating_after, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			c.UserID, c.OpponentID, c.MatchID, c.Result, c.Before, c.After, r.dialect.now()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r sqlRatings) RatingHistory(userID string, limit int) ([]models.RatingChange, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, opponent_id, match_id, result, rating_before, rating_after, created_at
+		FROM rating_history WHERE user_id = ? ORDER BY id DESC LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.RatingChange
+	for rows.Next() {
+		var c models.RatingChange
+		if err := rows.Scan(&c.UserID, &c.OpponentID, &c.MatchID, &c.Result, &c.Before, &c.After, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
+
+// ----------- pvp matches -----------
+
+type sqlPVPMatches struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func (r sqlPVPMatches) RecordPVPResult(m *models.PVPMatchResult, fn func(slot string, u *models.User) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var winnerID interface{}
+	if m.WinnerID != "" {
+		winnerID = m.WinnerID
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO pvp_matches (id, room_id, player_a_id, player_b_id, winner_id, reason, ended_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		m.ID, m.RoomID, m.PlayerAID, m.PlayerBID, winnerID, m.Reason, r.dialect.now()); err != nil {
+		return err
+	}
+
+	// ล็อกตามลำดับ id เสมอ เหมือน UpdateRatings
+	slots := []string{"A", "B"}
+	if m.PlayerBID < m.PlayerAID {
+		slots = []string{"B", "A"}
+	}
+	ids := map[string]string{"A": m.PlayerAID, "B": m.PlayerBID}
+	for _, slot := range slots {
+		err := updateUserTx(tx, r.dialect, ids[slot], func(u *models.User) error {
+			return fn(slot, u)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ----------- seasons -----------
+
+type sqlSeasons struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// seasonResultColumn คอลัมน์ที่นับผลแต่ละแบบ
+var seasonResultColumn = map[string]string{
+	"Win":  "wins",
+	"Lose": "losses",
+	"Draw": "draws",
+}
+
+const seasonStandingOrder = `s.points DESC, s.wins DESC, s.user_id`
+
+func (r sqlSeasons) LatestSeason() (*models.Season, error) {
+	var s models.Season
+	var startsAt, endsAt string
+	err := r.db.QueryRow(`SELECT id, number, starts_at, ends_at, closed_at IS NOT NULL FROM seasons ORDER BY number DESC LIMIT 1`).
+		Scan(&s.ID, &s.Number, &startsAt, &endsAt, &s.Closed)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if s.StartsAt, err = r.dialect.parseTime(startsAt); err != nil {
+		return nil, err
+	}
+	if s.EndsAt, err = r.dialect.parseTime(endsAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r sqlSeasons) CreateSeason(s *models.Season) error {
+	res, err := r.db.Exec(`INSERT INTO seasons (number, starts_at, ends_at) VALUES (?, ?, ?)`,
+		s.Number, r.dialect.timeArg(s.StartsAt), r.dialect.timeArg(s.EndsAt))
+	if err != nil {
+		return err
+	}
+	s.ID, err = res.LastInsertId()
+	return err
+}
+
+func (r sqlSeasons) AddSeasonResult(seasonID int64, userID, result string, points int) error {
+	column, ok := seasonResultColumn[result]
+	if !ok {
+		return fmt.Errorf("unknown result %q", result)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current int
+	err = tx.QueryRow(`SELECT points FROM season_standings WHERE season_id = ? AND user_id = ?`+r.dialect.forUpdate,
+		seasonID, userID).Scan(&current)
+	if err == sql.ErrNoRows {
+		if _, err := tx.Exec(`INSERT INTO season_standings (season_id, user_id) VALUES (?, ?)`, seasonID, userID); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE season_standings SET points = ?, `+column+` = `+column+` + 1 WHERE season_id = ? AND user_id = ?`,
+		max(current+points, 0), seasonID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r sqlSeasons) SeasonStandings(seasonID int64, offset, limit int) ([]models.SeasonStanding, error) {
+	rows, err := r.db.Query(`
+		SELECT s.user_id, u.username, s.points, s.wins, s.losses, s.draws, s.rewarded_at IS NOT NULL
+		FROM season_standings s JOIN users u ON u.id = s.user_id
+		WHERE s.season_id = ?
+		ORDER BY `+seasonStandingOrder+`
+		LIMIT ? OFFSET ?`, seasonID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var standings []models.SeasonStanding
+	for rows.Next() {
+		st := models.SeasonStanding{SeasonID: seasonID, Placement: offset + len(standings) + 1}
+		if err := rows.Scan(&st.UserID, &st.Username, &st.Points, &st.Wins, &st.Losses, &st.Draws, &st.Rewarded); err != nil {
+			return nil, err
+		}
+		standings = append(standings, st)
+	}
+	return standings, rows.Err()
+}
+
+func (r sqlSeasons) SeasonStanding(seasonID int64, userID string) (*models.SeasonStanding, error) {
+	st := models.SeasonStanding{SeasonID: seasonID, UserID: userID}
+	err := r.db.QueryRow(`
+		SELECT u.username, s.points, s.wins, s.losses, s.draws, s.rewarded_at IS NOT NULL
+		FROM season_standings s JOIN users u ON u.id = s.user_id
+		WHERE s.season_id = ? AND s.user_id = ?`, seasonID, userID).
+		Scan(&st.Username, &st.Points, &st.Wins, &st.Losses, &st.Draws, &st.Rewarded)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	// อันดับ = จำนวนคนที่อยู่ก่อนตามลำดับเดียวกับ SeasonStandings + 1
+	var ahead int
+	err = r.db.QueryRow(`
+		SELECT COUNT(*) FROM season_standings
+		WHERE season_id = ? AND (points > ? OR (points = ? AND wins > ?) OR (points = ? AND wins = ? AND user_id < ?))`,
+		seasonID, st.Points, st.Points, st.Wins, st.Points, st.Wins, userID).Scan(&ahead)
+	if err != nil {
+		return nil, err
+	}
+	st.Placement = ahead + 1
+	return &st, nil
+}
+
+func (r sqlSeasons) CloseSeason(seasonID int64, initialRating int, keep float64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var closed bool
+	err = tx.QueryRow(`SELECT closed_at IS NOT NULL FROM seasons WHERE id = ?`+r.dialect.forUpdate, seasonID).Scan(&closed)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if closed {
+		return nil
+	}
+
+	rows, err := tx.Query(`SELECT s.user_id FROM season_standings s WHERE s.season_id = ? ORDER BY `+seasonStandingOrder, seasonID)
+	if err != nil {
+		return err
+	}
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, id := range userIDs {
+		if _, err := tx.Exec(`UPDATE season_standings SET placement = ? WHERE season_id = ? AND user_id = ?`, i+1, seasonID, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET rating = ? + ROUND((rating - ?) * ?)`, initialRating, initialRating, keep); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE seasons SET closed_at = ? WHERE id = ?`, r.dialect.now(), seasonID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r sqlSeasons) GrantSeasonReward(seasonID int64, userID string, cards []models.DeckCard, fn func(u *models.User) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var rewarded bool
+	err = tx.QueryRow(`SELECT rewarded_at IS NOT NULL FROM season_standings WHERE season_id = ? AND user_id = ?`+r.dialect.forUpdate,
+		seasonID, userID).Scan(&rewarded)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+	if rewarded {
+		return nil
+	}
+
+	if err := updateUserTx(tx, r.dialect, userID, fn); err != nil {
+		return err
+	}
+	for _, card := range cards {
+		if err := addCardTx(tx, userID, card.CardType, card.Quantity); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE season_standings SET rewarded_at = ? WHERE season_id = ? AND user_id = ?`,
+		r.dialect.now(), seasonID, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ----------- tournaments -----------
+
+type sqlTournaments struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+const tournamentColumns = `id, name, format, status, best_of, max_players, rounds, current_round, created_by, winner_id, created_at`
+
+func scanTournament(d dialect, row rowScanner) (*models.Tournament, error) {
+	var t models.Tournament
+	var winnerID sql.NullString
+	var createdAt string
+	err := row.Scan(&t.ID, &t.Name, &t.Format, &t.Status, &t.BestOf, &t.MaxPlayers, &t.Rounds, &t.CurrentRound,
+		&t.CreatedBy, &winnerID, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.WinnerID = winnerID.String
+	if t.CreatedAt, err = d.parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// nullString เก็บสตริงว่างเป็น NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func (r sqlTournaments) CreateTournament(t *models.Tournament) error {
+	_, err := r.db.Exec(`INSERT INTO tournaments (`+tournamentColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.Name, t.Format, t.Status, t.BestOf, t.MaxPlayers, t.Rounds, t.CurrentRound,
+		t.CreatedBy, nullString(t.WinnerID), r.dialect.timeArg(t.CreatedAt))
+	return err
+}
+
+func (r sqlTournaments) GetTournament(id string) (*models.Tournament, error) {
+	return scanTournament(r.dialect, r.db.QueryRow(`SELECT `+tournamentColumns+` FROM tournaments WHERE id = ?`, id))
+}
+
+func (r sqlTournaments) ListTournaments(status string) ([]models.Tournament, error) {
+	rows, err := r.db.Query(`SELECT `+tournamentColumns+` FROM tournaments WHERE status = ? ORDER BY created_at DESC, id`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tournaments []models.Tournament
+	for rows.Next() {
+		t, err := scanTournament(r.dialect, rows)
+		if err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, *t)
+	}
+	return tournaments, rows.Err()
+}
+
+func (r sqlTournaments) AddTournamentPlayer(tournamentID, userID string) error {
+	_, err := r.db.Exec(`INSERT INTO tournament_players (tournament_id, user_id, registered_at) VALUES (?, ?, ?)`,
+		tournamentID, userID, r.dialect.now())
+	return err
+}
+
+func (r sqlTournaments) ListTournamentPlayers(tournamentID string) ([]models.TournamentPlayer, error) {
+	rows, err := r.db.Query(`
+		SELECT p.user_id, u.username, p.seed, p.points, p.wins, p.losses, p.draws, p.eliminated, p.registered_at
+		FROM tournament_players p JOIN users u ON u.id = p.user_id
+		WHERE p.tournament_id = ?
+		ORDER BY p.registered_at, p.user_id`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var players []models.TournamentPlayer
+	for rows.Next() {
+		p := models.TournamentPlayer{TournamentID: tournamentID}
+		var registeredAt string
+		if err := rows.Scan(&p.UserID, &p.Username, &p.Seed, &p.Points, &p.Wins, &p.Losses, &p.Draws, &p.Eliminated, &registeredAt); err != nil {
+			return nil, err
+		}
+		if p.RegisteredAt, err = r.dialect.parseTime(registeredAt); err != nil {
+			return nil, err
+		}
+		players = append(players, p)
+	}
+	return players, rows.Err()
+}
+
+func (r sqlTournaments) ListTournamentMatches(tournamentID string) ([]models.TournamentMatch, error) {
+	rows, err := r.db.Query(`
+		SELECT id, round, table_no, player_a_id, player_b_id, room_id, result, winner_id
+		FROM tournament_matches WHERE tournament_id = ?
+		ORDER BY round, table_no`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []models.TournamentMatch
+	for rows.Next() {
+		m := models.TournamentMatch{TournamentID: tournamentID}
+		var playerBID, roomID, winnerID sql.NullString
+		if err := rows.Scan(&m.ID, &m.Round, &m.Table, &m.PlayerAID, &playerBID, &roomID, &m.Result, &winnerID); err != nil {
+			return nil, err
+		}
+		m.PlayerBID, m.RoomID, m.WinnerID = playerBID.String, roomID.String, winnerID.String
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+func (r sqlTournaments) SaveTournament(t *models.Tournament, players []models.TournamentPlayer, finished, created []models.TournamentMatch) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE tournaments SET status = ?, rounds = ?, current_round = ?, winner_id = ? WHERE id = ?`,
+		t.Status, t.Rounds, t.CurrentRound, nullString(t.WinnerID), t.ID); err != nil {
+		return err
+	}
+	for _, p := range players {
+		if _, err := tx.Exec(`
+			UPDATE tournament_players SET seed = ?, points = ?, wins = ?, losses = ?, draws = ?, eliminated = ?
+			WHERE tournament_id = ? AND user_id = ?`,
+			p.Seed, p.Points, p.Wins, p.Losses, p.Draws, p.Eliminated, t.ID, p.UserID); err != nil {
+			return err
+		}
+	}
+	for _, m := range finished {
+		if _, err := tx.Exec(`UPDATE tournament_matches SET result = ?, winner_id = ? WHERE id = ?`,
+			m.Result, nullString(m.WinnerID), m.ID); err != nil {
+			return err
+		}
+	}
+	for _, m := range created {
+		if _, err := tx.Exec(`
+			INSERT INTO tournament_matches (id, tournament_id, round, table_no, player_a_id, player_b_id, room_id, result, winner_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			m.ID, t.ID, m.Round, m.Table, m.PlayerAID, nullString(m.PlayerBID), nullString(m.RoomID), m.Result, nullString(m.WinnerID)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ----------- friends -----------
+
+type sqlFriends struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func (r sqlFriends) GetFriendship(userA, userB string) (*models.Friendship, error) {
+	var f models.Friendship
+	var createdAt string
+	err := r.db.QueryRow(`
+		SELECT requester_id, addressee_id, status, created_at FROM friendships
+		WHERE (requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)`,
+		userA, userB, userB, userA).Scan(&f.RequesterID, &f.AddresseeID, &f.Status, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if f.CreatedAt, err = r.dialect.parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r sqlFriends) CreateFriendRequest(requesterID, addresseeID string) error {
+	_, err := r.db.Exec(`INSERT INTO friendships (requester_id, addressee_id, status, created_at) VALUES (?, ?, ?, ?)`,
+		requesterID, addresseeID, models.FriendPending, r.dialect.now())
+	return err
+}
+
+func (r sqlFriends) AcceptFriendRequest(requesterID, addresseeID string) error {
+	res, err := r.db.Exec(`UPDATE friendships SET status = ?, accepted_at = ? WHERE requester_id = ? AND addressee_id = ? AND status = ?`,
+		models.FriendAccepted, r.dialect.now(), requesterID, addresseeID, models.FriendPending)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r sqlFriends) DeleteFriendship(userA, userB string) error {
+	res, err := r.db.Exec(`
+		DELETE FROM friendships
+		WHERE (requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)`,
+		userA, userB, userB, userA)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r sqlFriends) ListFriends(userID string) ([]models.Friend, error) {
+	rows, err := r.db.Query(`
+		SELECT u.id, u.username, u.level, u.class, f.status, f.requester_id = ?, COALESCE(f.accepted_at, f.created_at)
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.requester_id = ? THEN f.addressee_id ELSE f.requester_id END
+		WHERE f.requester_id = ? OR f.addressee_id = ?
+		ORDER BY u.username, u.id`, userID, userID, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var friends []models.Friend
+	for rows.Next() {
+		var f models.Friend
+		var since string
+		if err := rows.Scan(&f.UserID, &f.Username, &f.Level, &f.Class, &f.Status, &f.Outgoing, &since); err != nil {
+			return nil, err
+		}
+		if f.Since, err = r.dialect.parseTime(since); err != nil {
+			return nil, err
+		}
+		friends = append(friends, f)
+	}
+	return friends, rows.Err()
+}
+
+// ----------- leaderboards -----------
+
+type sqlLeaderboards struct {
+	db *sql.DB
+}
+
+// leaderboardColumns คอลัมน์ที่ใช้จัดอันดับของแต่ละ board และตัวตัดสินเมื่อเท่ากัน
+var leaderboardColumns = map[string]struct{ value, order string }{
+	BoardCampaign: {"current_campaign_level", "current_campaign_level DESC, level DESC, exp DESC, id"},
+	BoardLevel:    {"level", "level DESC, exp DESC, id"},
+	BoardGold:     {"gold", "gold DESC, id"},
+	BoardStreak:   {"best_campaign_win_streak", "best_campaign_win_streak DESC, campaign_win_streak DESC, id"},
+}
+
+func (r sqlLeaderboards) TopUsers(board, class string, limit int) ([]models.LeaderboardEntry, error) {
+	cols, ok := leaderboardColumns[board]
+	if !ok {
+		return nil, fmt.Errorf("unknown leaderboard %q", board)
+	}
+
+	query := `SELECT id, username, class, level, ` + cols.value + ` FROM users`
+	args := []interface{}{}
+	if class != "" {
+		query += ` WHERE class = ?`
+		args = append(args, class)
+	}
+	query += ` ORDER BY ` + cols.order + ` LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.LeaderboardEntry
+	for rows.Next() {
+		e := models.LeaderboardEntry{Rank: len(entries) + 1}
+		if err := rows.Scan(&e.UserID, &e.Username, &e.Class, &e.Level, &e.Value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
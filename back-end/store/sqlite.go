@@ -0,0 +1,40 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var sqliteDialect = dialect{
+	name:      "sqlite",
+	forUpdate: "",
+	now:       func() interface{} { return time.Now().Format(sqlTimeLayout) },
+	loc:       time.Local,
+}
+
+const sqliteMaxOpenConns = 8
+
+// OpenSQLite เปิดไฟล์ฐานข้อมูล SQLite (สร้างใหม่ถ้ายังไม่มี) ตารางสร้างโดย package migrations
+//
+// SQLite ไม่มี SELECT ... FOR UPDATE จึงเปิดทุก transaction แบบ BEGIN IMMEDIATE (_txlock=immediate)
+// ซึ่งจองสิทธิ์เขียนทั้งฐานข้อมูลตั้งแต่เริ่ม transaction transaction อื่นที่จะเขียนต้องรอจนกว่าจะ commit
+// ได้ผลเหมือนการล็อกแถวใน MySQL (เช่นการหักทองใน BuyCard จะไม่ซ้อนกัน)
+func OpenSQLite(path string) (*SQLStore, error) {
+	dsn := "file:" + path + "?_txlock=immediate&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// WAL ให้อ่านพร้อมกันได้หลาย connection ระหว่างที่มี transaction เขียนค้างอยู่
+	// การเขียนยังทำได้ทีละ transaction ตัวอื่นรอตาม busy_timeout
+	db.SetMaxOpenConns(sqliteMaxOpenConns)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLStore{db: db, dialect: sqliteDialect}, nil
+}
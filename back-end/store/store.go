@@ -0,0 +1,175 @@
+// Package store รวม repository ของข้อมูลเกม (users, decks, wallets, matches)
+// handler ทั้งหมดเรียกผ่าน interface ในไฟล์นี้ แทนการเขียน SQL เอง
+// มี implementation สำหรับ MySQL และแบบเก็บในหน่วยความจำ (สำหรับเทสและรันในเครื่อง)
+//
+// method ที่รับ fn (UpdateUser, UpdateRatings, RecordPVPResult, GrantSeasonReward) เรียก fn ระหว่างที่ถือ
+// transaction หรือ lock ของ store อยู่ fn ต้องแก้แค่ค่าที่ได้รับและห้ามเรียก Store อีก
+// ไม่อย่างนั้นจะรอ lock ของตัวเอง (MemoryStore ค้างตลอดไป, SQL รอจนหมด lock timeout แล้วล้มเหลว)
+package store
+
+import (
+	"clash_and_card/models"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrNotEnoughGold = errors.New("not enough gold")
+
+	ErrSessionRevoked = errors.New("session revoked")
+	ErrTokenExpired   = errors.New("refresh token expired")
+	// ErrTokenReused refresh token ที่ถูกหมุนไปแล้วถูกใช้ซ้ำ session ทั้งชุดจะถูกเพิกถอน
+	ErrTokenReused = errors.New("refresh token reused")
+)
+
+type UserRepository interface {
+	GetUser(id string) (*models.User, error)
+	// GetCredentials คืน user id และ password hash ของอีเมลนี้
+	GetCredentials(email string) (id string, passwordHash string, err error)
+	EmailExists(email string) (bool, error)
+	// CreateUser สร้างผู้ใช้ใหม่พร้อมเด็คเริ่มต้น
+	CreateUser(u *models.User, passwordHash string, deck []models.DeckCard) error
+	// UpdateUser อ่านผู้ใช้ ส่งให้ fn แก้ไข แล้วบันทึกกลับแบบ atomic
+	// ถ้า fn คืน error จะไม่บันทึกอะไรเลย
+	UpdateUser(id string, fn func(u *models.User) error) error
+}
+
+type DeckRepository interface {
+	// GetDeck คืนการ์ดในเด็คเรียงตาม card_type
+	GetDeck(userID string) ([]models.DeckCard, error)
+}
+
+type WalletRepository interface {
+	// BuyCard หักทองและเพิ่มการ์ดลงเด็คใน transaction เดียว
+	BuyCard(userID, cardType string, price int) error
+}
+
+type MatchRepository interface {
+	CreateMatch(m *models.MatchRecord) error
+	AppendMatchEvent(matchID string, round int, eventType string, payload string) error
+	FinishMatch(matchID, status, resultA, detailA, resultB, detailB string) error
+	GetMatch(matchID string) (*models.MatchRecord, error)
+	// ListMatchEvents คืน event ของ match ตามลำดับที่บันทึก
+	ListMatchEvents(matchID string) ([]models.MatchEvent, error)
+}
+
+// SessionRepository เก็บ session การ login และ refresh token (เก็บเฉพาะ hash)
+// refresh token ทุกตัวที่หมุนต่อกันมาจาก login ครั้งเดียวอยู่ใน session เดียวกัน (token family)
+type SessionRepository interface {
+	// CreateSession เปิด session ใหม่พร้อม refresh token ตัวแรก
+	CreateSession(sessionID, userID, tokenHash string, expiresAt time.Time) error
+	// RotateRefreshToken ใช้ refresh token เดิมแลกตัวใหม่ใน session เดียวกัน
+	// ถ้า token เดิมเคยถูกใช้ไปแล้วจะเพิกถอนทั้ง session และคืน ErrTokenReused
+	RotateRefreshToken(oldHash, newHash string, expiresAt time.Time) (sessionID, userID string, err error)
+	// RevokeSessionByToken เพิกถอน session ที่ refresh token นี้อยู่
+	RevokeSessionByToken(tokenHash string) error
+	// SessionActive คืน false ถ้าไม่มี session นี้หรือถูกเพิกถอนแล้ว
+	SessionActive(sessionID string) (bool, error)
+}
+
+// LiveMatchRepository เก็บ snapshot ของ match ที่ยังเล่นอยู่ (หนึ่งแถวต่อ match เขียนทับทุกครั้ง)
+type LiveMatchRepository interface {
+	SaveLiveMatch(m *models.LiveMatch) error
+	DeleteLiveMatch(id string) error
+	ListLiveMatches() ([]models.LiveMatch, error)
+}
+
+// RatingRepository rating PvP ของผู้เล่นและประวัติการเปลี่ยนแปลง
+type RatingRepository interface {
+	// UpdateRatings ล็อก rating ปัจจุบันของผู้เล่นทั้งสองคน ส่งให้ fn คำนวณ แล้วบันทึก rating ใหม่พร้อมประวัติใน transaction เดียว
+	// a และ b ที่ fn ได้รับกรอก UserID, OpponentID, MatchID และ Before มาแล้ว fn ต้องกรอก Result และ After
+	UpdateRatings(matchID, userAID, userBID string, fn func(a, b *models.RatingChange)) error
+	// RatingHistory คืนประวัติของผู้ใช้ ล่าสุดก่อน ไม่เกิน limit รายการ
+	RatingHistory(userID string, limit int) ([]models.RatingChange, error)
+}
+
+// PVPMatchRepository ผลของ match PvP ที่จบแล้ว
+type PVPMatchRepository interface {
+	// RecordPVPResult บันทึกผล match และส่งผู้เล่นแต่ละ slot ("A", "B") ให้ fn ให้รางวัล ใน transaction เดียว
+	// ถ้า fn คืน error จะไม่บันทึกอะไรเลย
+	RecordPVPResult(m *models.PVPMatchResult, fn func(slot string, u *models.User) error) error
+}
+
+// SeasonRepository ฤดูกาลแรงก์ คะแนนของผู้เล่นในแต่ละฤดูกาล และรางวัลตอนจบฤดูกาล
+// อันดับเรียงตามคะแนน แล้วจำนวนชนะ แล้ว user id (ให้ลำดับคงที่เมื่อเสมอกัน)
+type SeasonRepository interface {
+	// LatestSeason คืนฤดูกาลล่าสุด (ที่ปิดไปแล้วก็ได้) ErrNotFound ถ้ายังไม่เคยมี
+	LatestSeason() (*models.Season, error)
+	// CreateSeason สร้างฤดูกาลใหม่และกรอก ID
+	CreateSeason(s *models.Season) error
+	// AddSeasonResult บันทึกผลหนึ่งเกม ("Win", "Lose" หรือ "Draw") และบวกคะแนน points (ติดลบได้ แต่คะแนนรวมไม่ต่ำกว่า 0)
+	AddSeasonResult(seasonID int64, userID, result string, points int) error
+	// SeasonStandings คืนอันดับตั้งแต่ offset ไม่เกิน limit คน
+	SeasonStandings(seasonID int64, offset, limit int) ([]models.SeasonStanding, error)
+	// SeasonStanding คืนคะแนนและอันดับของผู้เล่นคนเดียว ErrNotFound ถ้ายังไม่ได้เล่นในฤดูกาลนี้
+	SeasonStanding(seasonID int64, userID string) (*models.SeasonStanding, error)
+	// CloseSeason บันทึกอันดับสุดท้าย ปิดฤดูกาล และ soft reset rating ของทุกคน
+	// (rating ใหม่ = initialRating + (rating - initialRating) * keep) ใน transaction เดียว
+	CloseSeason(seasonID int64, initialRating int, keep float64) error
+	// GrantSeasonReward ส่งผู้ใช้ให้ fn เพิ่มทองหรือ exp เพิ่มการ์ดลงเด็ค และทำเครื่องหมายว่าได้รับรางวัลแล้ว ใน transaction เดียว
+	// ถ้าเคยได้รับไปแล้วจะไม่ทำอะไร
+	GrantSeasonReward(seasonID int64, userID string, cards []models.DeckCard, fn func(u *models.User) error) error
+}
+
+// TournamentRepository การแข่งขัน ผู้เล่นที่สมัคร และคู่ของแต่ละรอบ
+// การจับคู่และตัดสินผลทำใน package battle repository แค่อ่านและบันทึก
+type TournamentRepository interface {
+	// CreateTournament สร้างการแข่งขันใหม่ ผู้เรียกกรอก ID มาแล้ว
+	CreateTournament(t *models.Tournament) error
+	GetTournament(id string) (*models.Tournament, error)
+	// ListTournaments คืนการแข่งขันที่มีสถานะ status ใหม่ก่อน
+	ListTournaments(status string) ([]models.Tournament, error)
+	// AddTournamentPlayer สมัครผู้เล่นเข้าการแข่งขัน ผู้เรียกต้องตรวจว่ายังไม่ได้สมัครและยังไม่เต็ม
+	AddTournamentPlayer(tournamentID, userID string) error
+	// ListTournamentPlayers คืนผู้เล่นเรียงตามลำดับที่สมัคร พร้อม Username
+	ListTournamentPlayers(tournamentID string) ([]models.TournamentPlayer, error)
+	// ListTournamentMatches คืนทุกคู่เรียงตามรอบและโต๊ะ
+	ListTournamentMatches(tournamentID string) ([]models.TournamentMatch, error)
+	// SaveTournament บันทึกสถานะการแข่งขัน ผลรวมของ players ผลของ finished และเพิ่มคู่ใหม่ created ใน transaction เดียว
+	SaveTournament(t *models.Tournament, players []models.TournamentPlayer, finished, created []models.TournamentMatch) error
+}
+
+// FriendRepository คำขอเป็นเพื่อนและรายชื่อเพื่อน ระหว่างสองคนมีได้แถวเดียวไม่ว่าใครเป็นคนขอ
+type FriendRepository interface {
+	// GetFriendship คืนความสัมพันธ์ระหว่างสองคนไม่ว่าใครเป็นคนขอ ErrNotFound ถ้าไม่มี
+	GetFriendship(userA, userB string) (*models.Friendship, error)
+	// CreateFriendRequest ส่งคำขอใหม่ ผู้เรียกต้องตรวจก่อนว่ายังไม่มีความสัมพันธ์กันอยู่
+	CreateFriendRequest(requesterID, addresseeID string) error
+	// AcceptFriendRequest ตอบรับคำขอจาก requester ถึง addressee ErrNotFound ถ้าไม่มีคำขอที่รออยู่
+	AcceptFriendRequest(requesterID, addresseeID string) error
+	// DeleteFriendship ลบคำขอที่รออยู่หรือเพื่อน ไม่ว่าใครเป็นคนขอ ErrNotFound ถ้าไม่มี
+	DeleteFriendship(userA, userB string) error
+	// ListFriends คืนเพื่อนและคำขอทั้งขาเข้าและขาออกของผู้เล่น เรียงตามชื่อ
+	ListFriends(userID string) ([]models.Friend, error)
+}
+
+// board ของ leaderboard
+const (
+	BoardCampaign = "campaign" // ด่าน campaign ที่ไปถึง
+	BoardLevel    = "level"
+	BoardGold     = "gold"
+	BoardStreak   = "streak" // ชนะ campaign ติดกันมากที่สุด
+)
+
+// LeaderboardRepository อ่านอันดับผู้เล่น ใช้ตอนสร้าง cache ของ leaderboard ไม่ได้เรียกทุก request
+type LeaderboardRepository interface {
+	// TopUsers คืนผู้เล่น limit คนแรกของ board เรียงมากไปน้อย class ว่างคือทุกคลาส
+	TopUsers(board, class string, limit int) ([]models.LeaderboardEntry, error)
+}
+
+type Store interface {
+	Users() UserRepository
+	Decks() DeckRepository
+	Wallets() WalletRepository
+	Matches() MatchRepository
+	Sessions() SessionRepository
+	LiveMatches() LiveMatchRepository
+	Ratings() RatingRepository
+	PVPMatches() PVPMatchRepository
+	Seasons() SeasonRepository
+	Tournaments() TournamentRepository
+	Friends() FriendRepository
+	Leaderboards() LeaderboardRepository
+	Close() error
+}
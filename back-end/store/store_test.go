@@ -0,0 +1,303 @@
+package store_test
+
+import (
+	"clash_and_card/migrations"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// contractStores คืน implementation ทุกตัวที่ต้องทำตามสัญญาใน store.go เหมือนกัน
+// SQLite ใช้ไฟล์ชั่วคราวที่ผ่าน migration แล้ว ทำหน้าที่แทน SQLStore ทั้งสอง dialect
+func contractStores() map[string]func(t *testing.T) store.Store {
+	return map[string]func(t *testing.T) store.Store{
+		"memory": func(t *testing.T) store.Store { return store.NewMemory() },
+		"sqlite": func(t *testing.T) store.Store {
+			st, err := store.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("OpenSQLite: %v", err)
+			}
+			t.Cleanup(func() { st.Close() })
+			if _, err := migrations.Up(st.DB(), st.Dialect()); err != nil {
+				t.Fatalf("migrations.Up: %v", err)
+			}
+			return st
+		},
+	}
+}
+
+// runContract รันเทสเดียวกันกับ store ทุกแบบ
+func runContract(t *testing.T, test func(t *testing.T, st store.Store)) {
+	for name, open := range contractStores() {
+		t.Run(name, func(t *testing.T) { test(t, open(t)) })
+	}
+}
+
+func createUser(t *testing.T, st store.Store, rating int) *models.User {
+	t.Helper()
+	id := uuid.New().String()
+	u := &models.User{
+		ID:       id,
+		Username: "Player",
+		Email:    id + "@example.com",
+		Stat:     models.UnitStat{Atk: 10, Def: 10, HP: 100, Spd: 10},
+		Level:    1,
+		Gold:     100,
+		Class:    "warrior",
+		Rating:   rating,
+	}
+	if err := st.Users().CreateUser(u, "hash", []models.DeckCard{{CardType: "rock", Quantity: 5}}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return u
+}
+
+func getUser(t *testing.T, st store.Store, id string) *models.User {
+	t.Helper()
+	u, err := st.Users().GetUser(id)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	return u
+}
+
+func TestUpdateUserPreservesRating(t *testing.T) {
+	runContract(t, func(t *testing.T, st store.Store) {
+		u := createUser(t, st, 1000)
+
+		err := st.Users().UpdateUser(u.ID, func(u *models.User) error {
+			u.Gold += 50
+			u.Rating = 9999 // rating แก้ผ่าน RatingRepository เท่านั้น
+			u.ID = "other"  // id เปลี่ยนไม่ได้
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("UpdateUser: %v", err)
+		}
+		got := getUser(t, st, u.ID)
+		if got.Gold != 150 {
+			t.Fatalf("Gold = %d, want 150", got.Gold)
+		}
+		if got.Rating != 1000 {
+			t.Fatalf("Rating = %d, want 1000", got.Rating)
+		}
+
+		// fn คืน error ต้องไม่บันทึกอะไรเลย
+		errStop := errors.New("stop")
+		err = st.Users().UpdateUser(u.ID, func(u *models.User) error {
+			u.Gold = 0
+			return errStop
+		})
+		if err != errStop {
+			t.Fatalf("UpdateUser err = %v, want %v", err, errStop)
+		}
+		if got := getUser(t, st, u.ID); got.Gold != 150 {
+			t.Fatalf("Gold after failed update = %d, want 150", got.Gold)
+		}
+	})
+}
+
+func TestUpdateRatings(t *testing.T) {
+	runContract(t, func(t *testing.T, st store.Store) {
+		a := createUser(t, st, 1000)
+		b := createUser(t, st, 1200)
+
+		err := st.Ratings().UpdateRatings("match-1", a.ID, b.ID, func(ca, cb *models.RatingChange) {
+			if ca.UserID != a.ID || ca.OpponentID != b.ID || ca.MatchID != "match-1" || ca.Before != 1000 {
+				t.Errorf("change A = %+v", ca)
+			}
+			if cb.UserID != b.ID || cb.OpponentID != a.ID || cb.MatchID != "match-1" || cb.Before != 1200 {
+				t.Errorf("change B = %+v", cb)
+			}
+			ca.Result, ca.After = "Win", 1020
+			cb.Result, cb.After = "Lose", 1180
+		})
+		if err != nil {
+			t.Fatalf("UpdateRatings: %v", err)
+		}
+		if got := getUser(t, st, a.ID).Rating; got != 1020 {
+			t.Fatalf("rating A = %d, want 1020", got)
+		}
+		if got := getUser(t, st, b.ID).Rating; got != 1180 {
+			t.Fatalf("rating B = %d, want 1180", got)
+		}
+
+		if err := st.Ratings().UpdateRatings("match-2", a.ID, b.ID, func(ca, cb *models.RatingChange) {
+			ca.Result, ca.After = "Draw", ca.Before+1
+			cb.Result, cb.After = "Draw", cb.Before-1
+		}); err != nil {
+			t.Fatalf("UpdateRatings: %v", err)
+		}
+
+		// ล่าสุดก่อน และไม่เกิน limit
+		history, err := st.Ratings().RatingHistory(a.ID, 10)
+		if err != nil {
+			t.Fatalf("RatingHistory: %v", err)
+		}
+		if len(history) != 2 || history[0].MatchID != "match-2" || history[1].MatchID != "match-1" {
+			t.Fatalf("history = %+v", history)
+		}
+		if h := history[1]; h.Result != "Win" || h.Before != 1000 || h.After != 1020 || h.OpponentID != b.ID || h.CreatedAt == "" {
+			t.Fatalf("history[1] = %+v", h)
+		}
+		if history, _ := st.Ratings().RatingHistory(a.ID, 1); len(history) != 1 {
+			t.Fatalf("limit 1: got %d entries", len(history))
+		}
+
+		if err := st.Ratings().UpdateRatings("match-3", a.ID, "missing", func(ca, cb *models.RatingChange) {}); err != store.ErrNotFound {
+			t.Fatalf("missing player: err = %v, want %v", err, store.ErrNotFound)
+		}
+		if got := getUser(t, st, a.ID).Rating; got != 1021 {
+			t.Fatalf("rating A after failed update = %d, want 1021", got)
+		}
+	})
+}
+
+func TestRecordPVPResult(t *testing.T) {
+	runContract(t, func(t *testing.T, st store.Store) {
+		a := createUser(t, st, 1000)
+		b := createUser(t, st, 1000)
+		result := &models.PVPMatchResult{ID: "pvp-1", RoomID: "room-1", PlayerAID: a.ID, PlayerBID: b.ID, WinnerID: a.ID, Reason: "hp"}
+
+		// fn คืน error กับคนใดคนหนึ่ง ต้องไม่บันทึกทั้งผลและรางวัล
+		errStop := errors.New("stop")
+		err := st.PVPMatches().RecordPVPResult(result, func(slot string, u *models.User) error {
+			u.Gold += 10
+			if slot == "B" {
+				return errStop
+			}
+			return nil
+		})
+		if err != errStop {
+			t.Fatalf("RecordPVPResult err = %v, want %v", err, errStop)
+		}
+		if got := getUser(t, st, a.ID).Gold; got != 100 {
+			t.Fatalf("gold A after failed record = %d, want 100", got)
+		}
+
+		seen := map[string]string{}
+		err = st.PVPMatches().RecordPVPResult(result, func(slot string, u *models.User) error {
+			seen[slot] = u.ID
+			if slot == "A" {
+				u.Gold += 30
+			} else {
+				u.Gold += 10
+			}
+			u.Rating = 0 // rating แก้ผ่าน RatingRepository เท่านั้น
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("RecordPVPResult: %v", err)
+		}
+		if seen["A"] != a.ID || seen["B"] != b.ID {
+			t.Fatalf("fn got slots %v", seen)
+		}
+		if got := getUser(t, st, a.ID); got.Gold != 130 || got.Rating != 1000 {
+			t.Fatalf("user A gold %d rating %d, want 130 1000", got.Gold, got.Rating)
+		}
+		if got := getUser(t, st, b.ID); got.Gold != 110 || got.Rating != 1000 {
+			t.Fatalf("user B gold %d rating %d, want 110 1000", got.Gold, got.Rating)
+		}
+
+		// match เดียวบันทึกได้ครั้งเดียว
+		if err := st.PVPMatches().RecordPVPResult(result, func(string, *models.User) error { return nil }); err == nil {
+			t.Fatal("recording the same match twice succeeded")
+		}
+
+		missing := &models.PVPMatchResult{ID: "pvp-2", PlayerAID: a.ID, PlayerBID: "missing", Reason: "hp"}
+		if err := st.PVPMatches().RecordPVPResult(missing, func(string, *models.User) error { return nil }); err != store.ErrNotFound {
+			t.Fatalf("missing player: err = %v, want %v", err, store.ErrNotFound)
+		}
+	})
+}
+
+func TestNotFound(t *testing.T) {
+	runContract(t, func(t *testing.T, st store.Store) {
+		a := createUser(t, st, 1000)
+		b := createUser(t, st, 1000)
+
+		tests := []struct {
+			name string
+			call func() error
+		}{
+			{"GetUser", func() error { _, err := st.Users().GetUser("missing"); return err }},
+			{"GetCredentials", func() error { _, _, err := st.Users().GetCredentials("missing@example.com"); return err }},
+			{"UpdateUser", func() error {
+				return st.Users().UpdateUser("missing", func(*models.User) error { return nil })
+			}},
+			{"GetMatch", func() error { _, err := st.Matches().GetMatch("missing"); return err }},
+			{"RotateRefreshToken", func() error {
+				_, _, err := st.Sessions().RotateRefreshToken("missing", "new", time.Now().Add(time.Hour))
+				return err
+			}},
+			{"RevokeSessionByToken", func() error { return st.Sessions().RevokeSessionByToken("missing") }},
+			{"LatestSeason", func() error { _, err := st.Seasons().LatestSeason(); return err }},
+			{"GetTournament", func() error { _, err := st.Tournaments().GetTournament("missing"); return err }},
+			{"GetFriendship", func() error { _, err := st.Friends().GetFriendship(a.ID, b.ID); return err }},
+			{"AcceptFriendRequest", func() error { return st.Friends().AcceptFriendRequest(a.ID, b.ID) }},
+			{"DeleteFriendship", func() error { return st.Friends().DeleteFriendship(a.ID, b.ID) }},
+		}
+		for _, tt := range tests {
+			if err := tt.call(); err != store.ErrNotFound {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, store.ErrNotFound)
+			}
+		}
+
+		season := &models.Season{Number: 1, StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour)}
+		if err := st.Seasons().CreateSeason(season); err != nil {
+			t.Fatalf("CreateSeason: %v", err)
+		}
+		if _, err := st.Seasons().SeasonStanding(season.ID, a.ID); err != store.ErrNotFound {
+			t.Errorf("SeasonStanding: err = %v, want %v", err, store.ErrNotFound)
+		}
+	})
+}
+
+func TestBuyCardConcurrent(t *testing.T) {
+	runContract(t, func(t *testing.T, st store.Store) {
+		u := createUser(t, st, 1000) // ทอง 100 การ์ด rock 5 ใบ
+		const price, buyers = 30, 20
+
+		var wg sync.WaitGroup
+		errs := make(chan error, buyers)
+		for i := 0; i < buyers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- st.Wallets().BuyCard(u.ID, "rock", price)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		bought := 0
+		for err := range errs {
+			switch err {
+			case nil:
+				bought++
+			case store.ErrNotEnoughGold:
+			default:
+				t.Fatalf("BuyCard: %v", err)
+			}
+		}
+		if bought != 100/price {
+			t.Fatalf("bought %d cards, want %d", bought, 100/price)
+		}
+		if got := getUser(t, st, u.ID).Gold; got != 100-bought*price {
+			t.Fatalf("gold = %d, want %d", got, 100-bought*price)
+		}
+		deck, err := st.Decks().GetDeck(u.ID)
+		if err != nil {
+			t.Fatalf("GetDeck: %v", err)
+		}
+		if len(deck) != 1 || deck[0].Quantity != 5+bought {
+			t.Fatalf("deck = %+v, want %d rock", deck, 5+bought)
+		}
+	})
+}
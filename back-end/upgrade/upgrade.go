@@ -1,33 +1,20 @@
 package upgrade
 
 import (
+	"clash_and_card/models"
+	"clash_and_card/store"
 	"clash_and_card/user"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
-func UpgradeStatHandler(db *sql.DB) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		var tokenStr string
-		fmt.Sscanf(authHeader, "Bearer %s", &tokenStr)
-		if tokenStr == "" {
-			http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
+var errNotEnoughStatPoint = errors.New("not enough stat points")
 
-		userID, err := user.ExtractUserIDFromToken(tokenStr)
-		if err != nil || userID == "0" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+func UpgradeStatHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := user.UserIDFromContext(r.Context())
 
 		var req struct {
 			Type string `json:"type"`
@@ -52,25 +39,30 @@ func UpgradeStatHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		var statPoint int
-		err = db.QueryRow("SELECT stat_point FROM users WHERE id = ?", userID).Scan(&statPoint)
-		if err != nil {
+		err := st.Users().UpdateUser(userID, func(u *models.User) error {
+			if u.StatPoint < 1 {
+				return errNotEnoughStatPoint
+			}
+			switch stat {
+			case "atk":
+				u.Stat.Atk += increase
+			case "def":
+				u.Stat.Def += increase
+			case "spd":
+				u.Stat.Spd += increase
+			case "hp":
+				u.Stat.HP += increase
+			}
+			u.StatPoint--
+			return nil
+		})
+		if err == store.ErrNotFound {
 			http.Error(w, "User not found", http.StatusNotFound)
 			return
-		}
-		if statPoint < 1 {
+		} else if err == errNotEnoughStatPoint {
 			http.Error(w, "Not enough stat points", http.StatusBadRequest)
 			return
-		}
-
-		query := fmt.Sprintf(`
-			UPDATE users
-			SET %s = %s + ?, stat_point = stat_point - 1
-			WHERE id = ?
-		`, stat, stat)
-
-		_, err = db.Exec(query, increase, userID)
-		if err != nil {
+		} else if err != nil {
 			http.Error(w, "Failed to update stat", http.StatusInternalServerError)
 			return
 		}
@@ -79,31 +71,13 @@ func UpgradeStatHandler(db *sql.DB) http.HandlerFunc {
 		w.Write([]byte(`{"message":"Stat upgraded successfully"}`))
 	}
 }
-func BuyCardHandler(db *sql.DB) http.HandlerFunc {
+
+// BuyCardHandler ซื้อการ์ดหนึ่งใบในราคา cardPrice
+func BuyCardHandler(st store.Store, cardPrice int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		fmt.Println("Buy call")
 
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			fmt.Println("Missing Authorization header")
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		var tokenStr string
-		fmt.Sscanf(authHeader, "Bearer %s", &tokenStr)
-		if tokenStr == "" {
-			fmt.Println("Invalid Authorization header")
-			http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		userID, err := user.ExtractUserIDFromToken(tokenStr)
-		if err != nil || userID == "0" {
-			fmt.Println("Unauthorized:", err)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+		userID, _ := user.UserIDFromContext(r.Context())
 
 		var req struct {
 			Type string `json:"type"`
@@ -114,74 +88,18 @@ func BuyCardHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		tx, err := db.Begin()
-		if err != nil {
-			fmt.Println("Failed to begin transaction:", err)
-			http.Error(w, "Failed to begin transaction", http.StatusInternalServerError)
-			return
-		}
-
-		defer func() {
-			if p := recover(); p != nil {
-				fmt.Println("Panic recovered:", p)
-				tx.Rollback()
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-			}
-		}()
-
-		var gold int
-		err = tx.QueryRow(`SELECT gold FROM users WHERE id = ? FOR UPDATE`, userID).Scan(&gold)
-		if err != nil {
-			fmt.Println("User not found or query error:", err)
-			tx.Rollback()
+		err := st.Wallets().BuyCard(userID, req.Type, cardPrice)
+		if err == store.ErrNotFound {
+			fmt.Println("User not found:", userID)
 			http.Error(w, "User not found", http.StatusNotFound)
 			return
-		}
-
-		if gold < 500 {
-			fmt.Println("Not enough gold:", gold)
-			tx.Rollback()
+		} else if err == store.ErrNotEnoughGold {
+			fmt.Println("Not enough gold:", userID)
 			http.Error(w, "Not enough gold", http.StatusBadRequest)
 			return
-		}
-
-		_, err = tx.Exec(`UPDATE users SET gold = gold - 500 WHERE id = ?`, userID)
-		if err != nil {
-			fmt.Println("Failed to deduct gold:", err)
-			tx.Rollback()
-			http.Error(w, "Failed to deduct gold", http.StatusInternalServerError)
-			return
-		}
-
-		res, err := tx.Exec(`UPDATE decks SET quantity = quantity + 1 WHERE user_id = ? AND card_type = ?`, userID, req.Type)
-		if err != nil {
-			fmt.Println("Failed to update deck:", err)
-			tx.Rollback()
-			http.Error(w, "Failed to update deck", http.StatusInternalServerError)
-			return
-		}
-
-		rowsAffected, err := res.RowsAffected()
-		if err != nil {
-			fmt.Println("Failed to check update result:", err)
-			tx.Rollback()
-			http.Error(w, "Failed to check update result", http.StatusInternalServerError)
-			return
-		}
-
-		if rowsAffected == 0 {
-			_, err = tx.Exec(`INSERT INTO decks (user_id, card_type, quantity) VALUES (?, ?, 1)`, userID, req.Type)
-			if err != nil {
-				fmt.Println("Failed to insert new deck row:", err)
-				tx.Rollback()
-				http.Error(w, "Failed to insert new deck row", http.StatusInternalServerError)
-				return
-			}
-		}
-
-		if err = tx.Commit(); err != nil {
-			fmt.Println("Failed to commit transaction:", err)
-			http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
+		} else if err != nil {
+			fmt.Println("Failed to buy card:", err)
+			http.Error(w, "Failed to buy card", http.StatusInternalServerError)
 			return
 		}
 
@@ -1,45 +1,29 @@
 package user
 
 import (
-	"clash_and_card/models"
-	"database/sql"
+	"clash_and_card/store"
 	"encoding/json"
 	"fmt"
 	"net/http"
 )
 
-func GetUserHandler(db *sql.DB) http.HandlerFunc {
+func GetUserHandler(st store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		fmt.Println("🔍 [GetUserHandler] Called")
 
-		authHeader := r.Header.Get("Authorization")
-		fmt.Println("🔑 Authorization Header:", authHeader)
-		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-			fmt.Println("❌ Missing Authorization header")
-			return
-		}
+		userID, _ := UserIDFromContext(r.Context())
 
-		tokenStr := ""
-		fmt.Sscanf(authHeader, "Bearer %s", &tokenStr)
-		fmt.Println("🧾 Extracted Token:", tokenStr)
-		if tokenStr == "" {
-			http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
-			fmt.Println("❌ Invalid Authorization format")
+		u, err := st.Users().GetUser(userID)
+		if err == store.ErrNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+			fmt.Println("❌ User not found for ID:", userID)
 			return
-		}
-
-		userID, err := ExtractUserIDFromToken(tokenStr)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			fmt.Println("❌ Token extraction failed:", err)
+		} else if err != nil {
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			fmt.Println("❌ Load user error:", err)
 			return
 		}
 
-		query := `SELECT id, username, email, atk, def, hp, spd, level, current_campaign_level, exp, gold, created_at, class, stat_point FROM users WHERE id = ?`
-
-		row := db.QueryRow(query, userID)
-
 		type Stat struct {
 			Atk int `json:"atk"`
 			Def int `json:"def"`
@@ -47,7 +31,7 @@ func GetUserHandler(db *sql.DB) http.HandlerFunc {
 			Spd int `json:"spd"`
 		}
 
-		var user struct {
+		user := struct {
 			ID                   string `json:"id"`
 			Username             string `json:"username"`
 			Email                string `json:"email"`
@@ -59,33 +43,24 @@ func GetUserHandler(db *sql.DB) http.HandlerFunc {
 			CreatedAt            string `json:"created_at"`
 			Class                string `json:"class"`
 			StatPoint            int    `json:"statPoint"`
-		}
-
-		err = row.Scan(
-			&user.ID,
-			&user.Username,
-			&user.Email,
-			&user.Stat.Atk,
-			&user.Stat.Def,
-			&user.Stat.Hp,
-			&user.Stat.Spd,
-			&user.Level,
-			&user.CurrentCampaignLevel,
-			&user.Exp,
-			&user.Gold,
-			&user.CreatedAt,
-			&user.Class,
-			&user.StatPoint,
-		)
-
-		if err == sql.ErrNoRows {
-			http.Error(w, "User not found", http.StatusNotFound)
-			fmt.Println("❌ User not found for ID:", userID)
-			return
-		} else if err != nil {
-			http.Error(w, "Server error", http.StatusInternalServerError)
-			fmt.Println("❌ SQL Scan Error:", err)
-			return
+			Rating               int    `json:"rating"`
+			CampaignWinStreak    int    `json:"campaignWinStreak"`
+			BestCampaignStreak   int    `json:"bestCampaignWinStreak"`
+		}{
+			ID:                   u.ID,
+			Username:             u.Username,
+			Email:                u.Email,
+			Stat:                 Stat{Atk: u.Stat.Atk, Def: u.Stat.Def, Hp: u.Stat.HP, Spd: u.Stat.Spd},
+			Level:                u.Level,
+			CurrentCampaignLevel: u.CurrentCampaignLevel,
+			Exp:                  u.Exp,
+			Gold:                 u.Gold,
+			CreatedAt:            u.CreatedAt,
+			Class:                u.Class,
+			StatPoint:            u.StatPoint,
+			Rating:               u.Rating,
+			CampaignWinStreak:    u.CampaignWinStreak,
+			BestCampaignStreak:   u.BestCampaignWinStreak,
 		}
 
 		fmt.Println("✅ User data fetched successfully:", user)
@@ -100,43 +75,15 @@ func GetUserHandler(db *sql.DB) http.HandlerFunc {
 	}
 }
 
-func GetUserDeckHandler(db *sql.DB) http.HandlerFunc {
+func GetUserDeckHandler(st store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
-			return
-		}
+		userID, _ := UserIDFromContext(r.Context())
 
-		var tokenStr string
-		fmt.Sscanf(authHeader, "Bearer %s", &tokenStr)
-		if tokenStr == "" {
-			http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		userID, err := ExtractUserIDFromToken(tokenStr)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		rows, err := db.Query("SELECT card_type, quantity FROM decks WHERE user_id = ?", userID)
+		deck, err := st.Decks().GetDeck(userID)
 		if err != nil {
 			http.Error(w, "Error loading deck", http.StatusInternalServerError)
 			return
 		}
-		defer rows.Close()
-
-		var deck []models.DeckCard
-		for rows.Next() {
-			var card models.DeckCard
-			if err := rows.Scan(&card.CardType, &card.Quantity); err != nil {
-				http.Error(w, "Error reading card", http.StatusInternalServerError)
-				return
-			}
-			deck = append(deck, card)
-		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(deck)
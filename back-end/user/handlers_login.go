@@ -1,7 +1,8 @@
 package user
 
 import (
-	"database/sql"
+	"clash_and_card/models"
+	"clash_and_card/store"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -12,7 +13,26 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret = []byte("SECRET_KEY")
+var (
+	jwtSecret       = []byte("SECRET_KEY")
+	tokenTTL        = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+
+	// sessions ใช้ตรวจว่า session ของ access token ยังไม่ถูกเพิกถอน
+	sessions store.SessionRepository
+)
+
+// ConfigureTokens ตั้ง secret อายุของ access token และ refresh token ต้องเรียกก่อนเริ่มรับ request
+func ConfigureTokens(secret string, ttl, refreshTTL time.Duration) {
+	jwtSecret = []byte(secret)
+	tokenTTL = ttl
+	refreshTokenTTL = refreshTTL
+}
+
+// ConfigureSessions ตั้งที่เก็บ session ที่ ExtractUserIDFromToken ใช้ตรวจการเพิกถอน
+func ConfigureSessions(repo store.SessionRepository) {
+	sessions = repo
+}
 
 type LoginRequest struct {
 	Email    string `json:"email"`
@@ -25,13 +45,7 @@ type RegisterRequest struct {
 	Class    string `json:"class"`
 }
 
-func isEmailExists(db *sql.DB, email string) (bool, error) {
-	var exists bool
-	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", email).Scan(&exists)
-	return exists, err
-}
-
-func LoginHandler(db *sql.DB) http.HandlerFunc {
+func LoginHandler(st store.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req LoginRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -40,10 +54,8 @@ func LoginHandler(db *sql.DB) http.HandlerFunc {
 		}
 
 		// ดึง hash password จาก db
-		var userID string
-		var hashedPassword string
-		err := db.QueryRow(`SELECT id, password FROM users WHERE email = ?`, req.Email).Scan(&userID, &hashedPassword)
-		if err == sql.ErrNoRows {
+		userID, hashedPassword, err := st.Users().GetCredentials(req.Email)
+		if err == store.ErrNotFound {
 			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
 			return
 		} else if err != nil {
@@ -57,19 +69,19 @@ func LoginHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		// ออก token
-		token, err := CreateToken(userID)
+		// ออก access token และ refresh token ของ session ใหม่
+		tokens, err := startSession(st, userID)
 		if err != nil {
 			http.Error(w, "Token generation failed", http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"token": token})
+		json.NewEncoder(w).Encode(tokens)
 	}
 }
 
-func CheckEmailHandler(db *sql.DB) http.HandlerFunc {
+func CheckEmailHandler(st store.Store) http.HandlerFunc {
 	type Req struct {
 		Email string `json:"email"`
 	}
@@ -87,7 +99,7 @@ func CheckEmailHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "Invalid input", http.StatusBadRequest)
 			return
 		}
-		exists, err := isEmailExists(db, req.Email)
+		exists, err := st.Users().EmailExists(req.Email)
 		if err != nil {
 			http.Error(w, "Server error", http.StatusInternalServerError)
 			return
@@ -110,7 +122,7 @@ func initDeck(class string) (rock int, paper int, scissors int) {
 	}
 }
 
-func RegisterHandler(db *sql.DB) http.HandlerFunc {
+func RegisterHandler(st store.Store, startingStats models.UnitStat) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -122,7 +134,7 @@ func RegisterHandler(db *sql.DB) http.HandlerFunc {
 			http.Error(w, "Invalid input", http.StatusBadRequest)
 			return
 		}
-		exists, err := isEmailExists(db, req.Email)
+		exists, err := st.Users().EmailExists(req.Email)
 		if err != nil {
 			http.Error(w, "Server error", http.StatusInternalServerError)
 			return
@@ -137,53 +149,56 @@ func RegisterHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 		userID := uuid.New().String()
-		_, err = db.Exec(
-			`INSERT INTO users (
-                id, username, email, password,
-                atk, def, spd, hp,
-                level, current_campaign_level,
-                exp, gold, created_at,
-                class, stat_point
-            ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			userID,
-			"Player", req.Email, string(hashedPassword), 20, 10, 10, 50, 1, 1, 0, 0, time.Now(), req.Class, 0,
-		)
-		if err != nil {
-			http.Error(w, "Server error", http.StatusInternalServerError)
-			return
+		newUser := &models.User{
+			ID:                   userID,
+			Username:             "Player",
+			Email:                req.Email,
+			Stat:                 startingStats,
+			Level:                1,
+			CurrentCampaignLevel: 1,
+			Exp:                  0,
+			Gold:                 0,
+			Class:                req.Class,
+			StatPoint:            0,
+			Rating:               models.InitialRating,
 		}
 
 		initRock, initPaper, initScissors := initDeck(req.Class)
+		deck := []models.DeckCard{
+			{CardType: "rock", Quantity: initRock},
+			{CardType: "paper", Quantity: initPaper},
+			{CardType: "scissors", Quantity: initScissors},
+		}
 
-		_, err = db.Exec("INSERT INTO decks (user_id, card_type, quantity) VALUES (?, ?, ?), (?, ?, ?), (?, ?, ?)",
-			userID, "rock", initRock,
-			userID, "paper", initPaper,
-			userID, "scissors", initScissors,
-		)
-		if err != nil {
+		if err := st.Users().CreateUser(newUser, string(hashedPassword), deck); err != nil {
 			http.Error(w, "Server error", http.StatusInternalServerError)
 			return
 		}
 
-		// ออก token
-		token, err := CreateToken(userID)
+		// ออก access token และ refresh token ของ session ใหม่
+		tokens, err := startSession(st, userID)
 		if err != nil {
 			http.Error(w, "Token generation failed", http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]string{"token": token})
+		json.NewEncoder(w).Encode(tokens)
 	}
 }
 
+// ExtractUserIDFromToken ตรวจ access token และคืน user id
+// token ที่ไม่มี session หรือ session ถูกเพิกถอนแล้ว (logout, ใช้ refresh token ซ้ำ) จะใช้ไม่ได้
 func ExtractUserIDFromToken(tokenStr string) (string, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
-	})
-	if err != nil || !token.Valid {
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
 		return "", err
 	}
+	if !token.Valid {
+		return "", errors.New("invalid token")
+	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
@@ -195,13 +210,30 @@ func ExtractUserIDFromToken(tokenStr string) (string, error) {
 		return "", errors.New("user_id claim missing or invalid")
 	}
 
+	sessionID, ok := claims["sid"].(string)
+	if !ok || sessionID == "" {
+		return "", errors.New("sid claim missing or invalid")
+	}
+	if sessions == nil {
+		return "", errors.New("session store not configured")
+	}
+	active, err := sessions.SessionActive(sessionID)
+	if err != nil {
+		return "", err
+	}
+	if !active {
+		return "", errors.New("session revoked")
+	}
+
 	return userID, nil
 }
 
-func CreateToken(userID string) (string, error) {
+// CreateToken ออก access token อายุสั้นของ session นี้
+func CreateToken(userID, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // token หมดอายุ 24 ชั่วโมง
+		"sid":     sessionID,
+		"exp":     time.Now().Add(tokenTTL).Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
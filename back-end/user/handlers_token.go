@@ -0,0 +1,139 @@
+package user
+
+import (
+	"clash_and_card/store"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tokenPair คำตอบของ login, register และ refresh
+// "token" คือ access token ชื่อเดิมที่ frontend ใช้อยู่
+type tokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"` // อายุของ access token (วินาที)
+}
+
+// newRefreshToken สุ่ม refresh token และคืน hash ที่ใช้เก็บในฐานข้อมูล
+func newRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTokenPair(userID, sessionID, refreshToken string) (tokenPair, error) {
+	access, err := CreateToken(userID, sessionID)
+	if err != nil {
+		return tokenPair{}, err
+	}
+	return tokenPair{
+		Token:        access,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(tokenTTL / time.Second),
+	}, nil
+}
+
+// startSession เปิด session ใหม่ตอน login/register
+func startSession(st store.Store, userID string) (tokenPair, error) {
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		return tokenPair{}, err
+	}
+	sessionID := uuid.New().String()
+	if err := st.Sessions().CreateSession(sessionID, userID, hash, time.Now().Add(refreshTokenTTL)); err != nil {
+		return tokenPair{}, err
+	}
+	return newTokenPair(userID, sessionID, refreshToken)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefreshTokenHandler แลก refresh token เป็น access token ใหม่ และหมุน refresh token ทุกครั้ง
+// refresh token ใช้ได้ครั้งเดียว ถ้าถูกใช้ซ้ำจะถือว่ารั่วและเพิกถอนทั้ง session
+func RefreshTokenHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req refreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		newToken, newHash, err := newRefreshToken()
+		if err != nil {
+			http.Error(w, "Token generation failed", http.StatusInternalServerError)
+			return
+		}
+
+		sessionID, userID, err := st.Sessions().RotateRefreshToken(
+			hashRefreshToken(req.RefreshToken), newHash, time.Now().Add(refreshTokenTTL))
+		switch err {
+		case nil:
+		case store.ErrNotFound:
+			writeUnauthorized(w, "Invalid refresh token")
+			return
+		case store.ErrTokenExpired:
+			writeUnauthorized(w, "Refresh token expired")
+			return
+		case store.ErrSessionRevoked, store.ErrTokenReused:
+			if err == store.ErrTokenReused {
+				log.Println("[WARN] refresh token reused, session revoked")
+			}
+			writeUnauthorized(w, "Session revoked")
+			return
+		default:
+			log.Println("[ERROR] Rotate refresh token:", err)
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+
+		tokens, err := newTokenPair(userID, sessionID, newToken)
+		if err != nil {
+			http.Error(w, "Token generation failed", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	}
+}
+
+// LogoutHandler เพิกถอน session ของ refresh token นี้ทั้งชุด
+// access token ที่ออกจาก session เดียวกันจะใช้ไม่ได้ทันที
+func LogoutHandler(st store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req refreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+
+		// token ที่ไม่รู้จักถือว่า logout ไปแล้ว
+		err := st.Sessions().RevokeSessionByToken(hashRefreshToken(req.RefreshToken))
+		if err != nil && err != store.ErrNotFound {
+			log.Println("[ERROR] Revoke session:", err)
+			http.Error(w, "Server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Logged out"})
+	}
+}
@@ -0,0 +1,168 @@
+package user
+
+import (
+	"bytes"
+	"clash_and_card/migrations"
+	"clash_and_card/models"
+	"clash_and_card/store"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// tokenTestStores คืน store ทุกแบบที่ session ต้องทำงานเหมือนกัน SQLite ใช้ไฟล์ชั่วคราวที่ผ่าน migration แล้ว
+func tokenTestStores() map[string]func(t *testing.T) store.Store {
+	return map[string]func(t *testing.T) store.Store{
+		"memory": func(t *testing.T) store.Store { return store.NewMemory() },
+		"sqlite": func(t *testing.T) store.Store {
+			st, err := store.OpenSQLite(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatalf("OpenSQLite: %v", err)
+			}
+			t.Cleanup(func() { st.Close() })
+			if _, err := migrations.Up(st.DB(), st.Dialect()); err != nil {
+				t.Fatalf("migrations.Up: %v", err)
+			}
+			return st
+		},
+	}
+}
+
+// newTokenTestUser ตั้ง package ให้ใช้ store นี้ สร้างผู้เล่นหนึ่งคนและเปิด session แรกให้
+func newTokenTestUser(t *testing.T, st store.Store) (string, tokenPair) {
+	t.Helper()
+	ConfigureTokens("test-secret", time.Hour, 24*time.Hour)
+	ConfigureSessions(st.Sessions())
+
+	userID := uuid.New().String()
+	u := &models.User{ID: userID, Username: "Player", Email: userID + "@example.com", Level: 1, Class: "warrior", Rating: models.InitialRating}
+	if err := st.Users().CreateUser(u, "hash", nil); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	tokens, err := startSession(st, userID)
+	if err != nil {
+		t.Fatalf("startSession: %v", err)
+	}
+	return userID, tokens
+}
+
+func postRefresh(h http.HandlerFunc, refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(refreshRequest{RefreshToken: refreshToken})
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("POST", "/api/token/refresh", bytes.NewReader(body)))
+	return rec
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	for name, open := range tokenTestStores() {
+		t.Run(name, func(t *testing.T) {
+			st := open(t)
+			userID, first := newTokenTestUser(t, st)
+			refresh := RefreshTokenHandler(st)
+
+			rec := postRefresh(refresh, first.RefreshToken)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("refresh: status %d body %s", rec.Code, rec.Body)
+			}
+			var second tokenPair
+			if err := json.NewDecoder(rec.Body).Decode(&second); err != nil {
+				t.Fatalf("decode refresh response: %v", err)
+			}
+			if second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+				t.Fatalf("refresh token was not rotated: %q", second.RefreshToken)
+			}
+			if got, err := ExtractUserIDFromToken(second.Token); err != nil || got != userID {
+				t.Fatalf("new access token: user %q err %v, want %q", got, err, userID)
+			}
+
+			// ใช้ token เดิมซ้ำ ถือว่ารั่ว session ทั้งชุดต้องถูกเพิกถอน
+			if rec := postRefresh(refresh, first.RefreshToken); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("reuse old refresh token: status %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if rec := postRefresh(refresh, second.RefreshToken); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("rotated refresh token after reuse: status %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			for _, access := range []string{first.Token, second.Token} {
+				if _, err := ExtractUserIDFromToken(access); err == nil {
+					t.Fatal("access token still valid after refresh token reuse")
+				}
+			}
+		})
+	}
+}
+
+func TestRotateRefreshTokenErrors(t *testing.T) {
+	for name, open := range tokenTestStores() {
+		t.Run(name, func(t *testing.T) {
+			st := open(t)
+			userID, _ := newTokenTestUser(t, st)
+			later := time.Now().Add(time.Hour)
+
+			expired, expiredHash, _ := newRefreshToken()
+			if err := st.Sessions().CreateSession(uuid.New().String(), userID, expiredHash, time.Now().Add(-time.Minute)); err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+			if _, _, err := st.Sessions().RotateRefreshToken(expiredHash, "next-"+expiredHash, later); err != store.ErrTokenExpired {
+				t.Fatalf("rotate expired token: err = %v, want %v", err, store.ErrTokenExpired)
+			}
+			if rec := postRefresh(RefreshTokenHandler(st), expired); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("refresh expired token: status %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+
+			_, hash, _ := newRefreshToken()
+			if err := st.Sessions().CreateSession(uuid.New().String(), userID, hash, later); err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+			if _, _, err := st.Sessions().RotateRefreshToken(hash, "a-"+hash, later); err != nil {
+				t.Fatalf("first rotate: %v", err)
+			}
+			if _, _, err := st.Sessions().RotateRefreshToken(hash, "b-"+hash, later); err != store.ErrTokenReused {
+				t.Fatalf("second rotate: err = %v, want %v", err, store.ErrTokenReused)
+			}
+			if _, _, err := st.Sessions().RotateRefreshToken("a-"+hash, "c-"+hash, later); err != store.ErrSessionRevoked {
+				t.Fatalf("rotate after reuse: err = %v, want %v", err, store.ErrSessionRevoked)
+			}
+
+			if _, _, err := st.Sessions().RotateRefreshToken("unknown", "x", later); err != store.ErrNotFound {
+				t.Fatalf("rotate unknown token: err = %v, want %v", err, store.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	for name, open := range tokenTestStores() {
+		t.Run(name, func(t *testing.T) {
+			st := open(t)
+			userID, tokens := newTokenTestUser(t, st)
+			_, other := newTokenTestUser(t, st)
+
+			if got, err := ExtractUserIDFromToken(tokens.Token); err != nil || got != userID {
+				t.Fatalf("before logout: user %q err %v", got, err)
+			}
+
+			body, _ := json.Marshal(refreshRequest{RefreshToken: tokens.RefreshToken})
+			rec := httptest.NewRecorder()
+			LogoutHandler(st)(rec, httptest.NewRequest("POST", "/api/logout", bytes.NewReader(body)))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("logout: status %d body %s", rec.Code, rec.Body)
+			}
+
+			if _, err := ExtractUserIDFromToken(tokens.Token); err == nil {
+				t.Fatal("access token still valid after logout")
+			}
+			if rec := postRefresh(RefreshTokenHandler(st), tokens.RefreshToken); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("refresh after logout: status %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			// session อื่นไม่ได้รับผลกระทบ
+			if _, err := ExtractUserIDFromToken(other.Token); err != nil {
+				t.Fatalf("other session after logout: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package user
+
+import (
+	"clash_and_card/httputil"
+	"context"
+	"net/http"
+	"strings"
+)
+
+type contextKey string
+
+const userIDKey contextKey = "userID"
+
+// RequireAuth ตรวจ Bearer token ใน header Authorization แล้วเก็บ user id ไว้ใน context ของ request
+// handler ที่อยู่หลัง middleware นี้อ่าน user id ได้จาก UserIDFromContext
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			writeUnauthorized(w, "Missing Authorization header")
+			return
+		}
+
+		tokenStr, ok := strings.CutPrefix(authHeader, "Bearer ")
+		tokenStr = strings.TrimSpace(tokenStr)
+		if !ok || tokenStr == "" {
+			writeUnauthorized(w, "Invalid Authorization header")
+			return
+		}
+
+		userID, err := ExtractUserIDFromToken(tokenStr)
+		if err != nil || userID == "" {
+			writeUnauthorized(w, "Invalid token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userIDKey, userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// UserIDFromContext คืน user id ที่ RequireAuth ใส่ไว้ ok เป็น false ถ้า route ไม่ได้ผ่าน RequireAuth
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDKey).(string)
+	return userID, ok && userID != ""
+}
+
+// writeUnauthorized ตอบ 401 เป็น JSON รูปแบบเดียวกับ error อื่นของ API
+func writeUnauthorized(w http.ResponseWriter, msg string) {
+	httputil.WriteJSONError(w, http.StatusUnauthorized, msg)
+}